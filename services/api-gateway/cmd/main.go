@@ -2,12 +2,14 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"log"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -36,6 +38,13 @@ func main() {
 
 	jwksURL := envOr("AUTH_JWKS_URL", "http://auth-service:9001/oauth/jwks")
 	jwks := shsec.NewJWKSCache(jwksURL, 5*time.Minute)
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	go jwks.Start(bgCtx)
+	// jti yang dicabut auth-service (logout / revoke) disinkron dari Redis pub/sub
+	denylist := shsec.NewLocalDenylist(rdb)
+	go denylist.Start(bgCtx)
+	// identifier resource (RFC 8707) gateway; harus terdaftar di oauth_resources auth-service
+	audience := envOr("AUTH_AUDIENCE", "https://api.bkc.local/user")
 
 	userRP.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
 		log.Printf("Error proxying to user-service: %v", err)
@@ -55,7 +64,24 @@ func main() {
 
 	rl := shmw.RateLimitSlidingWindow(rdb, "rl:gw", 60, time.Minute)
 
-	requireJWT := mymw.RequireJWTWithJWKS(jwks, cfg.JWT.Issuer)
+	// proof DPoP: replay jti di Redis; htu dari GATEWAY_PUBLIC_URL (di belakang proxy / TLS offload)
+	dpop := &shsec.DPoPVerifier{
+		RDB:       rdb,
+		Prefix:    "gw:",
+		NonceKey:  []byte(os.Getenv("DPOP_NONCE_KEY")),
+		PublicURL: os.Getenv("GATEWAY_PUBLIC_URL"),
+	}
+
+	validator := &shsec.Validator{
+		Keys:           jwks,
+		Issuers:        []string{cfg.JWT.Issuer},
+		Audiences:      []string{audience},
+		TokenTypes:     []string{"access"},
+		Leeway:         30 * time.Second,
+		RequiredClaims: []string{"sub", "exp", "iat"},
+		Revocation:     denylist,
+	}
+	requireJWT := mymw.RequireJWT(validator, dpop)
 	requireProfile := mymw.RequireScopeFromClaims("profile")
 
 	// ===== HEALTH CHECK (NO PROXY) =====
@@ -66,9 +92,21 @@ func main() {
 		// FIX: Jangan proxy healthz ke backend
 	}).Methods("GET")
 
-	// ===== USER/ME ROUTE =====
-	r.Handle("/user/me",
-		rl(requireJWT(requireProfile(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	// metrik refresh JWKS (stale => auth-service tidak terjangkau, key lama masih dipakai)
+	r.HandleFunc("/healthz/jwks", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(jwks.Stats())
+	}).Methods("GET")
+
+	r.HandleFunc("/healthz/denylist", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(denylist.Stats())
+	}).Methods("GET")
+
+	// proxyUser meneruskan request ke user-service dengan claims JWT sebagai header X-*.
+	// target menentukan path di user-service.
+	proxyUser := func(target func(r *http.Request) string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Extract claims dari context
 			claims, ok := mymw.ClaimsFromContext(r.Context())
 			if !ok || claims == nil {
@@ -86,7 +124,7 @@ func main() {
 			}
 
 			// Set request path dan headers
-			r.URL.Path = "/me"
+			r.URL.Path = target(r)
 			r.Header.Set("X-User-Id", claims.UserID)
 			r.Header.Set("X-Client-Id", claims.ClientID)
 			r.Header.Set("X-Tenant-Id", claims.TenantID)
@@ -116,9 +154,38 @@ func main() {
 				userCB.RecordSuccess()
 				log.Printf("Request success - %s", userCB.String())
 			}
-		})))),
+		})
+	}
+
+	// ===== USER/ME ROUTE =====
+	r.Handle("/user/me",
+		rl(requireJWT(requireProfile(proxyUser(func(*http.Request) string { return "/me" })))),
 	).Methods("GET")
 
+	// ===== USER/ME/* (2FA, dll) =====
+	r.PathPrefix("/user/me/").Handler(
+		rl(requireJWT(proxyUser(func(r *http.Request) string {
+			return strings.TrimPrefix(r.URL.Path, "/user")
+		}))),
+	)
+
+	// ===== USER/PASSWORD/* (lupa / reset password, tanpa JWT) =====
+	rlPassword := shmw.RateLimitSlidingWindow(rdb, "rl:gw:password", 10, time.Minute)
+	for _, p := range []string{"/user/password/forgot", "/user/password/reset"} {
+		r.Handle(p, rlPassword(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if userCB.IsOpen() {
+				http.Error(w, "service unavailable - circuit breaker open", http.StatusServiceUnavailable)
+				return
+			}
+			// header identitas hanya boleh berasal dari gateway (route ber-JWT)
+			for _, h := range []string{"X-User-Id", "X-Client-Id", "X-Tenant-Id", "X-Scope"} {
+				r.Header.Del(h)
+			}
+			r.URL.Path = strings.TrimPrefix(r.URL.Path, "/user")
+			userRP.ServeHTTP(w, r)
+		}))).Methods("POST")
+	}
+
 	// Apply middleware stack
 	handler := shhttp.CORS(shhttp.CorrelationID(shhttp.JSONLogger(r)))
 
@@ -128,6 +195,12 @@ func main() {
 		ReadTimeout:  cfg.Server.ReadTimeout,
 		WriteTimeout: cfg.Server.WriteTimeout,
 		IdleTimeout:  cfg.Server.IdleTimeout,
+		TLS: &shhttp.TLSOptions{
+			CertFile:     cfg.Server.TLSCertFile,
+			KeyFile:      cfg.Server.TLSKeyFile,
+			ClientCAFile: cfg.Server.TLSClientCAFile,
+			ClientAuth:   cfg.Server.TLSClientAuth,
+		},
 	})
 
 	quit := make(chan os.Signal, 1)
@@ -135,7 +208,7 @@ func main() {
 
 	go func() {
 		log.Printf("api-gateway listening on %s, proxy -> %s", srv.Addr, userURL)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := shhttp.ListenAndServe(srv); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("gateway server error: %v", err)
 		}
 	}()
@@ -149,6 +222,7 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Printf("gateway shutdown error: %v", err)
 	}
+	stopBackground()
 	if err := rdb.Close(); err != nil {
 		log.Printf("redis close error: %v", err)
 	}
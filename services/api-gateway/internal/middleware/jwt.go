@@ -18,7 +18,11 @@ func ClaimsFromContext(ctx context.Context) (*security.TokenClaims, bool) {
 	return c, ok
 }
 
-func RequireJWTWithJWKS(jwks *security.JWKSCache, expectedIssuer string) func(http.Handler) http.Handler {
+// RequireJWT — token divalidasi v (issuer, audience resource gateway, typ access,
+// exp/nbf, revocation); refresh token dan token untuk API / client lain ditolak.
+// Token DPoP-bound (cnf.jkt) wajib disertai proof DPoP yang diverifikasi dpop;
+// token certificate-bound (cnf.x5t#S256) wajib lewat mTLS dengan sertifikat yang sama.
+func RequireJWT(v *security.Validator, dpop *security.DPoPVerifier) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			auth := r.Header.Get("Authorization")
@@ -29,18 +33,30 @@ func RequireJWTWithJWKS(jwks *security.JWKSCache, expectedIssuer string) func(ht
 				return
 			}
 			parts := strings.SplitN(auth, " ", 2)
-			if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+			if len(parts) != 2 || (!strings.EqualFold(parts[0], "Bearer") && !strings.EqualFold(parts[0], "DPoP")) {
 				http.Error(w, "unauthorized", http.StatusUnauthorized)
 				return
 			}
 			token := strings.TrimSpace(parts[1])
 
-			_, claims, err := jwks.VerifyRS256(token, expectedIssuer)
+			claims, err := v.Validate(r.Context(), token)
 			if err != nil {
 				log.Printf("Error verifying JWT: %v", err)
 				http.Error(w, "unauthorized", http.StatusUnauthorized)
 				return
 			}
+			if err := dpop.VerifyBinding(r, parts[0], token, claims); err != nil {
+				log.Printf("Error verifying DPoP proof: %v", err)
+				dpop.Challenge(w, err)
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			// certificate-bound token (RFC 8705): gateway harus men-terminate TLS sendiri
+			if err := security.VerifyCertificateBinding(r, claims); err != nil {
+				log.Printf("Error verifying certificate binding: %v", err)
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
 
 			log.Printf("Claims added to context: %+v", claims)
 
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"crypto/x509"
+	"encoding/base64"
 	"fmt"
 	"log"
 	"net/http"
@@ -15,10 +17,12 @@ import (
 	shdb "bkc_microservice/shared/database"
 	shhttp "bkc_microservice/shared/http"
 	smfa "bkc_microservice/shared/mfa"
+	shnotify "bkc_microservice/shared/notify"
 	shsec "bkc_microservice/shared/security"
 	session "bkc_microservice/shared/session"
 
 	appsvc "bkc_microservice/services/auth-service/internal/application/services"
+	"bkc_microservice/services/auth-service/internal/infrastructure/clients"
 	"bkc_microservice/services/auth-service/internal/infrastructure/persistence"
 	httpif "bkc_microservice/services/auth-service/internal/interfaces/http"
 
@@ -47,14 +51,24 @@ func main() {
 	// 	cfg.DB.User, cfg.DB.Password, cfg.DB.Host, cfg.DB.Port, cfg.DB.Name)
 	// shdb.MustRunMigrations(dsn, "./migrations")
 
-	activeKid := os.Getenv("JWT_ACTIVE_KID")
-	if activeKid == "" {
-		activeKid = "auth-k1"
-	}
-	keys := map[string]struct{ PrivatePath, PublicPath string }{
-		"auth-k1": {cfg.JWT.PrivateKeyPath, cfg.JWT.PublicKeyPath},
+	// JWT_KEYS_DIR => key store dengan rotasi terjadwal; selain itu satu key RSA (konfigurasi lama)
+	keyCtx, stopKeys := context.WithCancel(context.Background())
+	defer stopKeys()
+	var keystore *shsec.KeyStore
+	if cfg.JWT.KeysDir != "" {
+		keystore = shsec.MustLoadKeyStoreDir(cfg.JWT.KeysDir, cfg.JWT.Issuer, cfg.JWT.KeyRetireGrace)
+		// reload hanya bermakna untuk direktori key (rotasi tanpa restart)
+		go keystore.Run(keyCtx, cfg.JWT.KeyReloadInterval)
+	} else {
+		activeKid := os.Getenv("JWT_ACTIVE_KID")
+		if activeKid == "" {
+			activeKid = "auth-k1"
+		}
+		keys := map[string]struct{ PrivatePath, PublicPath string }{
+			activeKid: {cfg.JWT.PrivateKeyPath, cfg.JWT.PublicKeyPath},
+		}
+		keystore = shsec.MustLoadKeyStore(activeKid, cfg.JWT.Issuer, keys)
 	}
-	keystore := shsec.MustLoadKeyStore(activeKid, cfg.JWT.Issuer, keys)
 
 	var rdbIface interface{}
 	if os.Getenv("REDIS_MODE") == "cluster" {
@@ -77,29 +91,106 @@ func main() {
 
 	userRepo := persistence.NewMySQLUserRepo(pool)
 	clientRepo := persistence.NewMySQLClientRepo(pool)
+	clientSecretRepo := persistence.NewMySQLClientSecretRepo(pool)
 	codeRepo := persistence.NewMySQLAuthCodeRepo(pool)
 	tokenRepo := persistence.NewMySQLTokenRepo(pool)
+	eventRepo := persistence.NewMySQLSecurityEventRepo(pool)
+	auditRepo := persistence.NewMySQLTokenAuditRepo(pool)
+	recoveryRepo := persistence.NewMySQLRecoveryCodeRepo(pool)
+	scopeRepo := persistence.NewMySQLScopeRepo(pool)
+	consentRepo := persistence.NewMySQLConsentRepo(pool)
+	resourceRepo := persistence.NewMySQLResourceRepo(pool)
+
+	secretBox, err := shsec.NewSecretBoxFromBase64(os.Getenv("MFA_ENCRYPTION_KEY"))
+	if err != nil {
+		log.Fatalf("invalid MFA_ENCRYPTION_KEY: %v", err)
+	}
+
+	// kunci HMAC cookie sesi login (/oauth/login), base64 minimal 32 byte
+	sessionKey, err := base64.StdEncoding.DecodeString(os.Getenv("AUTH_SESSION_KEY"))
+	if err != nil || len(sessionKey) < 32 {
+		log.Fatalf("invalid AUTH_SESSION_KEY: must be base64 of at least 32 bytes")
+	}
+
+	// CA sertifikat client untuk tls_client_auth (RFC 8705); opsional
+	var clientCAs *x509.CertPool
+	if cfg.Server.TLSClientCAFile != "" {
+		if clientCAs, err = shhttp.LoadCertPool(cfg.Server.TLSClientCAFile); err != nil {
+			log.Fatalf("invalid SERVER_TLS_CLIENT_CA_FILE: %v", err)
+		}
+	}
+
+	// X-Forwarded-For hanya dipercaya dari proxy ini (api-gateway)
+	trustedProxies, err := shhttp.ParseTrustedProxies(cfg.Server.TrustedProxies)
+	if err != nil {
+		log.Fatalf("invalid TRUSTED_PROXIES: %v", err)
+	}
 
 	fmt.Println("userRepo:", userRepo)
 	fmt.Println("clientRepo:", clientRepo)
 	fmt.Println("codeRepo:", codeRepo)
 	fmt.Println("tokenRepo:", tokenRepo)
 
+	publicURL := envOr("AUTH_PUBLIC_URL", "http://localhost:9001")
+
 	authSvc := appsvc.NewAuthService(appsvc.Dep{
 		UserRepo:       userRepo,
 		ClientRepo:     clientRepo,
+		ClientSecrets:  clientSecretRepo,
 		CodeRepo:       codeRepo,
 		TokenRepo:      tokenRepo,
+		EventRepo:      eventRepo,
+		AuditRepo:      auditRepo,
+		Recovery:       recoveryRepo,
+		ScopeRepo:      scopeRepo,
+		ResourceRepo:   resourceRepo,
+		ConsentRepo:    consentRepo,
 		KeyStore:       keystore,
 		RDB:            rdb,
 		SessionManager: session.NewManager(rdb),
+		Denylist:       &shsec.JTIDenylist{RDB: rdb},
 		MFAService:     smfa.NewService(&smfa.TOTPService{}, smfa.NewOTPService(rdb)),
+		SecretBox:      secretBox,
+		Notifier:       shnotify.NewLogNotifier(),
+		UserClient:     clients.NewUserServiceClient(cfg.UserServiceURL, os.Getenv("INTERNAL_API_KEY")),
 		AccessTTL:      cfg.JWT.AccessTTL,
 		RefreshTTL:     cfg.JWT.RefreshTTL,
 		CodeTTL:        cfg.JWT.AuthCodeTTL,
 		UserServiceURL: cfg.UserServiceURL,
+		PublicURL:      publicURL,
+
+		DefaultResource: os.Getenv("OAUTH2_DEFAULT_RESOURCE"),
+
+		ClientSecretTTL:     cfg.JWT.ClientSecretTTL,
+		SecretRotationGrace: cfg.JWT.ClientSecretGrace,
+
+		AuthSessionKey: sessionKey,
+		AuthSessionTTL: cfg.JWT.SessionTTL,
+
+		// DPOP_NONCE_KEY kosong => proof DPoP tanpa nonce server; htu endpoint
+		// userinfo / admin dihitung dari URL publik
+		DPoP: &shsec.DPoPVerifier{RDB: rdb, Prefix: "auth:", NonceKey: []byte(os.Getenv("DPOP_NONCE_KEY")), PublicURL: publicURL},
+
+		ClientCAs: clientCAs,
+
+		CleanupBatchSize: cfg.Cleanup.BatchSize,
+		RevokedRetention: cfg.Cleanup.RevokedRetention,
+
+		TrustedProxies: trustedProxies,
+
+		Lockout: appsvc.LockoutPolicy{
+			Threshold:    cfg.Lockout.Threshold,
+			Duration:     cfg.Lockout.Duration,
+			ThrottleBase: cfg.Lockout.ThrottleBase,
+			ThrottleMax:  cfg.Lockout.ThrottleMax,
+		},
 	})
 
+	// janitor token / auth code kedaluwarsa; satu replica per putaran (lock Redis)
+	janitorCtx, stopJanitor := context.WithCancel(context.Background())
+	defer stopJanitor()
+	go authSvc.RunJanitor(janitorCtx, cfg.Cleanup.Interval)
+
 	r := httpif.NewRouter(authSvc)
 	handler := shhttp.CORS(shhttp.CorrelationID(shhttp.JSONLogger(r)))
 
@@ -109,6 +200,12 @@ func main() {
 		ReadTimeout:  cfg.Server.ReadTimeout,
 		WriteTimeout: cfg.Server.WriteTimeout,
 		IdleTimeout:  cfg.Server.IdleTimeout,
+		TLS: &shhttp.TLSOptions{
+			CertFile:     cfg.Server.TLSCertFile,
+			KeyFile:      cfg.Server.TLSKeyFile,
+			ClientCAFile: cfg.Server.TLSClientCAFile,
+			ClientAuth:   cfg.Server.TLSClientAuth,
+		},
 	})
 
 	quit := make(chan os.Signal, 1)
@@ -116,7 +213,7 @@ func main() {
 
 	go func() {
 		log.Printf("auth-service listening on %s, proxy -> %s", srv.Addr, cfg.UserServiceURL)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := shhttp.ListenAndServe(srv); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("gateway server error: %v", err)
 		}
 	}()
@@ -130,6 +227,7 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Printf("gateway shutdown error: %v", err)
 	}
+	stopJanitor()
 	if err := rdb.Close(); err != nil {
 		log.Printf("redis close error: %v", err)
 	}
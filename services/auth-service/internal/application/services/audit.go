@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"bkc_microservice/services/auth-service/internal/domain/entities"
+)
+
+// Audit trail token (oauth_token_audits): setiap penerbitan, refresh, revocation dan
+// introspection gagal dicatat beserta IP / user agent / correlation id request.
+// Gagal menulis audit hanya di-log; request token tidak ikut gagal.
+
+const (
+	AuditTokenIssued         = "issued"
+	AuditTokenRefreshed      = "refreshed"
+	AuditTokenRevoked        = "revoked"
+	AuditIntrospectionFailed = "introspection_failed"
+
+	// alasan revocation massal (detail audit)
+	AuditReasonReuseDetected  = "reuse_detected"
+	AuditReasonConsentRevoked = "consent_revoked"
+
+	maxAuditPage   = 1000
+	maxAuditExport = 50000
+)
+
+// RequestMeta — informasi request HTTP yang dicatat di audit.
+type RequestMeta struct {
+	IP            string // hop pertama X-Forwarded-For, bisa dipalsukan client: hanya untuk tampilan audit
+	RemoteIP      string // IP client menurut proxy tepercaya: dipakai untuk throttling
+	UserAgent     string
+	CorrelationID string
+}
+
+type requestMetaCtx struct{}
+
+// WithRequestMeta menyematkan metadata request ke ctx untuk audit trail.
+func WithRequestMeta(ctx context.Context, m RequestMeta) context.Context {
+	return context.WithValue(ctx, requestMetaCtx{}, m)
+}
+
+func requestMetaFromContext(ctx context.Context) RequestMeta {
+	m, _ := ctx.Value(requestMetaCtx{}).(RequestMeta)
+	return m
+}
+
+// recordTokenAudit menulis satu baris audit; field meta diambil dari ctx.
+func (s *AuthService) recordTokenAudit(ctx context.Context, a *entities.TokenAudit) {
+	if s.dep.AuditRepo == nil {
+		return
+	}
+	m := requestMetaFromContext(ctx)
+	a.IP, a.UserAgent, a.CorrelationID = strptr(m.IP), strptr(truncate(m.UserAgent, 512)), strptr(m.CorrelationID)
+	if err := s.dep.AuditRepo.Save(ctx, a); err != nil {
+		log.Printf("[AuthService] save token audit event=%s failed: %v", a.Event, err)
+	}
+}
+
+// auditRevokedTokens mencatat revocation massal: satu baris per access token yang dicabut
+// (jti di detail); tanpa access token hidup tetap dicatat satu baris refresh token.
+func (s *AuthService) auditRevokedTokens(ctx context.Context, a entities.TokenAudit, reason string, revoked []*entities.RevokedAccessToken) {
+	a.Event = AuditTokenRevoked
+	if len(revoked) == 0 {
+		detail := reason + " revoked=0"
+		a.TokenType, a.Detail = "refresh", &detail
+		s.recordTokenAudit(ctx, &a)
+		return
+	}
+	for _, t := range revoked {
+		row := a
+		detail := reason + " jti=" + t.JTI
+		row.TokenType, row.Detail = "access", &detail
+		s.recordTokenAudit(ctx, &row)
+	}
+}
+
+// auditIssued mencatat access token (+ refresh token) hasil issueTokens.
+func (s *AuthService) auditIssued(ctx context.Context, req tokenRequest, tok *entities.Token) {
+	event := AuditTokenIssued
+	if req.GrantType == "refresh_token" {
+		event = AuditTokenRefreshed
+	}
+	base := entities.TokenAudit{
+		Event:     event,
+		CompanyID: strptr(tok.CompanyID),
+		ClientID:  strptr(tok.ClientID),
+		UserID:    tok.UserID,
+		GrantType: strptr(req.GrantType),
+	}
+	if req.Actor != nil {
+		base.Detail = strptr("actor=" + req.Actor.Subject)
+	}
+	at := base
+	at.TokenType = "access"
+	s.recordTokenAudit(ctx, &at)
+	if tok.RefreshToken != nil {
+		rt := base
+		rt.TokenType = "refresh"
+		s.recordTokenAudit(ctx, &rt)
+	}
+}
+
+// TokenAuditQuery — filter admin; ClientID = client_id publik.
+type TokenAuditQuery struct {
+	UserID   string
+	ClientID string
+	TenantID string
+	Event    string
+	From     time.Time
+	To       time.Time
+	Limit    int
+	Offset   int
+}
+
+// QueryTokenAudits mencari audit token; export=true menaikkan batas baris untuk CSV.
+func (s *AuthService) QueryTokenAudits(ctx context.Context, q TokenAuditQuery, export bool) ([]*entities.TokenAudit, error) {
+	if s.dep.AuditRepo == nil {
+		return nil, nil
+	}
+	f := entities.TokenAuditFilter{
+		UserID:    q.UserID,
+		CompanyID: q.TenantID,
+		Event:     q.Event,
+		From:      q.From,
+		To:        q.To,
+		Limit:     q.Limit,
+		Offset:    q.Offset,
+	}
+	if q.ClientID != "" {
+		c, err := s.dep.ClientRepo.FindByClientID(ctx, q.ClientID)
+		if err != nil {
+			return nil, err
+		}
+		if c == nil {
+			return nil, ErrClientNotFound
+		}
+		f.ClientID = c.ID
+	}
+	limit, max := 100, maxAuditPage
+	if export {
+		limit, max = maxAuditExport, maxAuditExport
+	}
+	if f.Limit <= 0 {
+		f.Limit = limit
+	}
+	if f.Limit > max {
+		f.Limit = max
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+	return s.dep.AuditRepo.Query(ctx, f)
+}
+
+func truncate(v string, n int) string {
+	if len(v) > n {
+		return v[:n]
+	}
+	return v
+}
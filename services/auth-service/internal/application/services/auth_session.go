@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"bkc_microservice/services/auth-service/internal/infrastructure/clients"
+)
+
+const defaultAuthSessionTTL = 12 * time.Hour
+
+var (
+	ErrInvalidCredentials = errors.New("invalid_credentials")
+	ErrAccountLocked      = errors.New("account_locked")
+	ErrInvalidSession     = errors.New("invalid_session")
+	ErrSessionUnavailable = errors.New("session_unavailable")
+
+	ErrLoginRequired = newOAuthError("login_required", "end-user authentication is required")
+	ErrAccessDenied  = newOAuthError("access_denied", "the resource owner denied the request")
+
+	ErrUnsupportedResponseType = newOAuthError("unsupported_response_type", "only response_type=code is supported")
+)
+
+// AuthSession adalah sesi login browser di auth-service. Isinya disimpan di cookie
+// bertanda tangan HMAC; status aktifnya di Redis (shared/session.Manager) sehingga
+// bisa dicabut dari server.
+type AuthSession struct {
+	UserID   string `json:"uid"`
+	SID      string `json:"sid"`
+	Username string `json:"name,omitempty"`
+	AuthTime int64  `json:"auth_time"`
+}
+
+func (a *AuthSession) AuthenticatedAt() time.Time { return time.Unix(a.AuthTime, 0) }
+
+// Fresh — login masih memenuhi max_age (detik); maxAge < 0 berarti tanpa batas.
+func (a *AuthSession) Fresh(maxAge int, now time.Time) bool {
+	if maxAge < 0 {
+		return true
+	}
+	return now.Sub(a.AuthenticatedAt()) <= time.Duration(maxAge)*time.Second
+}
+
+func (s *AuthService) AuthSessionTTL() time.Duration {
+	if s.dep.AuthSessionTTL > 0 {
+		return s.dep.AuthSessionTTL
+	}
+	return defaultAuthSessionTTL
+}
+
+// authenticateUser memverifikasi login (email/username) + password ke user-service.
+func (s *AuthService) authenticateUser(ctx context.Context, login, password string) (*clients.AuthenticatedUser, error) {
+	if s.dep.UserClient == nil {
+		return nil, ErrSessionUnavailable
+	}
+	u, err := s.dep.UserClient.Authenticate(ctx, login, password)
+	switch {
+	case errors.Is(err, clients.ErrInvalidCredentials):
+		return nil, ErrInvalidCredentials
+	case errors.Is(err, clients.ErrAccountLocked):
+		return nil, ErrAccountLocked
+	case err != nil:
+		return nil, err
+	}
+	return u, nil
+}
+
+// Login memverifikasi kredensial user lalu membuat sesi browser baru.
+// Mengembalikan sesi dan nilai cookie yang sudah ditandatangani.
+func (s *AuthService) Login(ctx context.Context, login, password string) (*AuthSession, string, error) {
+	if s.dep.SessionManager == nil || len(s.dep.AuthSessionKey) == 0 {
+		return nil, "", ErrSessionUnavailable
+	}
+
+	u, err := s.authenticateUser(ctx, login, password)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sid, err := randomHex(24)
+	if err != nil {
+		return nil, "", err
+	}
+	sess := &AuthSession{
+		UserID:   u.ID,
+		SID:      sid,
+		Username: u.Username,
+		AuthTime: time.Now().Unix(),
+	}
+	if err := s.dep.SessionManager.Create(ctx, sess.UserID, sess.SID, s.AuthSessionTTL()); err != nil {
+		return nil, "", err
+	}
+
+	cookie, err := s.encodeSession(sess)
+	if err != nil {
+		return nil, "", err
+	}
+	log.Printf("[AuthService] login user=%s sid=%s", sess.UserID, sess.SID)
+	return sess, cookie, nil
+}
+
+// LoadSession memverifikasi tanda tangan cookie dan memastikan sesi belum dicabut.
+func (s *AuthService) LoadSession(ctx context.Context, cookie string) (*AuthSession, error) {
+	if s.dep.SessionManager == nil || len(s.dep.AuthSessionKey) == 0 {
+		return nil, ErrSessionUnavailable
+	}
+
+	payload, sig, ok := strings.Cut(cookie, ".")
+	if !ok {
+		return nil, ErrInvalidSession
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, s.sessionMAC(payload)) {
+		return nil, ErrInvalidSession
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalidSession
+	}
+
+	var sess AuthSession
+	if err := json.Unmarshal(raw, &sess); err != nil || sess.UserID == "" || sess.SID == "" {
+		return nil, ErrInvalidSession
+	}
+	if !s.dep.SessionManager.IsActive(ctx, sess.UserID, sess.SID) {
+		return nil, ErrInvalidSession
+	}
+	return &sess, nil
+}
+
+// Logout mencabut sesi browser.
+func (s *AuthService) Logout(ctx context.Context, sess *AuthSession) error {
+	if s.dep.SessionManager == nil || sess == nil {
+		return nil
+	}
+	return s.dep.SessionManager.Revoke(ctx, sess.UserID, sess.SID)
+}
+
+// SessionCSRFToken — token anti-CSRF form consent, terikat ke sesi.
+func (s *AuthService) SessionCSRFToken(sess *AuthSession) string {
+	mac := hmac.New(sha256.New, s.dep.AuthSessionKey)
+	mac.Write([]byte("csrf:" + sess.SID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *AuthService) VerifySessionCSRF(sess *AuthSession, token string) bool {
+	return token != "" && hmac.Equal([]byte(token), []byte(s.SessionCSRFToken(sess)))
+}
+
+func (s *AuthService) encodeSession(sess *AuthSession) (string, error) {
+	raw, err := json.Marshal(sess)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(raw)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.sessionMAC(payload)), nil
+}
+
+func (s *AuthService) sessionMAC(payload string) []byte {
+	mac := hmac.New(sha256.New, s.dep.AuthSessionKey)
+	mac.Write([]byte("session:" + payload))
+	return mac.Sum(nil)
+}
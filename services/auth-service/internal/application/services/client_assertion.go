@@ -0,0 +1,281 @@
+package services
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"bkc_microservice/services/auth-service/internal/domain/entities"
+	sharedsec "bkc_microservice/shared/security"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	ClientAssertionTypeJWT = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+	AuthMethodPrivateKeyJWT = "private_key_jwt"
+	AuthMethodSecretJWT     = "client_secret_jwt"
+
+	clientAssertionMaxLifetime = 10 * time.Minute
+	clientAssertionLeeway      = 30 * time.Second
+	clientJWKSCacheTTL         = 10 * time.Minute
+	clientJWKSMinRefresh       = time.Minute
+)
+
+var (
+	asymmetricAssertionAlgs = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+	hmacAssertionAlgs       = []string{"HS256", "HS384", "HS512"}
+)
+
+// ClientAuth — kredensial client dari request (/oauth/token, /oauth/introspect, /oauth/revoke):
+// client_secret (basic / post), JWT assertion (RFC 7523) atau sertifikat mTLS (RFC 8705).
+type ClientAuth struct {
+	ClientID      string
+	ClientSecret  string
+	AssertionType string
+	Assertion     string
+	Certificates  []*x509.Certificate // rantai sertifikat client koneksi TLS, leaf pertama
+}
+
+// HasCredentials — request membawa secret atau assertion
+func (a ClientAuth) HasCredentials() bool {
+	return a.ClientSecret != "" || a.Assertion != ""
+}
+
+func isJWTAuthMethod(method string) bool {
+	return method == AuthMethodPrivateKeyJWT || method == AuthMethodSecretJWT
+}
+
+// authenticateAssertion memverifikasi client_assertion: iss = sub = client_id, aud = issuer /
+// endpoint auth-service, exp wajib dan pendek, jti sekali pakai (Redis).
+func (s *AuthService) authenticateAssertion(ctx context.Context, auth ClientAuth) (*entities.OAuthClient, error) {
+	if auth.AssertionType != ClientAssertionTypeJWT || auth.Assertion == "" {
+		return nil, ErrInvalidClient
+	}
+
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(append(append([]string{}, asymmetricAssertionAlgs...), hmacAssertionAlgs...)),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(clientAssertionLeeway),
+	)
+
+	// client_id diambil dari klaim sebelum verifikasi untuk memilih key
+	var unverified jwt.RegisteredClaims
+	if _, _, err := parser.ParseUnverified(auth.Assertion, &unverified); err != nil {
+		return nil, ErrInvalidClient
+	}
+	clientID := unverified.Subject
+	if clientID == "" || unverified.Issuer != clientID || (auth.ClientID != "" && auth.ClientID != clientID) {
+		return nil, ErrInvalidClient
+	}
+
+	c, err := s.dep.ClientRepo.FindByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil || !isJWTAuthMethod(c.AuthMethod) {
+		return nil, ErrInvalidClient
+	}
+
+	var claims jwt.RegisteredClaims
+	if _, err := parser.ParseWithClaims(auth.Assertion, &claims, func(t *jwt.Token) (any, error) {
+		return s.assertionKey(ctx, c, t)
+	}); err != nil {
+		log.Printf("[AuthService] client assertion rejected client=%s: %v", c.ClientID, err)
+		return nil, ErrInvalidClient
+	}
+
+	if claims.Issuer != c.ClientID || claims.Subject != c.ClientID || claims.ID == "" {
+		return nil, ErrInvalidClient
+	}
+	if !s.assertionAudienceOK(claims.Audience) {
+		return nil, ErrInvalidClient
+	}
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl > clientAssertionMaxLifetime {
+		return nil, ErrInvalidClient
+	}
+
+	// replay: jti hanya boleh dipakai sekali sampai assertion kedaluwarsa
+	if s.dep.RDB == nil {
+		return nil, ErrInvalidClient
+	}
+	ok, err := s.dep.RDB.SetNX(ctx, "client_assertion:jti:"+c.ClientID+":"+claims.ID, "1", ttl+clientAssertionLeeway).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		log.Printf("[AuthService] client assertion replay client=%s jti=%s", c.ClientID, claims.ID)
+		return nil, ErrInvalidClient
+	}
+	return c, nil
+}
+
+func (s *AuthService) assertionKey(ctx context.Context, c *entities.OAuthClient, t *jwt.Token) (any, error) {
+	alg := t.Method.Alg()
+	if c.AuthAlg != nil && *c.AuthAlg != alg {
+		return nil, errors.New("unexpected alg")
+	}
+
+	if c.AuthMethod == AuthMethodSecretJWT {
+		if !containsString(hmacAssertionAlgs, alg) {
+			return nil, errors.New("client_secret_jwt requires HMAC")
+		}
+		return s.hmacKeys(ctx, c)
+	}
+
+	if !containsString(asymmetricAssertionAlgs, alg) {
+		return nil, errors.New("private_key_jwt requires asymmetric alg")
+	}
+	kid, _ := t.Header["kid"].(string)
+	jwk, err := s.clientJWK(ctx, c, kid)
+	if err != nil {
+		return nil, err
+	}
+	if jwk.Alg != "" && jwk.Alg != alg {
+		return nil, errors.New("alg does not match jwk")
+	}
+	return jwk.PublicKey()
+}
+
+// hmacKeys — semua secret aktif (rotasi) sebagai kandidat key HMAC
+func (s *AuthService) hmacKeys(ctx context.Context, c *entities.OAuthClient) (any, error) {
+	if s.dep.SecretBox == nil {
+		return nil, errors.New("secret box not configured")
+	}
+	secrets, err := s.dep.ClientSecrets.ListActive(ctx, c.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	var set jwt.VerificationKeySet
+	for _, sec := range secrets {
+		if sec.Sealed == nil {
+			continue
+		}
+		plain, err := s.dep.SecretBox.Open(*sec.Sealed)
+		if err != nil {
+			log.Printf("[AuthService] open client secret failed client=%s: %v", c.ClientID, err)
+			continue
+		}
+		set.Keys = append(set.Keys, []byte(plain))
+	}
+	if len(set.Keys) == 0 {
+		return nil, errors.New("no usable client secret")
+	}
+	return set, nil
+}
+
+func (s *AuthService) clientJWK(ctx context.Context, c *entities.OAuthClient, kid string) (*sharedsec.JWK, error) {
+	if c.JWKS != nil {
+		set, err := sharedsec.ParseJWKSet([]byte(*c.JWKS))
+		if err != nil {
+			return nil, err
+		}
+		return set.Lookup(kid)
+	}
+	if c.JWKSURI != nil {
+		return s.clientJWKS.lookup(ctx, *c.JWKSURI, kid)
+	}
+	return nil, errors.New("client has no jwks")
+}
+
+// clientJWKSet — seluruh key client (jwks inline / jwks_uri)
+func (s *AuthService) clientJWKSet(ctx context.Context, c *entities.OAuthClient) (*sharedsec.JWKSet, error) {
+	if c.JWKS != nil {
+		return sharedsec.ParseJWKSet([]byte(*c.JWKS))
+	}
+	if c.JWKSURI != nil {
+		return s.clientJWKS.get(ctx, *c.JWKSURI)
+	}
+	return nil, errors.New("client has no jwks")
+}
+
+func (s *AuthService) assertionAudienceOK(aud jwt.ClaimStrings) bool {
+	base := strings.TrimRight(s.dep.PublicURL, "/")
+	accepted := []string{
+		base,
+		base + "/oauth/token",
+		base + "/oauth/introspect",
+		base + "/oauth/revoke",
+	}
+	if s.dep.KeyStore != nil && s.dep.KeyStore.Issuer != "" {
+		accepted = append(accepted, s.dep.KeyStore.Issuer)
+	}
+	for _, a := range aud {
+		if containsString(accepted, a) {
+			return true
+		}
+	}
+	return false
+}
+
+/************** jwks_uri cache **************/
+
+// clientJWKSCache menyimpan JWKS client per URL; kid yang belum dikenal memicu fetch ulang
+// (dibatasi clientJWKSMinRefresh) supaya rotasi key di sisi client langsung terbaca.
+type clientJWKSCache struct {
+	mu      sync.Mutex
+	entries map[string]*cachedJWKS
+	client  *http.Client
+}
+
+type cachedJWKS struct {
+	set       *sharedsec.JWKSet
+	fetchedAt time.Time
+}
+
+func newClientJWKSCache() *clientJWKSCache {
+	return &clientJWKSCache{
+		entries: map[string]*cachedJWKS{},
+		client:  &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (c *clientJWKSCache) lookup(ctx context.Context, url, kid string) (*sharedsec.JWK, error) {
+	c.mu.Lock()
+	e := c.entries[url]
+	c.mu.Unlock()
+
+	if e != nil && time.Since(e.fetchedAt) < clientJWKSCacheTTL {
+		if k, err := e.set.Lookup(kid); err == nil {
+			return k, nil
+		}
+		if time.Since(e.fetchedAt) < clientJWKSMinRefresh {
+			return nil, errors.New("kid not found")
+		}
+	}
+
+	set, err := c.fetch(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return set.Lookup(kid)
+}
+
+// get mengembalikan JWKS dari cache selama belum kedaluwarsa
+func (c *clientJWKSCache) get(ctx context.Context, url string) (*sharedsec.JWKSet, error) {
+	c.mu.Lock()
+	e := c.entries[url]
+	c.mu.Unlock()
+	if e != nil && time.Since(e.fetchedAt) < clientJWKSCacheTTL {
+		return e.set, nil
+	}
+	return c.fetch(ctx, url)
+}
+
+func (c *clientJWKSCache) fetch(ctx context.Context, url string) (*sharedsec.JWKSet, error) {
+	set, err := sharedsec.FetchJWKSet(ctx, c.client, url)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.entries[url] = &cachedJWKS{set: set, fetchedAt: time.Now()}
+	c.mu.Unlock()
+	return set, nil
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"bkc_microservice/services/auth-service/internal/domain/entities"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// masa tumpang tindih default saat rotasi: secret lama masih diterima selama ini
+const defaultSecretRotationGrace = 24 * time.Hour
+
+var ErrInvalidClient = newOAuthError("invalid_client", "client authentication failed")
+
+// AuthenticateClient memverifikasi kredensial client sesuai token_endpoint_auth_method.
+// Public client (none) lolos tanpa secret.
+func (s *AuthService) AuthenticateClient(ctx context.Context, auth ClientAuth) (*entities.OAuthClient, error) {
+	if auth.Assertion != "" || auth.AssertionType != "" {
+		return s.authenticateAssertion(ctx, auth)
+	}
+
+	c, err := s.dep.ClientRepo.FindByClientID(ctx, auth.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, ErrInvalidClient
+	}
+	switch {
+	case c.AuthMethod == AuthMethodNone:
+		return c, nil
+	case isJWTAuthMethod(c.AuthMethod):
+		// client terdaftar dengan JWT assertion tidak boleh turun ke secret biasa
+		return nil, ErrInvalidClient
+	case isTLSAuthMethod(c.AuthMethod):
+		return s.authenticateTLSClient(ctx, c, auth.Certificates)
+	}
+	if auth.ClientSecret == "" || !s.verifyClientSecret(ctx, c, auth.ClientSecret) {
+		return nil, ErrInvalidClient
+	}
+	return c, nil
+}
+
+// usesClientSecret — metode auth yang membutuhkan secret dari server
+func usesClientSecret(method string) bool {
+	return method == AuthMethodSecretBasic || method == AuthMethodSecretPost || method == AuthMethodSecretJWT
+}
+
+func (s *AuthService) verifyClientSecret(ctx context.Context, c *entities.OAuthClient, secret string) bool {
+	secrets, err := s.dep.ClientSecrets.ListActive(ctx, c.ID, time.Now())
+	if err != nil {
+		log.Printf("[AuthService] list client secrets failed client=%s: %v", c.ClientID, err)
+		return false
+	}
+	for _, sec := range secrets {
+		if bcrypt.CompareHashAndPassword([]byte(sec.SecretHash), []byte(secret)) == nil {
+			return true
+		}
+	}
+
+	// data lama di oauth_clients.client_secret: plaintext (di-hash saat pertama kali cocok)
+	// atau sudah berupa hash bcrypt (data seed)
+	if c.Secret == nil || *c.Secret == "" {
+		return false
+	}
+	if isBcryptHash(*c.Secret) {
+		if bcrypt.CompareHashAndPassword([]byte(*c.Secret), []byte(secret)) != nil {
+			return false
+		}
+		s.migrateLegacySecret(ctx, c)
+		return true
+	}
+	if subtle.ConstantTimeCompare([]byte(*c.Secret), []byte(secret)) == 1 {
+		s.migrateLegacySecret(ctx, c)
+		return true
+	}
+	return false
+}
+
+func isBcryptHash(v string) bool {
+	return strings.HasPrefix(v, "$2a$") || strings.HasPrefix(v, "$2b$") || strings.HasPrefix(v, "$2y$")
+}
+
+// migrateLegacySecret memindahkan oauth_clients.client_secret ke oauth_client_secrets (hash).
+func (s *AuthService) migrateLegacySecret(ctx context.Context, c *entities.OAuthClient) {
+	if c.Secret == nil || *c.Secret == "" {
+		return
+	}
+	if isBcryptHash(*c.Secret) {
+		// sudah hash: pindahkan apa adanya
+		if err := s.dep.ClientSecrets.Create(ctx, &entities.ClientSecret{ClientID: c.ID, SecretHash: *c.Secret}); err != nil {
+			log.Printf("[AuthService] move hashed client secret failed client=%s: %v", c.ClientID, err)
+			return
+		}
+	} else if _, err := s.storeClientSecret(ctx, c, *c.Secret, nil); err != nil {
+		log.Printf("[AuthService] store hashed client secret failed client=%s: %v", c.ClientID, err)
+		return
+	}
+	if err := s.dep.ClientRepo.UpdateSecret(ctx, c.ClientID, nil); err != nil {
+		log.Printf("[AuthService] clear legacy client secret failed client=%s: %v", c.ClientID, err)
+		return
+	}
+	c.Secret = nil
+}
+
+// newClientSecret membuat secret acak, menyimpan hash-nya, dan mengembalikan plaintext (sekali tampil).
+func (s *AuthService) newClientSecret(ctx context.Context, c *entities.OAuthClient) (string, *entities.ClientSecret, error) {
+	secret, err := randomSecret()
+	if err != nil {
+		return "", nil, err
+	}
+
+	var exp *time.Time
+	if s.dep.ClientSecretTTL > 0 {
+		t := time.Now().Add(s.dep.ClientSecretTTL)
+		exp = &t
+	}
+	sec, err := s.storeClientSecret(ctx, c, secret, exp)
+	if err != nil {
+		return "", nil, err
+	}
+	return secret, sec, nil
+}
+
+// storeClientSecret menyimpan hash bcrypt; client_secret_jwt juga menyimpan salinan terenkripsi
+// karena verifikasi HMAC butuh secret asli.
+func (s *AuthService) storeClientSecret(ctx context.Context, c *entities.OAuthClient, secret string, expiresAt *time.Time) (*entities.ClientSecret, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	sec := &entities.ClientSecret{ClientID: c.ID, SecretHash: string(hash), ExpiresAt: expiresAt}
+
+	if c.AuthMethod == AuthMethodSecretJWT {
+		if s.dep.SecretBox == nil {
+			return nil, errors.New("client_secret_jwt requires encryption key")
+		}
+		sealed, err := s.dep.SecretBox.Seal(secret)
+		if err != nil {
+			return nil, err
+		}
+		sec.Sealed = &sealed
+	}
+
+	if err := s.dep.ClientSecrets.Create(ctx, sec); err != nil {
+		return nil, err
+	}
+	return sec, nil
+}
+
+// rotateClientSecret menerbitkan secret baru; secret aktif terakhir tetap berlaku sampai
+// masa rotasi habis, secret lain langsung dicabut (maksimal dua secret aktif).
+func (s *AuthService) rotateClientSecret(ctx context.Context, c *entities.OAuthClient) (string, *entities.ClientSecret, error) {
+	s.migrateLegacySecret(ctx, c)
+
+	now := time.Now()
+	active, err := s.dep.ClientSecrets.ListActive(ctx, c.ID, now)
+	if err != nil {
+		return "", nil, err
+	}
+
+	secret, sec, err := s.newClientSecret(ctx, c)
+	if err != nil {
+		return "", nil, err
+	}
+
+	keep := []string{sec.ID}
+	if len(active) > 0 {
+		prev := active[0]
+		grace := s.dep.SecretRotationGrace
+		if grace <= 0 {
+			grace = defaultSecretRotationGrace
+		}
+		graceEnd := now.Add(grace)
+		if prev.ExpiresAt == nil || prev.ExpiresAt.After(graceEnd) {
+			if err := s.dep.ClientSecrets.SetExpiry(ctx, prev.ID, graceEnd); err != nil {
+				return "", nil, err
+			}
+		}
+		keep = append(keep, prev.ID)
+	}
+	if err := s.dep.ClientSecrets.RevokeAllExcept(ctx, c.ID, keep...); err != nil {
+		return "", nil, err
+	}
+	return secret, sec, nil
+}
+
+// revokeClientSecrets mencabut semua secret (client berubah menjadi public).
+func (s *AuthService) revokeClientSecrets(ctx context.Context, c *entities.OAuthClient) error {
+	if err := s.dep.ClientSecrets.RevokeAllExcept(ctx, c.ID); err != nil {
+		return err
+	}
+	if c.Secret != nil {
+		if err := s.dep.ClientRepo.UpdateSecret(ctx, c.ClientID, nil); err != nil {
+			return err
+		}
+		c.Secret = nil
+	}
+	return nil
+}
+
+func secretExpiresAt(sec *entities.ClientSecret) int64 {
+	if sec == nil || sec.ExpiresAt == nil {
+		return 0
+	}
+	return sec.ExpiresAt.Unix()
+}
@@ -0,0 +1,505 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"bkc_microservice/services/auth-service/internal/domain/entities"
+	sharedsec "bkc_microservice/shared/security"
+)
+
+const (
+	ScopeOAuthAdmin = "oauth:admin"
+
+	AuthMethodSecretBasic = "client_secret_basic"
+	AuthMethodSecretPost  = "client_secret_post"
+	AuthMethodNone        = "none"
+)
+
+var (
+	ErrClientNotFound     = errors.New("client not found")
+	ErrUnauthorizedClient = newOAuthError("unauthorized_client", "grant type not allowed for this client")
+
+	supportedGrantTypes = []string{
+		"authorization_code", "refresh_token", "client_credentials", "password", GrantTypeMFAOTP, GrantTypeDeviceCode,
+		GrantTypeTokenExchange,
+	}
+	supportedAuthMethods = []string{
+		AuthMethodSecretBasic, AuthMethodSecretPost, AuthMethodSecretJWT, AuthMethodPrivateKeyJWT, AuthMethodNone,
+		AuthMethodTLSClient, AuthMethodSelfSignedTLS,
+	}
+)
+
+// ClientMetadata — metadata client RFC 7591 (request register / update RFC 7592).
+type ClientMetadata struct {
+	ClientName              string          `json:"client_name,omitempty"`
+	RedirectURIs            []string        `json:"redirect_uris,omitempty"`
+	GrantTypes              []string        `json:"grant_types,omitempty"`
+	Scope                   string          `json:"scope,omitempty"`
+	TokenEndpointAuthMethod string          `json:"token_endpoint_auth_method,omitempty"`
+	TokenEndpointAuthAlg    string          `json:"token_endpoint_auth_signing_alg,omitempty"`
+	JWKS                    json.RawMessage `json:"jwks,omitempty"`
+	JWKSURI                 string          `json:"jwks_uri,omitempty"`
+	CompanyID               string          `json:"company_id,omitempty"`
+	AccessTokenTTL          int64           `json:"access_token_ttl,omitempty"`  // detik
+	RefreshTokenTTL         int64           `json:"refresh_token_ttl,omitempty"` // detik
+
+	// OIDC RP-Initiated Logout & Back-Channel Logout
+	PostLogoutRedirectURIs []string `json:"post_logout_redirect_uris,omitempty"`
+	BackchannelLogoutURI   string   `json:"backchannel_logout_uri,omitempty"`
+
+	// PAR (RFC 9126) & request object (RFC 9101)
+	RequirePushedAuthorizationRequests bool   `json:"require_pushed_authorization_requests,omitempty"`
+	RequireSignedRequestObject         bool   `json:"require_signed_request_object,omitempty"`
+	RequestObjectSigningAlg            string `json:"request_object_signing_alg,omitempty"`
+
+	// DPoP (RFC 9449 §5.2): token endpoint menolak request tanpa proof DPoP
+	DPoPBoundAccessTokens bool `json:"dpop_bound_access_tokens,omitempty"`
+
+	// mTLS (RFC 8705): tls_client_auth wajib tepat satu atribut subject sertifikat
+	TLSClientAuthSubjectDN                string `json:"tls_client_auth_subject_dn,omitempty"`
+	TLSClientAuthSANDNS                   string `json:"tls_client_auth_san_dns,omitempty"`
+	TLSClientAuthSANURI                   string `json:"tls_client_auth_san_uri,omitempty"`
+	TLSClientAuthSANIP                    string `json:"tls_client_auth_san_ip,omitempty"`
+	TLSClientAuthSANEmail                 string `json:"tls_client_auth_san_email,omitempty"`
+	TLSClientCertificateBoundAccessTokens bool   `json:"tls_client_certificate_bound_access_tokens,omitempty"`
+}
+
+// ClientInformation — response register / read client.
+type ClientInformation struct {
+	ClientID              string `json:"client_id"`
+	ClientSecret          string `json:"client_secret,omitempty"` // hanya saat register / rotate
+	ClientIDIssuedAt      int64  `json:"client_id_issued_at"`
+	ClientSecretExpiresAt int64  `json:"client_secret_expires_at"`
+	RegistrationClientURI string `json:"registration_client_uri"`
+	ClientMetadata
+}
+
+/************** ADMIN AUTH **************/
+
+// AuthenticateBearer memvalidasi access token dari header Authorization (skema Bearer
+// atau DPoP). Token sender-constrained wajib disertai bukti kepemilikannya: proof DPoP
+// dari key cnf.jkt, atau sertifikat mTLS koneksi yang cocok dengan cnf.x5t#S256.
+func (s *AuthService) AuthenticateBearer(r *http.Request, scheme, accessToken, requiredScope string) (*sharedsec.TokenClaims, error) {
+	claims, err := s.verifyAccessToken(r.Context(), accessToken, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyTokenBinding(r, scheme, accessToken, claims); err != nil {
+		return nil, err
+	}
+	if requiredScope != "" && !hasScope(claims.Scope, requiredScope) {
+		return nil, ErrInsufficientScope
+	}
+	return claims, nil
+}
+
+// verifyTokenBinding — pemeriksaan cnf yang sama dengan resource server (gateway).
+func (s *AuthService) verifyTokenBinding(r *http.Request, scheme, accessToken string, claims *sharedsec.TokenClaims) error {
+	if err := s.dep.DPoP.VerifyBinding(r, scheme, accessToken, claims); err != nil {
+		log.Printf("[AuthService] dpop binding rejected: %v", err)
+		switch {
+		case errors.Is(err, sharedsec.ErrUseDPoPNonce):
+			return ErrUseDPoPNonce
+		case errors.Is(err, sharedsec.ErrInvalidDPoPProof):
+			return ErrInvalidDPoPProof
+		}
+		return ErrInvalidToken
+	}
+	if err := sharedsec.VerifyCertificateBinding(r, claims); err != nil {
+		log.Printf("[AuthService] certificate binding rejected: %v", err)
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// verifyAccessToken memvalidasi access token milik auth-service ini (signature, typ,
+// denylist jti, belum di-revoke) dan opsional mewajibkan satu scope.
+func (s *AuthService) verifyAccessToken(ctx context.Context, accessToken, requiredScope string) (*sharedsec.TokenClaims, error) {
+	claims, err := s.dep.KeyStore.Verify(accessToken)
+	if err != nil || claims.Type != "access" {
+		return nil, ErrInvalidToken
+	}
+	if s.jtiRevoked(ctx, claims) {
+		return nil, ErrInvalidToken
+	}
+	// token yang sudah di-revoke tidak lagi ada di repo
+	if t, err := s.dep.TokenRepo.FindByAccessToken(ctx, accessToken); err != nil || t == nil {
+		return nil, ErrInvalidToken
+	}
+	if requiredScope != "" && !hasScope(claims.Scope, requiredScope) {
+		return nil, ErrInsufficientScope
+	}
+	return claims, nil
+}
+
+/************** REGISTRATION (RFC 7591 / 7592) **************/
+
+func (s *AuthService) RegisterClient(ctx context.Context, md ClientMetadata) (*ClientInformation, error) {
+	if err := validateClientMetadata(&md); err != nil {
+		return nil, err
+	}
+
+	clientID, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &entities.OAuthClient{ClientID: clientID, CreatedAt: time.Now()}
+	applyClientMetadata(c, md)
+
+	if err := s.dep.ClientRepo.Create(ctx, c); err != nil {
+		return nil, err
+	}
+
+	info := s.clientInformation(c)
+	if usesClientSecret(md.TokenEndpointAuthMethod) {
+		secret, sec, err := s.newClientSecret(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		info.ClientSecret = secret
+		info.ClientSecretExpiresAt = secretExpiresAt(sec)
+	}
+	return info, nil
+}
+
+func (s *AuthService) GetClient(ctx context.Context, clientID string) (*ClientInformation, error) {
+	c, err := s.dep.ClientRepo.FindByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, ErrClientNotFound
+	}
+
+	info := s.clientInformation(c)
+	active, err := s.dep.ClientSecrets.ListActive(ctx, c.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if len(active) > 0 {
+		info.ClientSecretExpiresAt = secretExpiresAt(active[0])
+	}
+	return info, nil
+}
+
+// UpdateClient mengganti seluruh metadata client (RFC 7592 PUT); secret tidak berubah.
+// Pindah ke client_secret_jwt perlu rotasi secret agar salinan terenkripsinya tersedia.
+func (s *AuthService) UpdateClient(ctx context.Context, clientID string, md ClientMetadata) (*ClientInformation, error) {
+	c, err := s.dep.ClientRepo.FindByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, ErrClientNotFound
+	}
+	if err := validateClientMetadata(&md); err != nil {
+		return nil, err
+	}
+
+	applyClientMetadata(c, md)
+	if err := s.dep.ClientRepo.Update(ctx, c); err != nil {
+		return nil, mapClientRepoErr(err)
+	}
+
+	// client public / private_key_jwt tidak boleh menyimpan secret lama
+	if !usesClientSecret(md.TokenEndpointAuthMethod) {
+		if err := s.revokeClientSecrets(ctx, c); err != nil {
+			return nil, mapClientRepoErr(err)
+		}
+	}
+	return s.clientInformation(c), nil
+}
+
+func (s *AuthService) DeleteClient(ctx context.Context, clientID string) error {
+	return mapClientRepoErr(s.dep.ClientRepo.SoftDelete(ctx, clientID))
+}
+
+// RotateClientSecret membuat secret baru; secret lama masih berlaku selama masa rotasi.
+func (s *AuthService) RotateClientSecret(ctx context.Context, clientID string) (*ClientInformation, error) {
+	c, err := s.dep.ClientRepo.FindByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, ErrClientNotFound
+	}
+	if !usesClientSecret(c.AuthMethod) {
+		return nil, newOAuthError("invalid_client_metadata", "client does not use a client secret")
+	}
+
+	secret, sec, err := s.rotateClientSecret(ctx, c)
+	if err != nil {
+		return nil, mapClientRepoErr(err)
+	}
+
+	info := s.clientInformation(c)
+	info.ClientSecret = secret
+	info.ClientSecretExpiresAt = secretExpiresAt(sec)
+	return info, nil
+}
+
+func (s *AuthService) clientInformation(c *entities.OAuthClient) *ClientInformation {
+	md := ClientMetadata{
+		RedirectURIs:            c.RedirectURIs,
+		GrantTypes:              c.GrantTypes,
+		Scope:                   optionalString(c.Scopes),
+		TokenEndpointAuthMethod: c.AuthMethod,
+		TokenEndpointAuthAlg:    optionalString(c.AuthAlg),
+		JWKSURI:                 optionalString(c.JWKSURI),
+		ClientName:              optionalString(c.Name),
+		CompanyID:               optionalString(c.CompanyID),
+		PostLogoutRedirectURIs:  c.PostLogoutRedirectURIs,
+		BackchannelLogoutURI:    optionalString(c.BackchannelLogoutURI),
+
+		RequirePushedAuthorizationRequests: c.RequirePAR,
+		RequireSignedRequestObject:         c.RequireSignedRequestObject,
+		RequestObjectSigningAlg:            optionalString(c.RequestObjectAlg),
+		DPoPBoundAccessTokens:              c.DPoPBound,
+
+		TLSClientCertificateBoundAccessTokens: c.CertBound,
+	}
+	md.setTLSClientAuthAttribute(optionalString(c.TLSAuthAttr), optionalString(c.TLSAuthValue))
+	if c.JWKS != nil {
+		md.JWKS = json.RawMessage(*c.JWKS)
+	}
+	if c.AccessTTL != nil {
+		md.AccessTokenTTL = int64(c.AccessTTL.Seconds())
+	}
+	if c.RefreshTTL != nil {
+		md.RefreshTokenTTL = int64(c.RefreshTTL.Seconds())
+	}
+	return &ClientInformation{
+		ClientID:              c.ClientID,
+		ClientIDIssuedAt:      c.CreatedAt.Unix(),
+		RegistrationClientURI: strings.TrimRight(s.dep.PublicURL, "/") + "/oauth/register/" + c.ClientID,
+		ClientMetadata:        md,
+	}
+}
+
+func applyClientMetadata(c *entities.OAuthClient, md ClientMetadata) {
+	c.Name = strptr(md.ClientName)
+	c.RedirectURIs = md.RedirectURIs
+	c.PostLogoutRedirectURIs = md.PostLogoutRedirectURIs
+	c.BackchannelLogoutURI = strptr(md.BackchannelLogoutURI)
+	c.GrantTypes = md.GrantTypes
+	c.Scopes = strptr(md.Scope)
+	c.AuthMethod = md.TokenEndpointAuthMethod
+	c.AuthAlg = strptr(md.TokenEndpointAuthAlg)
+	c.JWKSURI = strptr(md.JWKSURI)
+	c.JWKS = nil
+	if len(md.JWKS) > 0 {
+		jwks := string(md.JWKS)
+		c.JWKS = &jwks
+	}
+	c.CompanyID = strptr(md.CompanyID)
+	c.RequirePAR = md.RequirePushedAuthorizationRequests
+	c.RequireSignedRequestObject = md.RequireSignedRequestObject
+	c.RequestObjectAlg = strptr(md.RequestObjectSigningAlg)
+	c.DPoPBound = md.DPoPBoundAccessTokens
+	attr, value, _ := md.tlsClientAuthAttribute()
+	c.TLSAuthAttr, c.TLSAuthValue = strptr(attr), strptr(value)
+	c.CertBound = md.TLSClientCertificateBoundAccessTokens
+	c.AccessTTL = secondsPtr(md.AccessTokenTTL)
+	c.RefreshTTL = secondsPtr(md.RefreshTokenTTL)
+}
+
+// validateClientMetadata mengisi default dan memvalidasi metadata (error RFC 7591).
+func validateClientMetadata(md *ClientMetadata) error {
+	if len(md.GrantTypes) == 0 {
+		md.GrantTypes = []string{"authorization_code", "refresh_token"}
+	}
+	if md.TokenEndpointAuthMethod == "" {
+		md.TokenEndpointAuthMethod = AuthMethodSecretBasic
+	}
+
+	for _, gt := range md.GrantTypes {
+		if !containsString(supportedGrantTypes, gt) {
+			return newOAuthError("invalid_client_metadata", "unsupported grant_type: "+gt)
+		}
+	}
+	if !containsString(supportedAuthMethods, md.TokenEndpointAuthMethod) {
+		return newOAuthError("invalid_client_metadata", "unsupported token_endpoint_auth_method")
+	}
+	if md.TokenEndpointAuthMethod == AuthMethodNone && containsString(md.GrantTypes, "client_credentials") {
+		return newOAuthError("invalid_client_metadata", "client_credentials requires a confidential client")
+	}
+	for _, sc := range strings.Fields(md.Scope) {
+		if !validScopeToken(sc) {
+			return newOAuthError("invalid_client_metadata", "malformed scope: "+sc)
+		}
+	}
+	if err := validateClientKeys(md); err != nil {
+		return err
+	}
+	if md.AccessTokenTTL < 0 || md.RefreshTokenTTL < 0 {
+		return newOAuthError("invalid_client_metadata", "token ttl must be positive")
+	}
+
+	if containsString(md.GrantTypes, "authorization_code") && len(md.RedirectURIs) == 0 {
+		return newOAuthError("invalid_redirect_uri", "redirect_uris required for authorization_code")
+	}
+	for _, ru := range md.RedirectURIs {
+		if err := validateRedirectURI(ru); err != nil {
+			return err
+		}
+	}
+	for _, ru := range md.PostLogoutRedirectURIs {
+		if err := validateRedirectURI(ru); err != nil {
+			return err
+		}
+	}
+	if md.BackchannelLogoutURI != "" {
+		// dipanggil server-to-server: wajib https (http hanya localhost), tanpa fragment
+		u, err := url.Parse(md.BackchannelLogoutURI)
+		if err != nil || u.Host == "" || u.Fragment != "" || (u.Scheme != "https" && !(u.Scheme == "http" && isLoopbackHost(u.Hostname()))) {
+			return newOAuthError("invalid_client_metadata", "backchannel_logout_uri must be an https url")
+		}
+	}
+	return nil
+}
+
+// validateClientKeys — metadata untuk autentikasi JWT assertion (RFC 7523)
+func validateClientKeys(md *ClientMetadata) error {
+	hasJWKS, hasURI := len(md.JWKS) > 0, md.JWKSURI != ""
+	if hasJWKS && hasURI {
+		return newOAuthError("invalid_client_metadata", "jwks and jwks_uri are mutually exclusive")
+	}
+
+	switch md.TokenEndpointAuthMethod {
+	case AuthMethodPrivateKeyJWT:
+		if !hasJWKS && !hasURI {
+			return newOAuthError("invalid_client_metadata", "private_key_jwt requires jwks or jwks_uri")
+		}
+		if md.TokenEndpointAuthAlg != "" && !containsString(asymmetricAssertionAlgs, md.TokenEndpointAuthAlg) {
+			return newOAuthError("invalid_client_metadata", "unsupported token_endpoint_auth_signing_alg")
+		}
+	case AuthMethodSecretJWT:
+		if md.TokenEndpointAuthAlg != "" && !containsString(hmacAssertionAlgs, md.TokenEndpointAuthAlg) {
+			return newOAuthError("invalid_client_metadata", "unsupported token_endpoint_auth_signing_alg")
+		}
+	default:
+		if md.TokenEndpointAuthAlg != "" {
+			return newOAuthError("invalid_client_metadata", "token_endpoint_auth_signing_alg requires a jwt auth method")
+		}
+	}
+
+	// request object diverifikasi dengan key client
+	if md.RequestObjectSigningAlg != "" && !containsString(asymmetricAssertionAlgs, md.RequestObjectSigningAlg) {
+		return newOAuthError("invalid_client_metadata", "unsupported request_object_signing_alg")
+	}
+	if (md.RequireSignedRequestObject || md.RequestObjectSigningAlg != "") && !hasJWKS && !hasURI {
+		return newOAuthError("invalid_client_metadata", "signed request objects require jwks or jwks_uri")
+	}
+	if err := validateClientTLS(md, hasJWKS || hasURI); err != nil {
+		return err
+	}
+
+	if hasJWKS {
+		if _, err := sharedsec.ParseJWKSet(md.JWKS); err != nil {
+			return newOAuthError("invalid_client_metadata", "invalid jwks: "+err.Error())
+		}
+	}
+	if hasURI {
+		u, err := url.Parse(md.JWKSURI)
+		if err != nil || u.Host == "" || (u.Scheme != "https" && !(u.Scheme == "http" && isLoopbackHost(u.Hostname()))) {
+			return newOAuthError("invalid_client_metadata", "jwks_uri must be an https url")
+		}
+	}
+	return nil
+}
+
+func isLoopbackHost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
+
+// redirect URI: absolut, tanpa fragment; http hanya untuk localhost. Skema custom (app native) boleh.
+func validateRedirectURI(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Fragment != "" {
+		return newOAuthError("invalid_redirect_uri", "invalid redirect_uri: "+raw)
+	}
+	if u.Scheme == "http" {
+		if !isLoopbackHost(u.Hostname()) {
+			return newOAuthError("invalid_redirect_uri", "http redirect_uri only allowed for localhost: "+raw)
+		}
+	}
+	if (u.Scheme == "http" || u.Scheme == "https") && u.Host == "" {
+		return newOAuthError("invalid_redirect_uri", "invalid redirect_uri: "+raw)
+	}
+	return nil
+}
+
+/************** GRANT / TTL POLICY **************/
+
+// clientAllowsGrant — client lama tanpa grant_types diizinkan semua grant
+func clientAllowsGrant(c *entities.OAuthClient, grantType string) bool {
+	return len(c.GrantTypes) == 0 || containsString(c.GrantTypes, grantType)
+}
+
+func (s *AuthService) accessTTL(c *entities.OAuthClient) time.Duration {
+	if c != nil && c.AccessTTL != nil && *c.AccessTTL > 0 {
+		return *c.AccessTTL
+	}
+	return s.dep.AccessTTL
+}
+
+func (s *AuthService) refreshTTL(c *entities.OAuthClient) time.Duration {
+	if c != nil && c.RefreshTTL != nil && *c.RefreshTTL > 0 {
+		return *c.RefreshTTL
+	}
+	return s.dep.RefreshTTL
+}
+
+/************** helpers **************/
+
+func mapClientRepoErr(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrClientNotFound
+	}
+	return err
+}
+
+func containsString(list []string, v string) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func secondsPtr(sec int64) *time.Duration {
+	if sec <= 0 {
+		return nil
+	}
+	d := time.Duration(sec) * time.Second
+	return &d
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func randomSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"bkc_microservice/services/auth-service/internal/domain/entities"
+)
+
+var (
+	ErrConsentNotFound = errors.New("consent not found")
+
+	ErrConsentRequired = newOAuthError("consent_required", "end-user consent is required")
+)
+
+// ConsentStatus — scope efektif permintaan authorize dibandingkan consent tersimpan.
+type ConsentStatus struct {
+	Scopes  []string // scope efektif (sudah difilter kebijakan client / user)
+	Granted []string // sudah pernah disetujui
+	Missing []string // perlu persetujuan baru (incremental consent)
+}
+
+// ConsentInfo — consent milik user untuk endpoint /oauth/consents.
+type ConsentInfo struct {
+	ID         string    `json:"id"`
+	ClientID   string    `json:"client_id"`
+	ClientName string    `json:"client_name,omitempty"`
+	TenantID   string    `json:"tenant_id,omitempty"`
+	Scopes     []string  `json:"scopes"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// CheckConsent menghitung scope yang belum disetujui user untuk client + tenant.
+func (s *AuthService) CheckConsent(ctx context.Context, userID, clientID, scope, companyID string) (*ConsentStatus, error) {
+	c, tenant, scopes, err := s.consentTarget(ctx, userID, clientID, scope, companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &ConsentStatus{Scopes: scopes}
+	if s.dep.ConsentRepo == nil {
+		st.Missing = scopes
+		return st, nil
+	}
+	existing, err := s.dep.ConsentRepo.Find(ctx, userID, c.ID, tenant)
+	if err != nil {
+		return nil, err
+	}
+	for _, sc := range scopes {
+		if existing != nil && hasScope(existing.Scopes, sc) {
+			st.Granted = append(st.Granted, sc)
+		} else {
+			st.Missing = append(st.Missing, sc)
+		}
+	}
+	return st, nil
+}
+
+// GrantConsent menyimpan persetujuan user; scope baru digabung dengan consent lama.
+func (s *AuthService) GrantConsent(ctx context.Context, userID, clientID, scope, companyID string) error {
+	if s.dep.ConsentRepo == nil {
+		return nil
+	}
+	c, tenant, scopes, err := s.consentTarget(ctx, userID, clientID, scope, companyID)
+	if err != nil {
+		return err
+	}
+
+	existing, err := s.dep.ConsentRepo.Find(ctx, userID, c.ID, tenant)
+	if err != nil {
+		return err
+	}
+	merged := scopes
+	if existing != nil {
+		merged = strings.Fields(existing.Scopes)
+		for _, sc := range scopes {
+			if !containsString(merged, sc) {
+				merged = append(merged, sc)
+			}
+		}
+	}
+
+	return s.dep.ConsentRepo.Upsert(ctx, &entities.Consent{
+		UserID:    userID,
+		ClientID:  c.ID,
+		CompanyID: tenant,
+		Scopes:    strings.Join(merged, " "),
+	})
+}
+
+// ListConsents mengembalikan consent yang pernah diberikan user.
+func (s *AuthService) ListConsents(ctx context.Context, userID string) ([]*ConsentInfo, error) {
+	if s.dep.ConsentRepo == nil {
+		return []*ConsentInfo{}, nil
+	}
+	list, err := s.dep.ConsentRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*ConsentInfo, 0, len(list))
+	for _, c := range list {
+		out = append(out, &ConsentInfo{
+			ID:         c.ID,
+			ClientID:   c.ClientPublicID,
+			ClientName: optionalString(c.ClientName),
+			TenantID:   c.CompanyID,
+			Scopes:     strings.Fields(c.Scopes),
+			CreatedAt:  c.CreatedAt,
+			UpdatedAt:  c.UpdatedAt,
+		})
+	}
+	return out, nil
+}
+
+// RevokeConsent menghapus consent user dan mencabut token client tersebut untuk user
+// pada tenant yang sama.
+func (s *AuthService) RevokeConsent(ctx context.Context, userID, consentID string) error {
+	if s.dep.ConsentRepo == nil {
+		return ErrConsentNotFound
+	}
+	c, err := s.dep.ConsentRepo.FindByID(ctx, consentID)
+	if err != nil {
+		return err
+	}
+	if c == nil || c.UserID != userID {
+		return ErrConsentNotFound
+	}
+
+	if err := s.dep.ConsentRepo.Delete(ctx, c.ID); err != nil {
+		return err
+	}
+	revoked, err := s.dep.TokenRepo.RevokeByUserClient(ctx, userID, c.ClientID, c.CompanyID)
+	if err != nil {
+		return err
+	}
+	s.denyRevoked(ctx, revoked)
+	s.auditRevokedTokens(ctx, entities.TokenAudit{
+		CompanyID: strptr(c.CompanyID),
+		ClientID:  strptr(c.ClientID),
+		UserID:    &userID,
+	}, AuditReasonConsentRevoked, revoked)
+	log.Printf("[AuthService] consent revoked user=%s client=%s tenant=%s", userID, c.ClientID, c.CompanyID)
+	return nil
+}
+
+// consentTarget menerapkan aturan scope & tenant yang sama dengan StartAuthorizationCode.
+func (s *AuthService) consentTarget(ctx context.Context, userID, clientID, scope, companyID string) (*entities.OAuthClient, string, []string, error) {
+	c, err := s.dep.ClientRepo.FindByClientID(ctx, clientID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if c == nil {
+		return nil, "", nil, errors.New("invalid client")
+	}
+	scope, err = s.resolveScopes(ctx, c, userID, scope)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	tenant, err := s.pickCompanyID(companyID, c)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return c, tenant, strings.Fields(scope), nil
+}
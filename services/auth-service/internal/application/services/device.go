@@ -0,0 +1,357 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Device Authorization Grant (RFC 8628) untuk kiosk cabang dan CLI tanpa browser.
+
+const (
+	GrantTypeDeviceCode = "urn:ietf:params:oauth:grant-type:device_code"
+
+	deviceCodeTTL      = 10 * time.Minute
+	devicePollInterval = 5 * time.Second
+
+	deviceStatusPending  = "pending"
+	deviceStatusApproved = "approved"
+	deviceStatusDenied   = "denied"
+
+	// tanpa huruf vokal / karakter mirip (RFC 8628 §6.1)
+	userCodeCharset = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength  = 8
+)
+
+var (
+	ErrInvalidUserCode = errors.New("invalid or expired user code")
+
+	ErrAuthorizationPending = newOAuthError("authorization_pending", "the user has not yet completed authorization")
+	ErrSlowDown             = newOAuthError("slow_down", "polling too frequently")
+	ErrExpiredToken         = newOAuthError("expired_token", "the device_code has expired")
+	ErrInvalidDeviceCode    = newOAuthError("invalid_grant", "invalid device_code")
+)
+
+// DeviceAuthorizationResponse — respons /oauth/device_authorization (RFC 8628 §3.2).
+type DeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval"`
+}
+
+// DeviceRequest — ringkasan permintaan device untuk halaman verifikasi.
+type DeviceRequest struct {
+	UserCode   string
+	ClientID   string
+	ClientName string
+	Scopes     []string
+}
+
+// deviceGrant disimpan di Redis sampai di-poll sukses atau kedaluwarsa.
+type deviceGrant struct {
+	ClientID  string     `json:"clientId"` // client_id publik
+	Scope     string     `json:"scope"`
+	TenantID  string     `json:"tenantId"`
+	Resources []string   `json:"resources,omitempty"` // RFC 8707
+	UserCode  string     `json:"userCode"`
+	Status    string     `json:"status"`
+	UserID    string     `json:"userId,omitempty"`
+	AuthTime  *time.Time `json:"authTime,omitempty"`
+	Interval  int64      `json:"interval"` // detik; naik 5 detik setiap slow_down
+	ExpiresAt time.Time  `json:"expiresAt"`
+}
+
+func deviceCodeKey(deviceCode string) string {
+	sum := sha256.Sum256([]byte(deviceCode))
+	return "device:code:" + hex.EncodeToString(sum[:])
+}
+func deviceUserCodeKey(userCode string) string { return "device:user:" + userCode }
+func devicePollKey(codeKey string) string      { return "device:poll:" + codeKey }
+
+// StartDeviceAuthorization menerbitkan device_code + user_code untuk client.
+func (s *AuthService) StartDeviceAuthorization(ctx context.Context, auth ClientAuth, scope, companyID string, resources []string) (*DeviceAuthorizationResponse, error) {
+	if s.dep.RDB == nil {
+		return nil, errors.New("device authorization unavailable")
+	}
+	c, err := s.AuthenticateClient(ctx, auth)
+	if err != nil {
+		return nil, err
+	}
+	if !clientAllowsGrant(c, GrantTypeDeviceCode) {
+		return nil, ErrUnauthorizedClient
+	}
+	scope, err = s.resolveScopes(ctx, c, "", scope)
+	if err != nil {
+		return nil, err
+	}
+	resources, scope, err = s.resolveResources(ctx, resources, scope)
+	if err != nil {
+		return nil, err
+	}
+	compID, err := s.pickCompanyID(companyID, c)
+	if err != nil {
+		return nil, err
+	}
+
+	deviceCode, err := randomSecret()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	g := deviceGrant{
+		ClientID:  c.ClientID,
+		Scope:     scope,
+		TenantID:  compID,
+		Resources: resources,
+		Status:    deviceStatusPending,
+		Interval:  int64(devicePollInterval.Seconds()),
+		ExpiresAt: now.Add(deviceCodeTTL),
+	}
+
+	// user_code harus unik selama masa berlaku
+	for i := 0; ; i++ {
+		if g.UserCode, err = newUserCode(); err != nil {
+			return nil, err
+		}
+		ok, err := s.dep.RDB.SetNX(ctx, deviceUserCodeKey(g.UserCode), deviceCodeKey(deviceCode), deviceCodeTTL).Result()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			break
+		}
+		if i >= 5 {
+			return nil, errors.New("failed to allocate user code")
+		}
+	}
+	if err := s.saveDeviceGrant(ctx, deviceCodeKey(deviceCode), &g); err != nil {
+		return nil, err
+	}
+
+	base := strings.TrimRight(s.dep.PublicURL, "/")
+	return &DeviceAuthorizationResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                formatUserCode(g.UserCode),
+		VerificationURI:         base + "/oauth/device",
+		VerificationURIComplete: base + "/oauth/device?user_code=" + formatUserCode(g.UserCode),
+		ExpiresIn:               int64(deviceCodeTTL.Seconds()),
+		Interval:                g.Interval,
+	}, nil
+}
+
+// LookupDeviceRequest dipakai halaman verifikasi untuk menampilkan client + scope.
+func (s *AuthService) LookupDeviceRequest(ctx context.Context, userCode string) (*DeviceRequest, error) {
+	_, g, err := s.deviceGrantByUserCode(ctx, userCode)
+	if err != nil {
+		return nil, err
+	}
+	name := g.ClientID
+	if c, err := s.dep.ClientRepo.FindByClientID(ctx, g.ClientID); err == nil && c != nil && c.Name != nil && *c.Name != "" {
+		name = *c.Name
+	}
+	return &DeviceRequest{
+		UserCode:   formatUserCode(g.UserCode),
+		ClientID:   g.ClientID,
+		ClientName: name,
+		Scopes:     strings.Fields(g.Scope),
+	}, nil
+}
+
+// DecideDeviceRequest menyimpan keputusan user (sesi login) atas user_code.
+// Persetujuan juga dicatat sebagai consent.
+func (s *AuthService) DecideDeviceRequest(ctx context.Context, sess *AuthSession, userCode string, approve bool) error {
+	key, g, err := s.deviceGrantByUserCode(ctx, userCode)
+	if err != nil {
+		return err
+	}
+	// user_code sekali pakai
+	_ = s.dep.RDB.Del(ctx, deviceUserCodeKey(g.UserCode)).Err()
+
+	if !approve {
+		g.Status = deviceStatusDenied
+		return s.saveDeviceGrant(ctx, key, g)
+	}
+
+	c, err := s.dep.ClientRepo.FindByClientID(ctx, g.ClientID)
+	if err != nil || c == nil {
+		return ErrInvalidUserCode
+	}
+	// scope terbatas hanya untuk user yang punya grant-nya
+	scope, err := s.resolveScopes(ctx, c, sess.UserID, g.Scope)
+	if err != nil {
+		return err
+	}
+	if err := s.GrantConsent(ctx, sess.UserID, g.ClientID, scope, g.TenantID); err != nil {
+		return err
+	}
+
+	authTime := sess.AuthenticatedAt()
+	g.Status = deviceStatusApproved
+	g.Scope = scope
+	g.UserID = sess.UserID
+	g.AuthTime = &authTime
+	log.Printf("[AuthService] device approved user=%s client=%s", sess.UserID, g.ClientID)
+	return s.saveDeviceGrant(ctx, key, g)
+}
+
+// ExchangeDeviceCode — grant device_code: polling client sampai user menyetujui.
+func (s *AuthService) ExchangeDeviceCode(ctx context.Context, auth ClientAuth, deviceCode string) (*TokenResponse, error) {
+	c, err := s.AuthenticateClient(ctx, auth)
+	if err != nil {
+		return nil, err
+	}
+	if !clientAllowsGrant(c, GrantTypeDeviceCode) {
+		return nil, ErrUnauthorizedClient
+	}
+	if s.dep.RDB == nil || deviceCode == "" {
+		return nil, ErrInvalidDeviceCode
+	}
+
+	key := deviceCodeKey(deviceCode)
+	g, err := s.loadDeviceGrant(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if g.ClientID != c.ClientID {
+		return nil, ErrInvalidDeviceCode
+	}
+
+	// rate limit per device: poll sebelum interval habis => slow_down (+5 detik)
+	ok, err := s.dep.RDB.SetNX(ctx, devicePollKey(key), "1", time.Duration(g.Interval)*time.Second).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		g.Interval += int64(devicePollInterval.Seconds())
+		_ = s.saveDeviceGrant(ctx, key, g)
+		return nil, ErrSlowDown
+	}
+
+	switch g.Status {
+	case deviceStatusPending:
+		return nil, ErrAuthorizationPending
+	case deviceStatusDenied:
+		_ = s.dep.RDB.Del(ctx, key, devicePollKey(key)).Err()
+		return nil, ErrAccessDenied
+	}
+
+	// device_code sekali pakai
+	n, err := s.dep.RDB.Del(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	if n != 1 {
+		return nil, ErrInvalidDeviceCode
+	}
+
+	u, err := s.dep.UserRepo.FindByID(ctx, g.UserID)
+	if err != nil || u == nil {
+		return nil, ErrInvalidDeviceCode
+	}
+	if err := s.requireMFA(ctx, u, mfaChallenge{
+		UserID:    g.UserID,
+		ClientID:  c.ClientID,
+		Scope:     g.Scope,
+		TenantID:  g.TenantID,
+		AuthTime:  g.AuthTime,
+		Resources: g.Resources,
+	}); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(ctx, tokenRequest{
+		Client:      c,
+		GrantType:   GrantTypeDeviceCode,
+		UserID:      g.UserID,
+		Scope:       g.Scope,
+		TenantID:    g.TenantID,
+		WithRefresh: clientAllowsGrant(c, "refresh_token"),
+		AuthTime:    g.AuthTime,
+		Resources:   g.Resources,
+	})
+}
+
+func (s *AuthService) deviceGrantByUserCode(ctx context.Context, userCode string) (string, *deviceGrant, error) {
+	if s.dep.RDB == nil {
+		return "", nil, ErrInvalidUserCode
+	}
+	key, err := s.dep.RDB.Get(ctx, deviceUserCodeKey(normalizeUserCode(userCode))).Result()
+	if err != nil {
+		return "", nil, ErrInvalidUserCode
+	}
+	g, err := s.loadDeviceGrant(ctx, key)
+	if err != nil || g.Status != deviceStatusPending {
+		return "", nil, ErrInvalidUserCode
+	}
+	return key, g, nil
+}
+
+func (s *AuthService) loadDeviceGrant(ctx context.Context, key string) (*deviceGrant, error) {
+	raw, err := s.dep.RDB.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrExpiredToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	var g deviceGrant
+	if err := json.Unmarshal(raw, &g); err != nil {
+		return nil, ErrInvalidDeviceCode
+	}
+	return &g, nil
+}
+
+// saveDeviceGrant menyimpan grant dengan sisa masa berlaku device_code.
+func (s *AuthService) saveDeviceGrant(ctx context.Context, key string, g *deviceGrant) error {
+	ttl := time.Until(g.ExpiresAt)
+	if ttl <= 0 {
+		return ErrExpiredToken
+	}
+	raw, err := json.Marshal(g)
+	if err != nil {
+		return err
+	}
+	return s.dep.RDB.Set(ctx, key, raw, ttl).Err()
+}
+
+func newUserCode() (string, error) {
+	buf := make([]byte, userCodeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	out := make([]byte, userCodeLength)
+	for i, b := range buf {
+		// 256 % 20 != 0: bias kecil dapat diterima untuk kode berumur 10 menit
+		out[i] = userCodeCharset[int(b)%len(userCodeCharset)]
+	}
+	return string(out), nil
+}
+
+// normalizeUserCode — input user boleh huruf kecil / dengan tanda hubung atau spasi.
+func normalizeUserCode(v string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(v) {
+		if strings.ContainsRune(userCodeCharset, r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func formatUserCode(code string) string {
+	if len(code) != userCodeLength {
+		return code
+	}
+	return code[:4] + "-" + code[4:]
+}
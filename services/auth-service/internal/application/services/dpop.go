@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	sharedsec "bkc_microservice/shared/security"
+)
+
+// DPoP (RFC 9449) di token endpoint: proof di header "DPoP" diverifikasi handler,
+// thumbprint key-nya dibawa lewat context ke issueTokens lalu disematkan sebagai
+// cnf.jkt access token (lihat tokenConfirmation). Refresh token dari request ber-DPoP
+// di-bind ke key yang sama.
+
+var (
+	ErrInvalidDPoPProof = newOAuthError("invalid_dpop_proof", "DPoP proof is invalid")
+	ErrUseDPoPNonce     = newOAuthError("use_dpop_nonce", "authorization server requires nonce in DPoP proof")
+	ErrDPoPRequired     = newOAuthError("invalid_dpop_proof", "DPoP proof is required for this client")
+)
+
+type dpopKeyCtx struct{}
+
+// WithDPoPKey menandai request token sebagai DPoP dengan thumbprint key jkt.
+func WithDPoPKey(ctx context.Context, jkt string) context.Context {
+	if jkt == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, dpopKeyCtx{}, jkt)
+}
+
+func dpopKeyFromContext(ctx context.Context) string {
+	jkt, _ := ctx.Value(dpopKeyCtx{}).(string)
+	return jkt
+}
+
+// VerifyTokenDPoP memverifikasi header DPoP request /oauth/token dan mengembalikan
+// thumbprint key-nya; tanpa header => "" (token bearer biasa).
+func (s *AuthService) VerifyTokenDPoP(ctx context.Context, proofs []string) (string, error) {
+	switch {
+	case len(proofs) == 0:
+		return "", nil
+	case len(proofs) > 1 || s.dep.DPoP == nil:
+		return "", ErrInvalidDPoPProof
+	}
+	htu := strings.TrimRight(s.dep.PublicURL, "/") + "/oauth/token"
+	p, err := s.dep.DPoP.Verify(ctx, proofs[0], http.MethodPost, htu, "")
+	if errors.Is(err, sharedsec.ErrUseDPoPNonce) {
+		return "", ErrUseDPoPNonce
+	}
+	if err != nil {
+		log.Printf("[AuthService] dpop proof rejected: %v", err)
+		return "", ErrInvalidDPoPProof
+	}
+	return p.JKT, nil
+}
+
+// DPoPNonce — nonce baru untuk header DPoP-Nonce; "" bila nonce tidak diwajibkan.
+func (s *AuthService) DPoPNonce() string {
+	return s.dep.DPoP.Nonce()
+}
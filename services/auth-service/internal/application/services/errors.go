@@ -0,0 +1,18 @@
+package services
+
+// OAuthError adalah error protokol OAuth2 ({"error": ..., "error_description": ...}).
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
+
+func newOAuthError(code, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
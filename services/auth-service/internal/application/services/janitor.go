@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrJanitorBusy = errors.New("cleanup already running")
+
+const (
+	janitorLockKey  = "auth:janitor:lock"
+	janitorStatsKey = "auth:janitor:last_run"
+
+	defaultCleanupBatch     = 1000
+	defaultRevokedRetention = 30 * 24 * time.Hour
+	janitorBatchPause       = 50 * time.Millisecond // jeda antar batch supaya tidak menahan lock tabel terus-menerus
+	janitorMaxRunTime       = 10 * time.Minute      // TTL lock; run yang lebih lama dihentikan
+)
+
+// unlockScript hanya menghapus lock milik pemegangnya (token acak)
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+  return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// CleanupRun — hasil satu putaran janitor; run terakhir disimpan di Redis supaya bisa
+// dibaca dari replica mana pun.
+type CleanupRun struct {
+	Trigger       string    `json:"trigger"` // "schedule" | "manual"
+	StartedAt     time.Time `json:"startedAt"`
+	FinishedAt    time.Time `json:"finishedAt"`
+	DurationMS    int64     `json:"durationMs"`
+	AuthCodes     int64     `json:"authCodes"`
+	RefreshTokens int64     `json:"refreshTokens"`
+	AccessTokens  int64     `json:"accessTokens"`
+	Batches       int       `json:"batches"`
+	Error         string    `json:"error,omitempty"`
+	Completed     bool      `json:"completed"` // false => berhenti karena error / waktu habis
+}
+
+// RunJanitor menjalankan cleanup setiap interval sampai ctx selesai. Hanya satu replica
+// yang membersihkan per putaran (lock Redis); replica lain melewati putaran itu.
+func (s *AuthService) RunJanitor(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		run, err := s.CleanupExpired(ctx, "schedule")
+		switch {
+		case errors.Is(err, ErrJanitorBusy):
+			continue
+		case err != nil:
+			log.Printf("[Janitor] cleanup failed: %v", err)
+		default:
+			log.Printf("[Janitor] removed auth_codes=%d refresh=%d access=%d in %dms",
+				run.AuthCodes, run.RefreshTokens, run.AccessTokens, run.DurationMS)
+		}
+	}
+}
+
+// CleanupExpired menghapus auth code, refresh token dan access token kedaluwarsa / dicabut
+// per batch. ErrJanitorBusy jika replica lain sedang membersihkan.
+func (s *AuthService) CleanupExpired(ctx context.Context, trigger string) (*CleanupRun, error) {
+	lockToken, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.dep.RDB.SetNX(ctx, janitorLockKey, lockToken, janitorMaxRunTime).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrJanitorBusy
+	}
+	defer func() {
+		// lock dilepas dengan context baru: ctx bisa sudah dibatalkan (shutdown)
+		relCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = unlockScript.Run(relCtx, s.dep.RDB, []string{janitorLockKey}, lockToken).Err()
+	}()
+
+	runCtx, cancel := context.WithTimeout(ctx, janitorMaxRunTime)
+	defer cancel()
+
+	run := &CleanupRun{Trigger: trigger, StartedAt: time.Now().UTC()}
+	err = s.cleanup(runCtx, run)
+	run.FinishedAt = time.Now().UTC()
+	run.DurationMS = run.FinishedAt.Sub(run.StartedAt).Milliseconds()
+	run.Completed = err == nil
+	if err != nil {
+		run.Error = err.Error()
+	}
+
+	if b, mErr := json.Marshal(run); mErr == nil {
+		if sErr := s.dep.RDB.Set(context.Background(), janitorStatsKey, b, 0).Err(); sErr != nil {
+			log.Printf("[Janitor] save run stats failed: %v", sErr)
+		}
+	}
+	return run, err
+}
+
+// LastCleanupRun mengembalikan run janitor terakhir (nil jika belum pernah berjalan).
+func (s *AuthService) LastCleanupRun(ctx context.Context) (*CleanupRun, error) {
+	b, err := s.dep.RDB.Get(ctx, janitorStatsKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var run CleanupRun
+	if err := json.Unmarshal(b, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (s *AuthService) cleanup(ctx context.Context, run *CleanupRun) error {
+	batch := s.dep.CleanupBatchSize
+	if batch <= 0 {
+		batch = defaultCleanupBatch
+	}
+	retention := s.dep.RevokedRetention
+	if retention <= 0 {
+		retention = defaultRevokedRetention
+	}
+	now := time.Now()
+
+	// refresh token lebih dulu: access token hanya dihapus jika tidak lagi dirujuk
+	steps := []struct {
+		count *int64
+		del   func() (int64, error)
+	}{
+		{&run.AuthCodes, func() (int64, error) { return s.dep.CodeRepo.DeleteExpired(ctx, now, batch) }},
+		{&run.RefreshTokens, func() (int64, error) {
+			return s.dep.TokenRepo.DeleteExpiredRefresh(ctx, now, now.Add(-retention), batch)
+		}},
+		{&run.AccessTokens, func() (int64, error) { return s.dep.TokenRepo.DeleteExpiredAccess(ctx, now, batch) }},
+	}
+	for _, st := range steps {
+		for {
+			n, err := st.del()
+			if err != nil {
+				return err
+			}
+			*st.count += n
+			run.Batches++
+			if n < int64(batch) {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(janitorBatchPause):
+			}
+		}
+	}
+	return nil
+}
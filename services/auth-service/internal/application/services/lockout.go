@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"bkc_microservice/services/auth-service/internal/domain/entities"
+	"bkc_microservice/shared/notify"
+)
+
+// Lockout password grant: gagal berturut-turut dihitung per akun (users.failed_login_attempts)
+// dan akun dikunci selama LockoutPolicy.Duration setelah Threshold; di atasnya jeda
+// eksponensial per username+IP di Redis supaya credential stuffing tidak hanya dibatasi
+// rate limit per IP.
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+
+	ErrInvalidUserCredentials = newOAuthError("invalid_grant", "invalid username or password")
+	ErrAccountLockedGrant     = newOAuthError("invalid_grant", "account is locked")
+	ErrAccountDisabled        = newOAuthError("invalid_grant", "account is disabled")
+)
+
+const (
+	eventAccountLocked = "account_locked"
+
+	loginThrottlePrefix = "auth:login:"
+	loginThrottleWindow = time.Hour // hitungan gagal per username+IP hilang setelah diam selama ini
+)
+
+// LockoutPolicy — zero value mematikan lockout dan throttling.
+type LockoutPolicy struct {
+	Threshold    int           // gagal berturut-turut sebelum akun dikunci; 0 => tanpa lockout
+	Duration     time.Duration // lama kunci otomatis
+	ThrottleBase time.Duration // jeda setelah gagal pertama per username+IP; 0 => tanpa throttling
+	ThrottleMax  time.Duration // batas atas jeda
+}
+
+// LoginThrottledError — percobaan login untuk username+IP ini ditolak sampai RetryAfter lewat.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return "login throttled, retry after " + e.RetryAfter.String()
+}
+
+// accountLocked — kunci admin (tanpa locked_until) atau kunci otomatis yang belum lewat.
+func accountLocked(u *entities.User, now time.Time) bool {
+	return u.IsLocked && (u.LockedUntil == nil || now.Before(*u.LockedUntil))
+}
+
+func loginUserHash(username string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(username))))
+	return hex.EncodeToString(sum[:16])
+}
+
+// loginThrottleKey — suffix key Redis "<hash username>:<ip>"; username tidak disimpan mentah.
+func loginThrottleKey(username, ip string) string {
+	return loginUserHash(username) + ":" + ip
+}
+
+// checkLoginThrottle menolak percobaan selama jeda username+IP belum lewat. Redis error
+// tidak memblokir login (rate limit per IP tetap berlaku).
+func (s *AuthService) checkLoginThrottle(ctx context.Context, key string) error {
+	if s.dep.RDB == nil || s.dep.Lockout.ThrottleBase <= 0 {
+		return nil
+	}
+	wait, err := s.dep.RDB.PTTL(ctx, loginThrottlePrefix+"wait:"+key).Result()
+	if err != nil {
+		log.Printf("[AuthService] login throttle lookup failed: %v", err)
+		return nil
+	}
+	if wait > 0 {
+		return &LoginThrottledError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// loginFailed mencatat percobaan gagal: jeda username+IP berikutnya = base * 2^(n-1)
+// (maksimal ThrottleMax) dan, bila user dikenal, hitungan gagal akun.
+func (s *AuthService) loginFailed(ctx context.Context, key string, u *entities.User, c *entities.OAuthClient) {
+	p := s.dep.Lockout
+	if s.dep.RDB != nil && p.ThrottleBase > 0 {
+		failKey := loginThrottlePrefix + "fail:" + key
+		n, err := s.dep.RDB.Incr(ctx, failKey).Result()
+		if err == nil {
+			_ = s.dep.RDB.Expire(ctx, failKey, loginThrottleWindow).Err()
+			delay := p.ThrottleBase
+			for i := int64(1); i < n && (p.ThrottleMax <= 0 || delay < p.ThrottleMax); i++ {
+				delay *= 2
+			}
+			if p.ThrottleMax > 0 && delay > p.ThrottleMax {
+				delay = p.ThrottleMax
+			}
+			err = s.dep.RDB.Set(ctx, loginThrottlePrefix+"wait:"+key, n, delay).Err()
+		}
+		if err != nil {
+			log.Printf("[AuthService] login throttle update failed: %v", err)
+		}
+	}
+
+	if u == nil || p.Threshold <= 0 {
+		return
+	}
+	attempts, lockedUntil, err := s.dep.UserRepo.RecordLoginFailure(ctx, u.ID, p.Threshold, p.Duration)
+	if err != nil {
+		log.Printf("[AuthService] record login failure user=%s failed: %v", u.ID, err)
+		return
+	}
+	if lockedUntil == nil || attempts != p.Threshold {
+		return
+	}
+	log.Printf("[AuthService] account locked user=%s until=%s", u.ID, lockedUntil.Format(time.RFC3339))
+	if s.dep.EventRepo == nil {
+		return
+	}
+	detail := strconv.Itoa(attempts) + " failed password attempts, locked until " + lockedUntil.UTC().Format(time.RFC3339)
+	if err := s.dep.EventRepo.Save(ctx, &entities.SecurityEvent{
+		EventType: eventAccountLocked,
+		CompanyID: c.CompanyID,
+		ClientID:  strptr(c.ID),
+		UserID:    &u.ID,
+		Detail:    &detail,
+	}); err != nil {
+		log.Printf("[AuthService] save security event failed: %v", err)
+	}
+}
+
+// loginSucceeded mereset throttling username+IP dan hitungan gagal akun, serta mencatat last_login.
+func (s *AuthService) loginSucceeded(ctx context.Context, key, userID string) {
+	if s.dep.RDB != nil && s.dep.Lockout.ThrottleBase > 0 {
+		_ = s.dep.RDB.Del(ctx, loginThrottlePrefix+"fail:"+key, loginThrottlePrefix+"wait:"+key).Err()
+	}
+	if err := s.dep.UserRepo.RecordLoginSuccess(ctx, userID); err != nil {
+		log.Printf("[AuthService] record login success user=%s failed: %v", userID, err)
+	}
+}
+
+// notifyAccountLocked memberi tahu pemilik akun (password benar) bahwa login ditolak
+// karena akun terkunci; response token tetap invalid_grant generik.
+func (s *AuthService) notifyAccountLocked(ctx context.Context, u *entities.User) {
+	if s.dep.Notifier == nil {
+		return
+	}
+	body := "Akun Anda sedang dikunci karena terlalu banyak percobaan login gagal."
+	if u.LockedUntil != nil {
+		body += " Coba lagi setelah " + u.LockedUntil.Format(time.RFC3339) + " atau hubungi admin."
+	} else {
+		body += " Hubungi admin untuk membuka kunci."
+	}
+	if err := s.dep.Notifier.Send(ctx, notify.Message{
+		To:      u.Email,
+		Subject: "Login ditolak: akun terkunci",
+		Body:    body,
+	}); err != nil {
+		log.Printf("[AuthService] notify account locked user=%s failed: %v", u.ID, err)
+	}
+}
+
+// UnlockUser (admin) melepas kunci akun, mereset hitungan gagal dan menghapus jeda
+// username+IP milik user (semua IP).
+func (s *AuthService) UnlockUser(ctx context.Context, userID string) error {
+	u, err := s.dep.UserRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return ErrUserNotFound
+	}
+	if err := s.dep.UserRepo.Unlock(ctx, userID); err != nil {
+		return err
+	}
+	if s.dep.RDB != nil {
+		iter := s.dep.RDB.Scan(ctx, 0, loginThrottlePrefix+"*:"+loginUserHash(u.Email)+":*", 1000).Iterator()
+		for iter.Next(ctx) {
+			_ = s.dep.RDB.Del(ctx, iter.Val()).Err()
+		}
+		if err := iter.Err(); err != nil {
+			log.Printf("[AuthService] clear login throttle user=%s failed: %v", userID, err)
+		}
+	}
+	log.Printf("[AuthService] account unlocked user=%s", userID)
+	return nil
+}
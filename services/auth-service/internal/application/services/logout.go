@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"bkc_microservice/services/auth-service/internal/domain/entities"
+	sharedsec "bkc_microservice/shared/security"
+)
+
+// Logout: end_session (OIDC RP-Initiated Logout 1.0), "logout everywhere" (cabut semua
+// token user + jti ke denylist) dan notifikasi Back-Channel Logout 1.0 ke client.
+
+const (
+	logoutTokenTTL     = 2 * time.Minute
+	backchannelTimeout = 5 * time.Second
+)
+
+var (
+	ErrLogoutConfirmationRequired = errors.New("logout_confirmation_required")
+
+	ErrInvalidIDTokenHint        = newOAuthError("invalid_request", "invalid id_token_hint")
+	ErrInvalidPostLogoutRedirect = newOAuthError("invalid_request", "post_logout_redirect_uri is not registered for this client")
+)
+
+// backchannelClient tidak mengikuti redirect (Back-Channel Logout §2.5)
+var backchannelClient = &http.Client{
+	Timeout: backchannelTimeout,
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// LogoutResult — ringkasan logout everywhere.
+type LogoutResult struct {
+	RevokedTokens int      `json:"revokedTokens"`
+	Clients       []string `json:"clients"` // client yang tokennya dicabut
+}
+
+// LogoutEverywhere mencabut semua access/refresh token user (clientID publik kosong =>
+// semua client), memasukkan jti access token yang masih hidup ke denylist, mengakhiri
+// semua sesi browser (hanya bila tanpa clientID) dan mengirim back-channel logout.
+func (s *AuthService) LogoutEverywhere(ctx context.Context, userID, clientID string) (*LogoutResult, error) {
+	var clientUUID string
+	if clientID != "" {
+		c, err := s.dep.ClientRepo.FindByClientID(ctx, clientID)
+		if err != nil {
+			return nil, err
+		}
+		if c == nil {
+			return nil, ErrClientNotFound
+		}
+		clientUUID = c.ID
+	}
+
+	revoked, err := s.dep.TokenRepo.RevokeByUser(ctx, userID, clientUUID)
+	if err != nil {
+		return nil, err
+	}
+	res := &LogoutResult{RevokedTokens: len(revoked), Clients: []string{}}
+	s.denyRevoked(ctx, revoked)
+	for _, t := range revoked {
+		if !containsString(res.Clients, t.ClientPublicID) {
+			res.Clients = append(res.Clients, t.ClientPublicID)
+		}
+	}
+
+	if clientID == "" && s.dep.SessionManager != nil {
+		if err := s.dep.SessionManager.RevokeAll(ctx, userID); err != nil {
+			log.Printf("[AuthService] revoke sessions user=%s failed: %v", userID, err)
+		}
+	}
+
+	detail := "logout_all revoked=" + strconv.Itoa(len(revoked))
+	s.recordTokenAudit(ctx, &entities.TokenAudit{
+		Event:     AuditTokenRevoked,
+		ClientID:  strptr(clientUUID),
+		UserID:    &userID,
+		TokenType: "access",
+		Detail:    &detail,
+	})
+	log.Printf("[AuthService] logout everywhere user=%s client=%s tokens=%d", userID, clientID, len(revoked))
+
+	s.notifyBackchannelLogout(userID, res.Clients)
+	return res, nil
+}
+
+// EndSessionRequest — parameter end_session (RP-Initiated Logout §2).
+type EndSessionRequest struct {
+	IDTokenHint           string
+	ClientID              string
+	PostLogoutRedirectURI string
+	State                 string
+	Confirmed             bool // user sudah menyetujui logout di halaman konfirmasi
+}
+
+// EndSession mengakhiri sesi browser sess (boleh nil) dan mengirim back-channel logout ke
+// client user pemilik sesi tersebut. Tanpa id_token_hint yang valid logout perlu konfirmasi user
+// (ErrLogoutConfirmationRequired). Mengembalikan URL redirect ("" => tampilkan halaman).
+func (s *AuthService) EndSession(ctx context.Context, req EndSessionRequest, sess *AuthSession) (string, error) {
+	var userID string
+	clientID := req.ClientID
+	if req.IDTokenHint != "" {
+		claims, err := s.dep.KeyStore.VerifyIDTokenHint(req.IDTokenHint)
+		if err != nil {
+			return "", ErrInvalidIDTokenHint
+		}
+		aud := claims.RegisteredClaims.Audience
+		if clientID != "" && !containsString(aud, clientID) {
+			return "", ErrInvalidIDTokenHint
+		}
+		if clientID == "" && len(aud) > 0 {
+			clientID = aud[0]
+		}
+		userID = strings.TrimPrefix(claims.Subject, "user:")
+		// hint milik user lain: jangan akhiri sesi browser ini tanpa konfirmasi
+		if sess != nil && sess.UserID != userID && !req.Confirmed {
+			return "", ErrLogoutConfirmationRequired
+		}
+	} else if sess != nil && !req.Confirmed {
+		return "", ErrLogoutConfirmationRequired
+	}
+
+	redirect, err := s.postLogoutRedirect(ctx, clientID, req.PostLogoutRedirectURI, req.State)
+	if err != nil {
+		return "", err
+	}
+
+	// back-channel logout hanya untuk sesi yang benar-benar diakhiri: id_token_hint saja
+	// (bisa kedaluwarsa / bocor) tidak boleh me-logout user dari semua client
+	if sess != nil {
+		if err := s.Logout(ctx, sess); err != nil {
+			return "", err
+		}
+		log.Printf("[AuthService] end_session user=%s sid=%s client=%s", sess.UserID, sess.SID, clientID)
+		clients, err := s.dep.TokenRepo.ListClientsByUser(ctx, sess.UserID)
+		if err != nil {
+			log.Printf("[AuthService] list logout clients user=%s failed: %v", sess.UserID, err)
+		}
+		s.notifyBackchannelLogout(sess.UserID, clients)
+	}
+	return redirect, nil
+}
+
+// postLogoutRedirect — URI wajib terdaftar persis di post_logout_redirect_uris client.
+func (s *AuthService) postLogoutRedirect(ctx context.Context, clientID, uri, state string) (string, error) {
+	if uri == "" {
+		return "", nil
+	}
+	if clientID == "" {
+		return "", newOAuthError("invalid_request", "client_id or id_token_hint is required with post_logout_redirect_uri")
+	}
+	c, err := s.dep.ClientRepo.FindByClientID(ctx, clientID)
+	if err != nil {
+		return "", err
+	}
+	if c == nil || !containsString(c.PostLogoutRedirectURIs, uri) {
+		return "", ErrInvalidPostLogoutRedirect
+	}
+	if state == "" {
+		return uri, nil
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", ErrInvalidPostLogoutRedirect
+	}
+	q := u.Query()
+	q.Set("state", state)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// notifyBackchannelLogout mengirim logout_token ke backchannel_logout_uri setiap client
+// secara asinkron; kegagalan hanya di-log (client tetap bisa mengandalkan denylist).
+func (s *AuthService) notifyBackchannelLogout(userID string, clientIDs []string) {
+	if len(clientIDs) == 0 {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(len(clientIDs)+1)*backchannelTimeout)
+		defer cancel()
+		for _, id := range clientIDs {
+			c, err := s.dep.ClientRepo.FindByClientID(ctx, id)
+			if err != nil || c == nil || optionalString(c.BackchannelLogoutURI) == "" {
+				continue
+			}
+			if err := s.sendLogoutToken(ctx, c, userID); err != nil {
+				log.Printf("[AuthService] back-channel logout client=%s user=%s failed: %v", c.ClientID, userID, err)
+			}
+		}
+	}()
+}
+
+func (s *AuthService) sendLogoutToken(ctx context.Context, c *entities.OAuthClient, userID string) error {
+	token, err := s.dep.KeyStore.SignLogoutToken(c.ClientID, userID, logoutTokenTTL)
+	if err != nil {
+		return err
+	}
+	form := url.Values{"logout_token": {token}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, *c.BackchannelLogoutURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := backchannelClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return errors.New("unexpected status " + resp.Status)
+	}
+	return nil
+}
+
+// denyJTI memasukkan jti access token ke denylist sampai exp dan mempublikasikannya ke
+// resource server (LocalDenylist).
+func (s *AuthService) denyJTI(ctx context.Context, jti string, exp time.Time) {
+	if s.dep.Denylist == nil || jti == "" {
+		return
+	}
+	if err := s.dep.Denylist.Add(ctx, jti, exp); err != nil {
+		log.Printf("[AuthService] denylist jti=%s failed: %v", jti, err)
+	}
+}
+
+func (s *AuthService) denyRevoked(ctx context.Context, revoked []*entities.RevokedAccessToken) {
+	for _, t := range revoked {
+		s.denyJTI(ctx, t.JTI, t.ExpiresAt)
+	}
+}
+
+// jtiRevoked — jti ada di denylist; Redis error => dianggap tidak dicabut (repo tetap
+// menolak token yang revoked).
+func (s *AuthService) jtiRevoked(ctx context.Context, claims *sharedsec.TokenClaims) bool {
+	if s.dep.Denylist == nil {
+		return false
+	}
+	revoked, err := s.dep.Denylist.IsRevoked(ctx, claims)
+	if err != nil {
+		log.Printf("[AuthService] denylist lookup failed: %v", err)
+	}
+	return revoked
+}
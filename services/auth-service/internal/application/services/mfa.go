@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"bkc_microservice/services/auth-service/internal/domain/entities"
+	"bkc_microservice/shared/mfa"
+	"bkc_microservice/shared/notify"
+	sharedsec "bkc_microservice/shared/security"
+)
+
+const (
+	GrantTypeMFAOTP = "urn:bkc:grant-type:mfa-otp"
+
+	mfaChallengeTTL    = 5 * time.Minute
+	mfaOTPResendWindow = 30 * time.Second
+	mfaMaxAttempts     = 5
+
+	MFAMethodTOTP     = "totp"
+	MFAMethodEmail    = "email_otp"
+	MFAMethodRecovery = "recovery_code"
+)
+
+var (
+	ErrInvalidMFAToken     = errors.New("invalid mfa_token")
+	ErrInvalidOTP          = errors.New("invalid otp")
+	ErrMFAAttemptsExceeded = errors.New("mfa_attempts_exceeded")
+	ErrMFAUnavailable      = errors.New("mfa_unavailable")
+	ErrOTPResendTooSoon    = errors.New("otp_resend_too_soon")
+)
+
+// MFARequiredError dikembalikan grant password / authorization_code jika user
+// mengaktifkan 2FA. Client menukar MFAToken + OTP lewat grant mfa-otp.
+type MFARequiredError struct {
+	MFAToken  string
+	Methods   []string
+	ExpiresIn int64
+}
+
+func (e *MFARequiredError) Error() string { return "mfa_required" }
+
+// mfaChallenge menyimpan hasil login tahap pertama sampai OTP diverifikasi.
+type mfaChallenge struct {
+	UserID   string     `json:"userId"`
+	ClientID string     `json:"clientId"` // client_id publik
+	Scope    string     `json:"scope"`
+	TenantID string     `json:"tenantId"`
+	Nonce    string     `json:"nonce,omitempty"`
+	AuthTime *time.Time `json:"authTime,omitempty"`
+
+	Resources []string `json:"resources,omitempty"` // RFC 8707
+
+	// password grant: OTP salah ikut dihitung lockout, reset hitungan setelah MFA lolos
+	ThrottleKey string `json:"throttleKey,omitempty"`
+}
+
+func mfaChallengeKey(token string) string { return "mfa:challenge:" + token }
+func mfaAttemptsKey(token string) string  { return "mfa:attempts:" + token }
+func mfaOTPKey(token string) string       { return "mfa:" + token }
+
+// requireMFA membuat challenge jika user mengaktifkan 2FA; nil => lanjut terbitkan token.
+func (s *AuthService) requireMFA(ctx context.Context, u *entities.User, ch mfaChallenge) error {
+	if u == nil || !u.TwoFactorEnabled {
+		return nil
+	}
+	if s.dep.RDB == nil || s.dep.MFAService == nil {
+		return ErrMFAUnavailable
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	raw, err := json.Marshal(ch)
+	if err != nil {
+		return err
+	}
+	if err := s.dep.RDB.Set(ctx, mfaChallengeKey(token), raw, mfaChallengeTTL).Err(); err != nil {
+		return err
+	}
+
+	methods := []string{MFAMethodEmail}
+	if u.TwoFactorSecret != nil && *u.TwoFactorSecret != "" {
+		methods = []string{MFAMethodTOTP, MFAMethodEmail, MFAMethodRecovery}
+	}
+	return &MFARequiredError{
+		MFAToken:  token,
+		Methods:   methods,
+		ExpiresIn: int64(mfaChallengeTTL.Seconds()),
+	}
+}
+
+func (s *AuthService) loadMFAChallenge(ctx context.Context, token string) (*mfaChallenge, error) {
+	if s.dep.RDB == nil {
+		return nil, ErrMFAUnavailable
+	}
+	raw, err := s.dep.RDB.Get(ctx, mfaChallengeKey(token)).Bytes()
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+	var ch mfaChallenge
+	if err := json.Unmarshal(raw, &ch); err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+	return &ch, nil
+}
+
+func (s *AuthService) dropMFAChallenge(ctx context.Context, token string) {
+	_ = s.dep.RDB.Del(ctx, mfaChallengeKey(token), mfaAttemptsKey(token)).Err()
+	_ = s.dep.MFAService.OTP.Invalidate(ctx, mfaOTPKey(token))
+}
+
+// SendMFAChallenge mengirim OTP email untuk mfa_token yang masih aktif.
+func (s *AuthService) SendMFAChallenge(ctx context.Context, mfaToken string) error {
+	ch, err := s.loadMFAChallenge(ctx, mfaToken)
+	if err != nil {
+		return err
+	}
+
+	// batasi resend
+	ok, err := s.dep.RDB.SetNX(ctx, "mfa:resend:"+mfaToken, "1", mfaOTPResendWindow).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrOTPResendTooSoon
+	}
+
+	u, err := s.dep.UserRepo.FindByID(ctx, ch.UserID)
+	if err != nil || u == nil {
+		return ErrInvalidMFAToken
+	}
+
+	code, err := s.dep.MFAService.OTP.Generate(ctx, mfaOTPKey(mfaToken), mfaChallengeTTL)
+	if err != nil {
+		return err
+	}
+
+	if s.dep.Notifier == nil {
+		return ErrMFAUnavailable
+	}
+	return s.dep.Notifier.Send(ctx, notify.Message{
+		To:      u.Email,
+		Subject: "Kode verifikasi login",
+		Body:    "Kode verifikasi Anda: " + code + " (berlaku 5 menit)",
+	})
+}
+
+// CompleteMFA — grant urn:bkc:grant-type:mfa-otp: tukar mfa_token + OTP (TOTP / email)
+// atau recovery code dengan token.
+func (s *AuthService) CompleteMFA(ctx context.Context, auth ClientAuth, mfaToken, otp, recoveryCode string) (*TokenResponse, error) {
+	ch, err := s.loadMFAChallenge(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := s.AuthenticateClient(ctx, auth)
+	if err != nil || c.ClientID != ch.ClientID {
+		return nil, ErrInvalidClient
+	}
+
+	u, err := s.dep.UserRepo.FindByID(ctx, ch.UserID)
+	if err != nil || u == nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	if accountLocked(u, time.Now()) {
+		s.dropMFAChallenge(ctx, mfaToken)
+		return nil, ErrAccountLockedGrant
+	}
+
+	if !s.verifyMFACode(ctx, u, mfaToken, otp, recoveryCode) {
+		if ch.ThrottleKey != "" {
+			s.loginFailed(ctx, ch.ThrottleKey, u, c)
+		}
+		n, _ := s.dep.RDB.Incr(ctx, mfaAttemptsKey(mfaToken)).Result()
+		_ = s.dep.RDB.Expire(ctx, mfaAttemptsKey(mfaToken), mfaChallengeTTL).Err()
+		if n >= mfaMaxAttempts {
+			log.Printf("[AuthService] mfa attempts exceeded user=%s client=%s", ch.UserID, ch.ClientID)
+			s.dropMFAChallenge(ctx, mfaToken)
+			return nil, ErrMFAAttemptsExceeded
+		}
+		return nil, ErrInvalidOTP
+	}
+
+	// mfa_token sekali pakai: hanya request yang berhasil menghapus challenge yang lanjut
+	n, err := s.dep.RDB.Del(ctx, mfaChallengeKey(mfaToken)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if n != 1 {
+		return nil, ErrInvalidMFAToken
+	}
+	s.dropMFAChallenge(ctx, mfaToken)
+	if ch.ThrottleKey != "" {
+		s.loginSucceeded(ctx, ch.ThrottleKey, u.ID)
+	}
+
+	return s.issueTokens(ctx, tokenRequest{
+		Client:      c,
+		GrantType:   GrantTypeMFAOTP,
+		UserID:      ch.UserID,
+		Scope:       ch.Scope,
+		TenantID:    ch.TenantID,
+		WithRefresh: clientAllowsGrant(c, "refresh_token"),
+		Nonce:       ch.Nonce,
+		AuthTime:    ch.AuthTime,
+		Resources:   ch.Resources,
+	})
+}
+
+func (s *AuthService) verifyMFACode(ctx context.Context, u *entities.User, mfaToken, otp, recoveryCode string) bool {
+	if recoveryCode != "" {
+		if s.dep.Recovery == nil {
+			return false
+		}
+		ok, err := s.dep.Recovery.Consume(ctx, u.ID, mfa.HashRecoveryCode(recoveryCode))
+		if err != nil {
+			log.Printf("[AuthService] consume recovery code failed user=%s: %v", u.ID, err)
+			return false
+		}
+		return ok
+	}
+
+	if otp == "" {
+		return false
+	}
+	if secret := s.totpSecret(u); secret != "" && s.dep.MFAService.TOTP.Validate(otp, secret) {
+		return true
+	}
+	return s.dep.MFAService.OTP.Verify(ctx, mfaOTPKey(mfaToken), otp)
+}
+
+// totpSecret mendekripsi users.two_factor_secret (data lama yang masih plaintext tetap diterima)
+func (s *AuthService) totpSecret(u *entities.User) string {
+	if u.TwoFactorSecret == nil || *u.TwoFactorSecret == "" {
+		return ""
+	}
+	if !sharedsec.IsSealed(*u.TwoFactorSecret) {
+		return *u.TwoFactorSecret
+	}
+	if s.dep.SecretBox == nil {
+		return ""
+	}
+	secret, err := s.dep.SecretBox.Open(*u.TwoFactorSecret)
+	if err != nil {
+		log.Printf("[AuthService] decrypt totp secret failed user=%s: %v", u.ID, err)
+		return ""
+	}
+	return secret
+}
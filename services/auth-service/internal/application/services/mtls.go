@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"log"
+	"net"
+	"time"
+
+	"bkc_microservice/services/auth-service/internal/domain/entities"
+	sharedsec "bkc_microservice/shared/security"
+)
+
+// Mutual-TLS (RFC 8705): client mesin mengautentikasi diri dengan sertifikat TLS
+// (tls_client_auth: sertifikat PKI dari CA tepercaya, self_signed_tls_client_auth:
+// sertifikat yang key-nya terdaftar di JWKS client) dan bisa meminta token yang
+// di-bind ke sertifikat tersebut (cnf.x5t#S256).
+
+const (
+	AuthMethodTLSClient     = "tls_client_auth"
+	AuthMethodSelfSignedTLS = "self_signed_tls_client_auth"
+)
+
+var ErrClientCertificateRequired = newOAuthError("invalid_request", "client certificate is required for certificate-bound tokens")
+
+func isTLSAuthMethod(method string) bool {
+	return method == AuthMethodTLSClient || method == AuthMethodSelfSignedTLS
+}
+
+type clientCertCtx struct{}
+
+// WithClientCertificate menyimpan sertifikat client (leaf) koneksi mTLS untuk penerbitan token.
+func WithClientCertificate(ctx context.Context, certs []*x509.Certificate) context.Context {
+	if len(certs) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, clientCertCtx{}, certs[0])
+}
+
+func clientCertificateFromContext(ctx context.Context) *x509.Certificate {
+	cert, _ := ctx.Value(clientCertCtx{}).(*x509.Certificate)
+	return cert
+}
+
+// authenticateTLSClient memverifikasi sertifikat koneksi terhadap metode mTLS client.
+func (s *AuthService) authenticateTLSClient(ctx context.Context, c *entities.OAuthClient, certs []*x509.Certificate) (*entities.OAuthClient, error) {
+	if len(certs) == 0 {
+		return nil, ErrInvalidClient
+	}
+	leaf := certs[0]
+	now := time.Now()
+	if now.Before(leaf.NotBefore) || now.After(leaf.NotAfter) {
+		return nil, ErrInvalidClient
+	}
+
+	var err error
+	switch c.AuthMethod {
+	case AuthMethodTLSClient:
+		err = s.verifyPKIClientCertificate(c, certs)
+	case AuthMethodSelfSignedTLS:
+		err = s.verifySelfSignedClientCertificate(ctx, c, leaf)
+	default:
+		err = errors.New("not a tls auth method")
+	}
+	if err != nil {
+		log.Printf("[AuthService] client certificate rejected client=%s: %v", c.ClientID, err)
+		return nil, ErrInvalidClient
+	}
+	return c, nil
+}
+
+// tls_client_auth: rantai sertifikat valid ke CA tepercaya + atribut terdaftar cocok
+func (s *AuthService) verifyPKIClientCertificate(c *entities.OAuthClient, certs []*x509.Certificate) error {
+	if s.dep.ClientCAs == nil {
+		return errors.New("no client CA configured")
+	}
+	intermediates := x509.NewCertPool()
+	for _, ic := range certs[1:] {
+		intermediates.AddCert(ic)
+	}
+	leaf := certs[0]
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         s.dep.ClientCAs,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return err
+	}
+	if !certificateMatches(leaf, optionalString(c.TLSAuthAttr), optionalString(c.TLSAuthValue)) {
+		return errors.New("certificate does not match registered subject")
+	}
+	return nil
+}
+
+// self_signed_tls_client_auth: public key sertifikat harus salah satu key di JWKS client
+func (s *AuthService) verifySelfSignedClientCertificate(ctx context.Context, c *entities.OAuthClient, leaf *x509.Certificate) error {
+	set, err := s.clientJWKSet(ctx, c)
+	if err != nil {
+		return err
+	}
+	pub, ok := leaf.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok {
+		return errors.New("unsupported certificate key")
+	}
+	for _, k := range set.Keys {
+		if key, err := k.PublicKey(); err == nil && pub.Equal(key) {
+			return nil
+		}
+	}
+	return errors.New("certificate key not in client jwks")
+}
+
+// certificateMatches — atribut tls_client_auth_* (RFC 8705 §2.1.2)
+func certificateMatches(cert *x509.Certificate, attr, value string) bool {
+	switch attr {
+	case "subject_dn":
+		return cert.Subject.String() == value
+	case "san_dns":
+		return containsString(cert.DNSNames, value)
+	case "san_uri":
+		for _, u := range cert.URIs {
+			if u.String() == value {
+				return true
+			}
+		}
+	case "san_ip":
+		ip := net.ParseIP(value)
+		for _, a := range cert.IPAddresses {
+			if ip != nil && a.Equal(ip) {
+				return true
+			}
+		}
+	case "san_email":
+		return containsString(cert.EmailAddresses, value)
+	}
+	return false
+}
+
+// tlsClientAuthAttribute — atribut tls_client_auth_* yang diisi di metadata beserta jumlahnya.
+func (md *ClientMetadata) tlsClientAuthAttribute() (attr, value string, n int) {
+	for _, a := range []struct{ name, value string }{
+		{"subject_dn", md.TLSClientAuthSubjectDN},
+		{"san_dns", md.TLSClientAuthSANDNS},
+		{"san_uri", md.TLSClientAuthSANURI},
+		{"san_ip", md.TLSClientAuthSANIP},
+		{"san_email", md.TLSClientAuthSANEmail},
+	} {
+		if a.value != "" {
+			attr, value, n = a.name, a.value, n+1
+		}
+	}
+	return attr, value, n
+}
+
+func (md *ClientMetadata) setTLSClientAuthAttribute(attr, value string) {
+	switch attr {
+	case "subject_dn":
+		md.TLSClientAuthSubjectDN = value
+	case "san_dns":
+		md.TLSClientAuthSANDNS = value
+	case "san_uri":
+		md.TLSClientAuthSANURI = value
+	case "san_ip":
+		md.TLSClientAuthSANIP = value
+	case "san_email":
+		md.TLSClientAuthSANEmail = value
+	}
+}
+
+// validateClientTLS — metadata mTLS: tls_client_auth butuh tepat satu atribut subject,
+// self_signed_tls_client_auth butuh JWKS berisi key sertifikat.
+func validateClientTLS(md *ClientMetadata, hasKeys bool) error {
+	attr, value, n := md.tlsClientAuthAttribute()
+	switch md.TokenEndpointAuthMethod {
+	case AuthMethodTLSClient:
+		if n != 1 {
+			return newOAuthError("invalid_client_metadata", "tls_client_auth requires exactly one tls_client_auth_* attribute")
+		}
+		if attr == "san_ip" && net.ParseIP(value) == nil {
+			return newOAuthError("invalid_client_metadata", "invalid tls_client_auth_san_ip")
+		}
+	case AuthMethodSelfSignedTLS:
+		if !hasKeys {
+			return newOAuthError("invalid_client_metadata", "self_signed_tls_client_auth requires jwks or jwks_uri")
+		}
+		fallthrough
+	default:
+		if n > 0 {
+			return newOAuthError("invalid_client_metadata", "tls_client_auth_* attributes require tls_client_auth")
+		}
+	}
+	return nil
+}
+
+func certificateThumbprintFromContext(ctx context.Context) string {
+	if cert := clientCertificateFromContext(ctx); cert != nil {
+		return sharedsec.CertificateThumbprint(cert)
+	}
+	return ""
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"bkc_microservice/services/auth-service/internal/domain/entities"
+	sharedsec "bkc_microservice/shared/security"
+)
+
+// testCert membuat sertifikat ECDSA; parent nil => self-signed.
+func testCert(t *testing.T, tmpl *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tmpl.SerialNumber = serial
+	tmpl.NotBefore = time.Now().Add(-time.Minute)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func testCA(t *testing.T, name string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	return testCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: name},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+}
+
+func testClientCert(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey) *x509.Certificate {
+	svcURI, _ := url.Parse("spiffe://bkc.local/sync-cbs")
+	cert, _ := testCert(t, &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "sync-cbs", Organization: []string{"BKC"}},
+		DNSNames:       []string{"sync-cbs.bkc.local"},
+		URIs:           []*url.URL{svcURI},
+		IPAddresses:    []net.IP{net.ParseIP("10.0.0.7")},
+		EmailAddresses: []string{"ops@bkc.local"},
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, caKey)
+	return cert
+}
+
+func TestAuthenticateTLSClientPKI(t *testing.T) {
+	ca, caKey := testCA(t, "bkc client ca")
+	leaf := testClientCert(t, ca, caKey)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	s := &AuthService{dep: Dep{ClientCAs: pool}}
+
+	cases := []struct {
+		attr, value string
+		ok          bool
+	}{
+		{"subject_dn", "CN=sync-cbs,O=BKC", true},
+		{"subject_dn", "CN=other,O=BKC", false},
+		{"san_dns", "sync-cbs.bkc.local", true},
+		{"san_dns", "evil.bkc.local", false},
+		{"san_uri", "spiffe://bkc.local/sync-cbs", true},
+		{"san_ip", "10.0.0.7", true},
+		{"san_ip", "10.0.0.8", false},
+		{"san_email", "ops@bkc.local", true},
+		{"san_email", "dev@bkc.local", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.attr+"="+tc.value, func(t *testing.T) {
+			c := &entities.OAuthClient{ClientID: "sync-cbs", AuthMethod: AuthMethodTLSClient, TLSAuthAttr: strptr(tc.attr), TLSAuthValue: strptr(tc.value)}
+			_, err := s.authenticateTLSClient(context.Background(), c, []*x509.Certificate{leaf})
+			if tc.ok && err != nil {
+				t.Fatalf("expected certificate to match, got %v", err)
+			}
+			if !tc.ok && !errors.Is(err, ErrInvalidClient) {
+				t.Fatalf("expected ErrInvalidClient, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAuthenticateTLSClientPKIUntrustedCA(t *testing.T) {
+	ca, _ := testCA(t, "bkc client ca")
+	rogue, rogueKey := testCA(t, "bkc client ca")
+	leaf := testClientCert(t, rogue, rogueKey)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	s := &AuthService{dep: Dep{ClientCAs: pool}}
+
+	c := &entities.OAuthClient{ClientID: "sync-cbs", AuthMethod: AuthMethodTLSClient, TLSAuthAttr: strptr("san_dns"), TLSAuthValue: strptr("sync-cbs.bkc.local")}
+	if _, err := s.authenticateTLSClient(context.Background(), c, []*x509.Certificate{leaf}); !errors.Is(err, ErrInvalidClient) {
+		t.Fatalf("certificate from untrusted CA accepted: %v", err)
+	}
+	if _, err := s.authenticateTLSClient(context.Background(), c, nil); !errors.Is(err, ErrInvalidClient) {
+		t.Fatalf("missing certificate accepted: %v", err)
+	}
+}
+
+func TestAuthenticateTLSClientSelfSigned(t *testing.T) {
+	cert, _ := testCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "device-42"}}, nil, nil)
+	other, _ := testCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "device-42"}}, nil, nil)
+
+	jwks := func(certs ...*x509.Certificate) *string {
+		var set sharedsec.JWKSet
+		for _, c := range certs {
+			k, err := sharedsec.NewJWK("", "ES256", c.PublicKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			set.Keys = append(set.Keys, k)
+		}
+		raw, _ := json.Marshal(set)
+		return strptr(string(raw))
+	}
+
+	s := &AuthService{}
+	c := &entities.OAuthClient{ClientID: "device-42", AuthMethod: AuthMethodSelfSignedTLS, JWKS: jwks(other, cert)}
+	if _, err := s.authenticateTLSClient(context.Background(), c, []*x509.Certificate{cert}); err != nil {
+		t.Fatalf("certificate key registered in jwks rejected: %v", err)
+	}
+
+	c.JWKS = jwks(other)
+	if _, err := s.authenticateTLSClient(context.Background(), c, []*x509.Certificate{cert}); !errors.Is(err, ErrInvalidClient) {
+		t.Fatalf("certificate key not in jwks accepted: %v", err)
+	}
+}
+
+func TestTokenConfirmationCertificateBound(t *testing.T) {
+	ca, caKey := testCA(t, "bkc client ca")
+	leaf := testClientCert(t, ca, caKey)
+	c := &entities.OAuthClient{ClientID: "sync-cbs", CertBound: true}
+
+	cnf, err := tokenConfirmation(WithClientCertificate(context.Background(), []*x509.Certificate{leaf}), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cnf == nil || cnf.X5T != sharedsec.CertificateThumbprint(leaf) {
+		t.Fatalf("cnf.x5t#S256 = %+v, want thumbprint of client certificate", cnf)
+	}
+
+	if _, err := tokenConfirmation(context.Background(), c); !errors.Is(err, ErrClientCertificateRequired) {
+		t.Fatalf("certificate-bound token issued without certificate: %v", err)
+	}
+}
+
+func TestVerifyTokenBindingCertificate(t *testing.T) {
+	ca, caKey := testCA(t, "bkc client ca")
+	leaf := testClientCert(t, ca, caKey)
+	other := testClientCert(t, ca, caKey)
+	claims := &sharedsec.TokenClaims{Confirmation: &sharedsec.Confirmation{X5T: sharedsec.CertificateThumbprint(leaf)}}
+	s := &AuthService{}
+
+	cases := []struct {
+		name string
+		tls  *tls.ConnectionState
+		ok   bool
+	}{
+		{"bound certificate", &tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf}}, true},
+		{"other certificate", &tls.ConnectionState{PeerCertificates: []*x509.Certificate{other}}, false},
+		{"tls without certificate", &tls.ConnectionState{}, false},
+		{"plain http", nil, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "https://auth.bkc.local/oauth/userinfo", nil)
+			r.TLS = tc.tls
+			err := s.verifyTokenBinding(r, "Bearer", "token", claims)
+			if tc.ok && err != nil {
+				t.Fatalf("bound certificate rejected: %v", err)
+			}
+			if !tc.ok && !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("expected ErrInvalidToken, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	sharedsec "bkc_microservice/shared/security"
+)
+
+var (
+	ErrInvalidToken      = errors.New("invalid_token")
+	ErrInsufficientScope = errors.New("insufficient_scope")
+)
+
+/************** DISCOVERY **************/
+
+type DiscoveryDocument struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	RegistrationEndpoint              string   `json:"registration_endpoint"`
+	DeviceAuthorizationEndpoint       string   `json:"device_authorization_endpoint"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	TokenEndpointAuthSigningAlgs      []string `json:"token_endpoint_auth_signing_alg_values_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+
+	PushedAuthorizationRequestEndpoint string   `json:"pushed_authorization_request_endpoint"`
+	RequirePushedAuthorizationRequests bool     `json:"require_pushed_authorization_requests"`
+	RequestParameterSupported          bool     `json:"request_parameter_supported"`
+	RequestURIParameterSupported       bool     `json:"request_uri_parameter_supported"`
+	RequestObjectSigningAlgs           []string `json:"request_object_signing_alg_values_supported"`
+
+	DPoPSigningAlgs []string `json:"dpop_signing_alg_values_supported"`
+
+	TLSClientCertificateBoundAccessTokens bool `json:"tls_client_certificate_bound_access_tokens"`
+
+	EndSessionEndpoint                string `json:"end_session_endpoint"`
+	BackchannelLogoutSupported        bool   `json:"backchannel_logout_supported"`
+	BackchannelLogoutSessionSupported bool   `json:"backchannel_logout_session_supported"`
+}
+
+func (s *AuthService) Discovery() *DiscoveryDocument {
+	base := strings.TrimRight(s.dep.PublicURL, "/")
+	issuer := s.dep.KeyStore.Issuer
+	if issuer == "" {
+		issuer = base
+	}
+
+	return &DiscoveryDocument{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             base + "/oauth/authorize",
+		TokenEndpoint:                     base + "/oauth/token",
+		UserInfoEndpoint:                  base + "/oauth/userinfo",
+		JWKSURI:                           base + "/oauth/jwks",
+		IntrospectionEndpoint:             base + "/oauth/introspect",
+		RevocationEndpoint:                base + "/oauth/revoke",
+		RegistrationEndpoint:              base + "/oauth/register",
+		DeviceAuthorizationEndpoint:       base + "/oauth/device_authorization",
+		ScopesSupported:                   []string{"openid", "profile", "email", "phone", "offline_access"},
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code", "refresh_token", "client_credentials", "password", GrantTypeDeviceCode, GrantTypeTokenExchange},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  sharedsec.SupportedSigningAlgs,
+		TokenEndpointAuthMethodsSupported: supportedAuthMethods,
+		TokenEndpointAuthSigningAlgs:      append(append([]string{}, asymmetricAssertionAlgs...), hmacAssertionAlgs...),
+		CodeChallengeMethodsSupported:     []string{"S256", "plain"},
+		ClaimsSupported: []string{
+			"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "at_hash", "azp", "tenantId",
+			"name", "preferred_username", "locale", "zoneinfo", "picture", "updated_at",
+			"email", "phone_number",
+		},
+
+		PushedAuthorizationRequestEndpoint: base + "/oauth/par",
+		RequestParameterSupported:          true,
+		RequestURIParameterSupported:       false, // hanya request_uri hasil PAR
+		RequestObjectSigningAlgs:           asymmetricAssertionAlgs,
+
+		DPoPSigningAlgs: sharedsec.DPoPSigningAlgs,
+
+		TLSClientCertificateBoundAccessTokens: true,
+
+		EndSessionEndpoint:                base + "/oauth/end_session",
+		BackchannelLogoutSupported:        true,
+		BackchannelLogoutSessionSupported: false, // logout_token hanya membawa sub, tanpa sid
+	}
+}
+
+/************** USERINFO **************/
+
+// UserInfo memvalidasi access token lalu mengembalikan klaim user sesuai scope
+// (openid wajib; profile/email/phone menentukan klaim tambahan).
+func (s *AuthService) UserInfo(r *http.Request, scheme, accessToken string) (map[string]any, error) {
+	ctx := r.Context()
+	claims, err := s.AuthenticateBearer(r, scheme, accessToken, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// token client_credentials tidak punya user
+	userID := claims.UserID
+	if userID == "" {
+		return nil, ErrInvalidToken
+	}
+	if !hasScope(claims.Scope, "openid") {
+		return nil, ErrInsufficientScope
+	}
+
+	out := map[string]any{"sub": claims.Subject}
+	if claims.TenantID != "" {
+		out["tenantId"] = claims.TenantID
+	}
+
+	if s.dep.UserClient == nil {
+		return out, nil
+	}
+	u, err := s.dep.UserClient.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, ErrInvalidToken
+	}
+
+	if hasScope(claims.Scope, "profile") {
+		out["preferred_username"] = u.Username
+		if name := firstNonEmpty(u.FullName, u.DisplayName); name != "" {
+			out["name"] = name
+		}
+		setClaim(out, "locale", u.Locale)
+		setClaim(out, "zoneinfo", u.Timezone)
+		setClaim(out, "picture", u.AvatarURL)
+		if u.UpdatedAt != nil {
+			out["updated_at"] = u.UpdatedAt.Unix()
+		}
+	}
+	if hasScope(claims.Scope, "email") && u.Email != "" {
+		out["email"] = u.Email
+	}
+	if hasScope(claims.Scope, "phone") {
+		setClaim(out, "phone_number", u.Phone)
+	}
+
+	return out, nil
+}
+
+func setClaim(m map[string]any, k string, v *string) {
+	if v != nil && *v != "" {
+		m[k] = *v
+	}
+}
+
+func firstNonEmpty(vals ...*string) string {
+	for _, v := range vals {
+		if v != nil && *v != "" {
+			return *v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,291 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"bkc_microservice/services/auth-service/internal/domain/entities"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+// Pushed Authorization Requests (RFC 9126) dan request object JAR (RFC 9101).
+// Parameter authorize dikirim client lewat back-channel (atau ditandatangani key client),
+// disimpan di Redis, lalu browser hanya membawa client_id + request_uri sehingga
+// parameter tidak bisa diubah di redirect.
+
+const (
+	RequestURIPrefix = "urn:ietf:params:oauth:request_uri:"
+
+	pushedRequestTTL           = 10 * time.Minute // mencakup waktu login + consent
+	requestObjectMaxLifetime   = time.Hour
+	requestObjectTypeHeaderJWT = "oauth-authz-req+jwt"
+)
+
+var (
+	ErrPARRequired           = newOAuthError("invalid_request", "pushed authorization request is required for this client")
+	ErrRequestObjectRequired = newOAuthError("invalid_request", "signed request object is required for this client")
+	ErrInvalidRequestURI     = newOAuthError("invalid_request_uri", "request_uri is invalid or expired")
+	ErrInvalidRequestObject  = newOAuthError("invalid_request_object", "request object is invalid")
+)
+
+// AuthorizationParams — parameter /oauth/authorize. RequestURI / RequestedAt terisi bila
+// request berasal dari PAR atau request object.
+type AuthorizationParams struct {
+	ResponseType        string   `json:"responseType"`
+	ClientID            string   `json:"clientId"`
+	RedirectURI         string   `json:"redirectUri"`
+	Scope               string   `json:"scope"`
+	State               string   `json:"state,omitempty"`
+	CodeChallenge       string   `json:"codeChallenge"`
+	CodeChallengeMethod string   `json:"codeChallengeMethod"`
+	CompanyID           string   `json:"companyId"`
+	Nonce               string   `json:"nonce,omitempty"`
+	Prompt              string   `json:"prompt,omitempty"`
+	MaxAge              string   `json:"maxAge,omitempty"`
+	Resource            []string `json:"resource,omitempty"` // RFC 8707, boleh berulang
+
+	RequestURI  string `json:"requestUri,omitempty"`
+	RequestedAt int64  `json:"requestedAt,omitempty"` // unix; prompt=login terpenuhi oleh login setelah ini
+}
+
+// PushedAuthorizationResponse — respons endpoint PAR (RFC 9126 §2.2).
+type PushedAuthorizationResponse struct {
+	RequestURI string `json:"request_uri"`
+	ExpiresIn  int64  `json:"expires_in"`
+}
+
+func pushedRequestKey(requestURI string) string {
+	return "par:" + strings.TrimPrefix(requestURI, RequestURIPrefix)
+}
+
+// PushAuthorizationRequest memvalidasi parameter authorize dari client terautentikasi lalu
+// menyimpannya. requestObject (opsional) menggantikan seluruh parameter form.
+func (s *AuthService) PushAuthorizationRequest(ctx context.Context, auth ClientAuth, params AuthorizationParams, requestObject string) (*PushedAuthorizationResponse, error) {
+	if s.dep.RDB == nil {
+		return nil, errors.New("pushed authorization requests unavailable")
+	}
+	c, err := s.AuthenticateClient(ctx, auth)
+	if err != nil {
+		return nil, err
+	}
+	if !clientAllowsGrant(c, "authorization_code") {
+		return nil, ErrUnauthorizedClient
+	}
+	if params.ClientID != "" && params.ClientID != c.ClientID {
+		return nil, newOAuthError("invalid_request", "client_id does not match the authenticated client")
+	}
+
+	p := &params
+	switch {
+	case requestObject != "":
+		if p, err = s.verifyRequestObject(ctx, c, requestObject); err != nil {
+			return nil, err
+		}
+	case c.RequireSignedRequestObject:
+		return nil, ErrRequestObjectRequired
+	}
+	p.ClientID = c.ClientID
+
+	// error dikembalikan langsung ke client; belum ada redirect
+	if _, p.RedirectURI, err = s.authorizeClient(ctx, c.ClientID, p.RedirectURI); err != nil {
+		return nil, newOAuthError("invalid_request", err.Error())
+	}
+	if strings.ToLower(p.ResponseType) != "code" {
+		return nil, ErrUnsupportedResponseType
+	}
+	if _, err := s.resolveScopes(ctx, c, "", p.Scope); err != nil {
+		return nil, err
+	}
+
+	if p, err = s.storePushedRequest(ctx, p); err != nil {
+		return nil, err
+	}
+	log.Printf("[AuthService] pushed authorization request client=%s", c.ClientID)
+	return &PushedAuthorizationResponse{
+		RequestURI: p.RequestURI,
+		ExpiresIn:  int64(pushedRequestTTL.Seconds()),
+	}, nil
+}
+
+// ResolveAuthorizationRequest menentukan parameter authorize efektif:
+// request_uri => parameter tersimpan (parameter query lain diabaikan), request =>
+// isi request object (disimpan seperti PAR), selain itu parameter query bila client
+// tidak mewajibkan PAR / request object.
+func (s *AuthService) ResolveAuthorizationRequest(ctx context.Context, params AuthorizationParams, requestURI, requestObject string) (*AuthorizationParams, error) {
+	if requestURI != "" && requestObject != "" {
+		return nil, newOAuthError("invalid_request", "request and request_uri are mutually exclusive")
+	}
+	c, err := s.dep.ClientRepo.FindByClientID(ctx, params.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, errors.New("invalid client")
+	}
+
+	switch {
+	case requestURI != "":
+		p, err := s.loadPushedRequest(ctx, requestURI)
+		if err != nil {
+			return nil, err
+		}
+		if p.ClientID != c.ClientID {
+			return nil, ErrInvalidRequestURI
+		}
+		return p, nil
+	case requestObject != "":
+		if c.RequirePAR {
+			return nil, ErrPARRequired
+		}
+		p, err := s.verifyRequestObject(ctx, c, requestObject)
+		if err != nil {
+			return nil, err
+		}
+		// disimpan supaya redirect login / form consent cukup membawa request_uri
+		return s.storePushedRequest(ctx, p)
+	case c.RequirePAR:
+		return nil, ErrPARRequired
+	case c.RequireSignedRequestObject:
+		return nil, ErrRequestObjectRequired
+	}
+	return &params, nil
+}
+
+// ConsumeAuthorizationRequest menghapus request_uri setelah code diterbitkan / ditolak user.
+func (s *AuthService) ConsumeAuthorizationRequest(ctx context.Context, requestURI string) {
+	if s.dep.RDB == nil || requestURI == "" {
+		return
+	}
+	_ = s.dep.RDB.Del(ctx, pushedRequestKey(requestURI)).Err()
+}
+
+func (s *AuthService) storePushedRequest(ctx context.Context, p *AuthorizationParams) (*AuthorizationParams, error) {
+	if s.dep.RDB == nil {
+		return nil, errors.New("pushed authorization requests unavailable")
+	}
+	ref, err := randomSecret()
+	if err != nil {
+		return nil, err
+	}
+	p.RequestURI = RequestURIPrefix + ref
+	p.RequestedAt = time.Now().Unix()
+
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.dep.RDB.Set(ctx, pushedRequestKey(p.RequestURI), raw, pushedRequestTTL).Err(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *AuthService) loadPushedRequest(ctx context.Context, requestURI string) (*AuthorizationParams, error) {
+	// hanya request_uri hasil PAR; request_uri eksternal (by reference) tidak didukung
+	if s.dep.RDB == nil || !strings.HasPrefix(requestURI, RequestURIPrefix) {
+		return nil, ErrInvalidRequestURI
+	}
+	raw, err := s.dep.RDB.Get(ctx, pushedRequestKey(requestURI)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrInvalidRequestURI
+	}
+	if err != nil {
+		return nil, err
+	}
+	var p AuthorizationParams
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return nil, ErrInvalidRequestURI
+	}
+	return &p, nil
+}
+
+// verifyRequestObject memverifikasi request object (JWT) dengan key client: alg asimetris,
+// iss = client_id, aud = auth-service, exp wajib. Hanya parameter di dalam JWT yang dipakai.
+func (s *AuthService) verifyRequestObject(ctx context.Context, c *entities.OAuthClient, raw string) (*AuthorizationParams, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(asymmetricAssertionAlgs),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuer(c.ClientID),
+		jwt.WithLeeway(clientAssertionLeeway),
+	)
+	claims := jwt.MapClaims{}
+	if _, err := parser.ParseWithClaims(raw, claims, func(t *jwt.Token) (any, error) {
+		return s.requestObjectKey(ctx, c, t)
+	}); err != nil {
+		log.Printf("[AuthService] request object rejected client=%s: %v", c.ClientID, err)
+		return nil, ErrInvalidRequestObject
+	}
+
+	aud, _ := claims.GetAudience()
+	if !s.assertionAudienceOK(aud) {
+		return nil, ErrInvalidRequestObject
+	}
+	if exp, _ := claims.GetExpirationTime(); exp == nil || time.Until(exp.Time) > requestObjectMaxLifetime {
+		return nil, ErrInvalidRequestObject
+	}
+	if id := claimString(claims, "client_id"); id != c.ClientID {
+		return nil, ErrInvalidRequestObject
+	}
+
+	p := &AuthorizationParams{
+		ResponseType:        claimString(claims, "response_type"),
+		ClientID:            c.ClientID,
+		RedirectURI:         claimString(claims, "redirect_uri"),
+		Scope:               claimString(claims, "scope"),
+		State:               claimString(claims, "state"),
+		CodeChallenge:       claimString(claims, "code_challenge"),
+		CodeChallengeMethod: claimString(claims, "code_challenge_method"),
+		CompanyID:           claimString(claims, "company_id"),
+		Nonce:               claimString(claims, "nonce"),
+		Prompt:              claimString(claims, "prompt"),
+		MaxAge:              claimString(claims, "max_age"),
+	}
+	switch v := claims["resource"].(type) {
+	case string:
+		p.Resource = []string{v}
+	case []any:
+		for _, r := range v {
+			if rs, ok := r.(string); ok {
+				p.Resource = append(p.Resource, rs)
+			}
+		}
+	}
+	return p, nil
+}
+
+func (s *AuthService) requestObjectKey(ctx context.Context, c *entities.OAuthClient, t *jwt.Token) (any, error) {
+	alg := t.Method.Alg()
+	if c.RequestObjectAlg != nil && *c.RequestObjectAlg != alg {
+		return nil, errors.New("unexpected alg")
+	}
+	if typ, _ := t.Header["typ"].(string); typ != "" && !strings.EqualFold(typ, requestObjectTypeHeaderJWT) && !strings.EqualFold(typ, "JWT") {
+		return nil, errors.New("unexpected typ")
+	}
+	kid, _ := t.Header["kid"].(string)
+	jwk, err := s.clientJWK(ctx, c, kid)
+	if err != nil {
+		return nil, err
+	}
+	if jwk.Alg != "" && jwk.Alg != alg {
+		return nil, errors.New("alg does not match jwk")
+	}
+	return jwk.PublicKey()
+}
+
+// claimString — klaim string; angka (mis. max_age) dikonversi ke string desimal
+func claimString(claims jwt.MapClaims, name string) string {
+	switch v := claims[name].(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatInt(int64(v), 10)
+	}
+	return ""
+}
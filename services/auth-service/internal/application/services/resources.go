@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+// Resource Indicators (RFC 8707): client menyebut API tujuan lewat parameter "resource";
+// access token diterbitkan dengan aud = resource tersebut dan scope dipersempit ke scope
+// yang diterima resource, sehingga token untuk satu API ditolak API lain.
+
+var ErrInvalidTarget = newOAuthError("invalid_target", "the requested resource is invalid, unknown, or not allowed")
+
+// scope yang dilayani auth-service sendiri (userinfo / refresh / endpoint admin &
+// impersonation); tidak dibatasi resource
+var authServerScopes = []string{"openid", "offline_access", ScopeOAuthAdmin, ScopeImpersonate}
+
+// resolveResources memvalidasi resource terhadap registry lalu mempersempit scope.
+// Tanpa resource => DefaultResource (bila dikonfigurasi dan melayani scope yang diminta);
+// selain itu token hanya ditujukan ke client itu sendiri (aud = client_id) seperti sebelumnya.
+func (s *AuthService) resolveResources(ctx context.Context, requested []string, scope string) ([]string, string, error) {
+	if len(requested) == 0 {
+		ok, err := s.defaultResourceServes(ctx, scope)
+		if err != nil || !ok {
+			return nil, scope, err
+		}
+		requested = []string{s.dep.DefaultResource}
+	}
+
+	var resources, accepted []string
+	for _, id := range requested {
+		if !validResourceURI(id) {
+			return nil, "", newOAuthError("invalid_target", "malformed resource: "+id)
+		}
+		if containsString(resources, id) {
+			continue
+		}
+		if s.dep.ResourceRepo == nil {
+			return nil, "", ErrInvalidTarget
+		}
+		res, err := s.dep.ResourceRepo.FindByIdentifier(ctx, id)
+		if err != nil {
+			return nil, "", err
+		}
+		if res == nil || !res.Active {
+			return nil, "", ErrInvalidTarget
+		}
+		resources = append(resources, res.Identifier)
+		accepted = append(accepted, strings.Fields(res.Scopes)...)
+	}
+
+	var out []string
+	for _, sc := range strings.Fields(scope) {
+		if containsString(authServerScopes, sc) || containsString(accepted, sc) {
+			out = append(out, sc)
+		}
+	}
+	if len(out) == 0 {
+		return nil, "", newOAuthError("invalid_scope", "requested scope is not accepted by the requested resource")
+	}
+	return resources, strings.Join(out, " "), nil
+}
+
+// defaultResourceServes — DefaultResource hanya dipakai bila melayani minimal satu scope
+// yang diminta dan semua scope non-auth-server; scope service lain (mis. client_credentials
+// antar service) tidak dipaksa ke resource default lalu ditolak invalid_scope.
+func (s *AuthService) defaultResourceServes(ctx context.Context, scope string) (bool, error) {
+	if s.dep.DefaultResource == "" || s.dep.ResourceRepo == nil {
+		return false, nil
+	}
+	res, err := s.dep.ResourceRepo.FindByIdentifier(ctx, s.dep.DefaultResource)
+	if err != nil {
+		return false, err
+	}
+	if res == nil || !res.Active {
+		return false, nil
+	}
+	accepted := strings.Fields(res.Scopes)
+	served := false
+	for _, sc := range strings.Fields(scope) {
+		switch {
+		case containsString(accepted, sc):
+			served = true
+		case !containsString(authServerScopes, sc):
+			return false, nil
+		}
+	}
+	return served, nil
+}
+
+// narrowResources — request token (code / refresh) hanya boleh meminta subset resource
+// yang sudah di-grant (RFC 8707 §2.2). Tanpa permintaan => seluruh grant.
+func narrowResources(granted, requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return granted, nil
+	}
+	for _, r := range requested {
+		if !containsString(granted, r) {
+			return nil, ErrInvalidTarget
+		}
+	}
+	return requested, nil
+}
+
+// resource harus URI absolut tanpa fragment (RFC 8707 §2)
+func validResourceURI(v string) bool {
+	u, err := url.Parse(v)
+	return err == nil && u.IsAbs() && u.Host != "" && u.Fragment == "" && !strings.Contains(v, " ")
+}
+
+// resources disimpan di DB sebagai string dipisah spasi
+func joinResources(resources []string) *string {
+	return strptr(strings.Join(resources, " "))
+}
+
+func splitResources(v *string) []string {
+	return strings.Fields(optionalString(v))
+}
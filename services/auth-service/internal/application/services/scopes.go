@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"bkc_microservice/services/auth-service/internal/domain/entities"
+)
+
+// scope bawaan untuk client lama yang oauth_clients.scopes-nya kosong
+var defaultClientScopes = []string{"openid", "profile", "email", "phone", "offline_access"}
+
+// scope login first-party (IssueTokenPair / LoginWithPasswordGrant)
+const firstPartyScope = "openid profile email offline_access"
+
+// resolveScopes memvalidasi scope yang diminta lalu mengiriskannya dengan scope client
+// dan (untuk flow user) grant scope terbatas milik user. Tanpa permintaan => seluruh
+// scope yang diizinkan. Hasil kosong => invalid_scope.
+func (s *AuthService) resolveScopes(ctx context.Context, c *entities.OAuthClient, userID, requested string) (string, error) {
+	req := strings.Fields(requested)
+	for _, sc := range req {
+		if !validScopeToken(sc) {
+			return "", newOAuthError("invalid_scope", "malformed scope: "+sc)
+		}
+	}
+
+	allowed := clientScopes(c)
+	want := allowed
+	if len(req) > 0 {
+		want = nil
+		for _, sc := range req {
+			if containsString(allowed, sc) && !containsString(want, sc) {
+				want = append(want, sc)
+			}
+		}
+	}
+
+	if userID != "" {
+		var err error
+		if want, err = s.filterRestrictedScopes(ctx, userID, want); err != nil {
+			return "", err
+		}
+	}
+
+	if len(want) == 0 {
+		return "", newOAuthError("invalid_scope", "requested scope is not allowed for this client")
+	}
+	return strings.Join(want, " "), nil
+}
+
+// narrowScope — refresh boleh meminta subset scope grant asli (RFC 6749 §6)
+func narrowScope(original, requested string) (string, error) {
+	if strings.TrimSpace(requested) == "" {
+		return original, nil
+	}
+	for _, sc := range strings.Fields(requested) {
+		if !hasScope(original, sc) {
+			return "", newOAuthError("invalid_scope", "scope exceeds the original grant: "+sc)
+		}
+	}
+	return requested, nil
+}
+
+// filterRestrictedScopes membuang scope terbatas yang tidak di-grant ke user
+func (s *AuthService) filterRestrictedScopes(ctx context.Context, userID string, scopes []string) ([]string, error) {
+	if s.dep.ScopeRepo == nil || len(scopes) == 0 {
+		return scopes, nil
+	}
+	restricted, err := s.dep.ScopeRepo.ListRestricted(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var grants []string
+	loaded := false
+	out := make([]string, 0, len(scopes))
+	for _, sc := range scopes {
+		if containsString(restricted, sc) {
+			if !loaded {
+				if grants, err = s.dep.ScopeRepo.ListUserGrants(ctx, userID); err != nil {
+					return nil, err
+				}
+				loaded = true
+			}
+			if !containsString(grants, sc) {
+				continue
+			}
+		}
+		out = append(out, sc)
+	}
+	return out, nil
+}
+
+func clientScopes(c *entities.OAuthClient) []string {
+	if c.Scopes == nil || strings.TrimSpace(*c.Scopes) == "" {
+		return defaultClientScopes
+	}
+	return strings.Fields(*c.Scopes)
+}
+
+// scope-token RFC 6749 §3.3: %x21 / %x23-5B / %x5D-7E
+func validScopeToken(sc string) bool {
+	if sc == "" {
+		return false
+	}
+	for i := 0; i < len(sc); i++ {
+		ch := sc[i]
+		if ch < 0x21 || ch > 0x7e || ch == '"' || ch == '\\' {
+			return false
+		}
+	}
+	return true
+}
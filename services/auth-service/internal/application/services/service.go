@@ -1,23 +1,24 @@
 package services
 
 import (
-	"bytes"
 	"context"
 	"crypto/rand"
 	"crypto/sha256"
 	"crypto/subtle"
+	"crypto/x509"
 	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
-	"net/http"
+	"net"
 	"strings"
 	"time"
 
 	"bkc_microservice/services/auth-service/internal/domain/entities"
 	"bkc_microservice/services/auth-service/internal/domain/repositories"
+	"bkc_microservice/services/auth-service/internal/infrastructure/clients"
 	mfa "bkc_microservice/shared/mfa"
+	"bkc_microservice/shared/notify"
 	sharedsec "bkc_microservice/shared/security"
 	session "bkc_microservice/shared/session"
 
@@ -25,33 +26,73 @@ import (
 )
 
 type Dep struct {
-	UserRepo   repositories.UserRepository
-	ClientRepo repositories.ClientRepository
-	CodeRepo   repositories.AuthCodeRepository
-	TokenRepo  repositories.TokenRepository
-	KeyStore   *sharedsec.RS256KeyStore
-	RDB        *redis.Client
+	UserRepo      repositories.UserRepository
+	ClientRepo    repositories.ClientRepository
+	ClientSecrets repositories.ClientSecretRepository
+	CodeRepo      repositories.AuthCodeRepository
+	TokenRepo     repositories.TokenRepository
+	EventRepo     repositories.SecurityEventRepository
+	AuditRepo     repositories.TokenAuditRepository // nil => audit trail token mati
+	Recovery      repositories.RecoveryCodeRepository
+	ScopeRepo     repositories.ScopeRepository
+	ResourceRepo  repositories.ResourceRepository
+	ConsentRepo   repositories.ConsentRepository
+	KeyStore      *sharedsec.RS256KeyStore
+	RDB           *redis.Client
 
 	SessionManager *session.Manager
+	Denylist       *sharedsec.JTIDenylist // jti access token yang dicabut (logout); nil => tidak dicek
 	MFAService     *mfa.Service
+	SecretBox      *sharedsec.SecretBox // dekripsi users.two_factor_secret
+	Notifier       notify.Notifier
+
+	UserClient *clients.UserServiceClient
 
 	AccessTTL      time.Duration
 	RefreshTTL     time.Duration
 	CodeTTL        time.Duration
 	UserServiceURL string
+	PublicURL      string // base URL publik auth-service (discovery / endpoint OIDC)
+
+	// resource (RFC 8707) untuk request tanpa parameter resource; "" => aud = client_id
+	DefaultResource string
+
+	ClientSecretTTL     time.Duration // 0 => secret client tidak kedaluwarsa
+	SecretRotationGrace time.Duration // masa secret lama tetap berlaku setelah rotasi (default 24 jam)
+
+	AuthSessionKey []byte        // kunci HMAC cookie sesi login
+	AuthSessionTTL time.Duration // umur sesi login browser (default 12 jam)
+
+	DPoP      *sharedsec.DPoPVerifier // proof DPoP di /oauth/token; nil => DPoP tidak didukung
+	ClientCAs *x509.CertPool          // CA sertifikat client tls_client_auth; nil => metode tidak dipakai
+
+	CleanupBatchSize int           // baris per DELETE janitor (default 1000)
+	RevokedRetention time.Duration // refresh token dicabut tanpa expiry disimpan selama ini (default 30 hari)
+
+	Lockout        LockoutPolicy // lockout akun & throttling password grant; zero value => mati
+	TrustedProxies []*net.IPNet  // proxy yang X-Forwarded-For-nya dipercaya (gateway); kosong => RemoteAddr
 }
 
-type AuthService struct{ dep Dep }
+type AuthService struct {
+	dep        Dep
+	clientJWKS *clientJWKSCache // jwks_uri client private_key_jwt
+}
 
-func (s *AuthService) Dep() Dep           { return s.dep }
-func NewAuthService(dep Dep) *AuthService { return &AuthService{dep: dep} }
+func (s *AuthService) Dep() Dep { return s.dep }
+func NewAuthService(dep Dep) *AuthService {
+	return &AuthService{dep: dep, clientJWKS: newClientJWKSCache()}
+}
 
+// TokenResponse — response token endpoint dengan nama parameter RFC 6749 §5.1 / OIDC Core.
 type TokenResponse struct {
-	AccessToken  string `json:"accessToken"`
-	TokenType    string `json:"tokenType"`
-	ExpiresIn    int64  `json:"expiresIn"`
-	RefreshToken string `json:"refreshToken,omitempty"`
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
 	Scope        string `json:"scope,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+
+	IssuedTokenType string `json:"issued_token_type,omitempty"` // token exchange (RFC 8693)
 }
 
 /* =================== helpers =================== */
@@ -86,154 +127,196 @@ func optionalString(p *string) string {
 /************** GRANTS **************/
 
 // Client Credentials — tanpa refresh token
-func (s *AuthService) IssueClientCredentials(ctx context.Context, clientID, clientSecret, scope, companyID string) (*TokenResponse, error) {
-	c, err := s.dep.ClientRepo.FindByClientID(ctx, clientID)
+func (s *AuthService) IssueClientCredentials(ctx context.Context, auth ClientAuth, scope, companyID string, resources []string) (*TokenResponse, error) {
+	c, err := s.AuthenticateClient(ctx, auth)
 	if err != nil {
-		fmt.Println("Error finding client:", err)
+		log.Printf("[AuthService] client_credentials authentication failed client=%s: %v", auth.ClientID, err)
 		return nil, err
 	}
-	if c.Secret == nil {
+	if c.AuthMethod == AuthMethodNone {
 		return nil, errors.New("client has no secret")
 	}
-	if subtle.ConstantTimeCompare([]byte(*c.Secret), []byte(clientSecret)) != 1 {
-		return nil, errors.New("invalid client secret")
+	if !clientAllowsGrant(c, "client_credentials") {
+		return nil, ErrUnauthorizedClient
 	}
 
-	compID, err := s.pickCompanyID(companyID, c)
+	scope, err = s.resolveScopes(ctx, c, "", scope)
 	if err != nil {
 		return nil, err
 	}
-
-	at, err := s.dep.KeyStore.SignWithActive(sharedsec.TokenClaims{
-		Scope:    scope,
-		ClientID: c.ClientID,
-		Type:     "access",
-		Audience: []string{c.ClientID},
-		TenantID: compID,
-	}, s.dep.AccessTTL)
+	resources, scope, err = s.resolveResources(ctx, resources, scope)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := s.dep.TokenRepo.Save(ctx, &entities.Token{
-		UserID:           nil,
-		ClientID:         c.ID,
-		AccessToken:      at,
-		RefreshToken:     nil,
-		Scopes:           &scope,
-		ExpiresAt:        time.Now().Add(s.dep.AccessTTL),
-		RefreshExpiresAt: time.Time{},
-		CompanyID:        compID,
-	}); err != nil {
+	compID, err := s.pickCompanyID(companyID, c)
+	if err != nil {
 		return nil, err
 	}
 
-	return &TokenResponse{
-		AccessToken: at,
-		TokenType:   "Bearer",
-		ExpiresIn:   int64(s.dep.AccessTTL.Seconds()),
-		Scope:       scope,
-	}, nil
+	return s.issueTokens(ctx, tokenRequest{
+		Client:    c,
+		GrantType: "client_credentials",
+		Scope:     scope,
+		TenantID:  compID,
+		Resources: resources,
+	})
 }
 
 // Resource Owner Password Credentials (dev/internal)
-func (s *AuthService) IssuePassword(ctx context.Context, clientID, clientSecret, username, password, scope, companyID string) (*TokenResponse, error) {
-	c, err := s.dep.ClientRepo.FindByClientID(ctx, clientID)
+func (s *AuthService) IssuePassword(ctx context.Context, auth ClientAuth, username, password, scope, companyID string, resources []string) (*TokenResponse, error) {
+	c, err := s.AuthenticateClient(ctx, auth)
 	if err != nil {
 		return nil, err
 	}
-
-	if c == nil {
-		return nil, errors.New("invalid client 1")
+	if !clientAllowsGrant(c, "password") {
+		return nil, ErrUnauthorizedClient
 	}
 
-	if c.Secret != nil && subtle.ConstantTimeCompare([]byte(*c.Secret), []byte(clientSecret)) != 1 {
-		return nil, errors.New("invalid client 2")
+	throttleKey := loginThrottleKey(username, requestMetaFromContext(ctx).RemoteIP)
+	if err := s.checkLoginThrottle(ctx, throttleKey); err != nil {
+		return nil, err
 	}
-
 	u, err := s.dep.UserRepo.FindByEmail(ctx, username)
 	if err != nil {
-		return nil, errors.New("invalid credentials 1")
+		return nil, err
 	}
-	ok, err := s.dep.UserRepo.CheckPassword(ctx, u.ID, password)
-	if err != nil || !ok {
-		return nil, errors.New("invalid credentials 2")
+	if u == nil {
+		s.loginFailed(ctx, throttleKey, nil, c)
+		return nil, ErrInvalidUserCredentials
+	}
+	// akun terkunci dijawab sama dengan password salah supaya status kunci tidak bisa
+	// di-probe; pemilik akun (password benar) diberi tahu lewat notifier
+	ok, _ := s.dep.UserRepo.CheckPassword(ctx, u.ID, password)
+	if accountLocked(u, time.Now()) {
+		if ok {
+			s.notifyAccountLocked(ctx, u)
+		} else {
+			s.loginFailed(ctx, throttleKey, nil, c)
+		}
+		return nil, ErrInvalidUserCredentials
+	}
+	if !ok {
+		s.loginFailed(ctx, throttleKey, u, c)
+		return nil, ErrInvalidUserCredentials
+	}
+	if !u.IsActive {
+		return nil, ErrAccountDisabled
 	}
 
 	compID, err := s.pickCompanyID(companyID, c)
-	if err != nil || !ok {
+	if err != nil {
 		return nil, errors.New("company not found")
 	}
 
-	at, err := s.dep.KeyStore.SignWithActive(sharedsec.TokenClaims{
-		Scope:    scope,
-		ClientID: c.ClientID,
-		UserID:   u.ID,
-		Type:     "access",
-		Audience: []string{c.ClientID},
-		TenantID: compID,
-	}, s.dep.AccessTTL)
+	scope, err = s.resolveScopes(ctx, c, u.ID, scope)
 	if err != nil {
 		return nil, err
 	}
-
-	rt, err := s.dep.KeyStore.SignWithActive(sharedsec.TokenClaims{
-		Scope:    scope,
-		ClientID: c.ClientID,
-		UserID:   u.ID,
-		Type:     "refresh",
-		Audience: []string{c.ClientID},
-		TenantID: compID,
-	}, s.dep.RefreshTTL)
+	resources, scope, err = s.resolveResources(ctx, resources, scope)
 	if err != nil {
 		return nil, err
 	}
 
 	now := time.Now()
-	if err := s.dep.TokenRepo.Save(ctx, &entities.Token{
-		UserID:           &u.ID,
-		ClientID:         c.ID,
-		AccessToken:      at,
-		RefreshToken:     &rt,
-		Scopes:           &scope,
-		ExpiresAt:        now.Add(s.dep.AccessTTL),
-		RefreshExpiresAt: now.Add(s.dep.RefreshTTL),
-		CompanyID:        compID,
+	if err := s.requireMFA(ctx, u, mfaChallenge{
+		UserID:    u.ID,
+		ClientID:  c.ClientID,
+		Scope:     scope,
+		TenantID:  compID,
+		AuthTime:  &now,
+		Resources: resources,
+
+		ThrottleKey: throttleKey,
 	}); err != nil {
 		return nil, err
 	}
+	// hitungan gagal baru direset setelah seluruh faktor lolos (user 2FA: di CompleteMFA)
+	s.loginSucceeded(ctx, throttleKey, u.ID)
 
-	return &TokenResponse{
-		AccessToken:  at,
-		TokenType:    "Bearer",
-		ExpiresIn:    int64(s.dep.AccessTTL.Seconds()),
-		RefreshToken: rt,
-		Scope:        scope,
-	}, nil
+	return s.issueTokens(ctx, tokenRequest{
+		Client:      c,
+		GrantType:   "password",
+		UserID:      u.ID,
+		Scope:       scope,
+		TenantID:    compID,
+		WithRefresh: clientAllowsGrant(c, "refresh_token"),
+		AuthTime:    &now,
+		Resources:   resources,
+	})
 }
 
-func (s *AuthService) StartAuthorizationCode(ctx context.Context, userID, clientID, redirectURI, scope, codeChallenge, codeMethod, companyID string) (string, error) {
-	log.Println("[AuthService] StartAuthorizationCode called with:", userID, clientID, redirectURI, scope, codeChallenge, codeMethod, companyID)
+// AuthorizeClient — client dan redirect_uri /oauth/authorize yang sudah divalidasi.
+type AuthorizeClient struct {
+	ClientID    string
+	ClientName  string
+	RedirectURI string
+}
+
+// ValidateAuthorizeRedirect memvalidasi client_id + redirect_uri. Error di sini tidak boleh
+// di-redirect ke client; setelah lolos, error protokol dikirim balik lewat redirect_uri.
+func (s *AuthService) ValidateAuthorizeRedirect(ctx context.Context, clientID, redirectURI string) (*AuthorizeClient, error) {
+	c, redirectURI, err := s.authorizeClient(ctx, clientID, redirectURI)
+	if err != nil {
+		return nil, err
+	}
+	name := optionalString(c.Name)
+	if name == "" {
+		name = c.ClientID
+	}
+	return &AuthorizeClient{ClientID: c.ClientID, ClientName: name, RedirectURI: redirectURI}, nil
+}
+
+func (s *AuthService) authorizeClient(ctx context.Context, clientID, redirectURI string) (*entities.OAuthClient, string, error) {
 	c, err := s.dep.ClientRepo.FindByClientID(ctx, clientID)
-	log.Printf("[AuthService] Client found: id=%s, redirect_uri=%v, company_id=%v", c.ID, c.RedirectURI, c.CompanyID)
+	if err != nil {
+		return nil, "", err
+	}
+	if c == nil {
+		return nil, "", errors.New("invalid client")
+	}
 
+	// redirect_uri wajib terdaftar dan cocok persis
+	switch {
+	case len(c.RedirectURIs) == 0:
+		return nil, "", errors.New("client has no registered redirect_uri")
+	case redirectURI == "" && len(c.RedirectURIs) == 1:
+		redirectURI = c.RedirectURIs[0]
+	case redirectURI == "":
+		return nil, "", errors.New("redirect_uri required")
+	case !containsString(c.RedirectURIs, redirectURI):
+		return nil, "", errors.New("invalid redirect_uri")
+	}
+	return c, redirectURI, nil
+}
+
+// StartAuthorizationCode menerbitkan authorization code untuk user yang sudah login
+// (sesi browser) dan mengembalikan redirect_uri yang sudah divalidasi.
+func (s *AuthService) StartAuthorizationCode(ctx context.Context, userID, clientID, redirectURI, scope, codeChallenge, codeMethod, companyID, nonce string, resources []string, authTime time.Time) (string, string, error) {
+	c, redirectURI, err := s.authorizeClient(ctx, clientID, redirectURI)
 	if err != nil {
-		return "", err
+		return "", "", err
+	}
+	if userID == "" {
+		return "", redirectURI, ErrLoginRequired
 	}
 
-	if c.RedirectURI != nil {
-		if redirectURI == "" {
-			redirectURI = *c.RedirectURI
-		} else if redirectURI != *c.RedirectURI {
-			return "", errors.New("invalid redirect_uri")
-		}
-	} else if redirectURI == "" {
-		return "", errors.New("redirect_uri required")
+	// error setelah redirect_uri valid dikirim balik ke client via redirect
+	if !clientAllowsGrant(c, "authorization_code") {
+		return "", redirectURI, ErrUnauthorizedClient
+	}
+	scope, err = s.resolveScopes(ctx, c, userID, scope)
+	if err != nil {
+		return "", redirectURI, err
+	}
+	resources, scope, err = s.resolveResources(ctx, resources, scope)
+	if err != nil {
+		return "", redirectURI, err
 	}
 
 	compID, err := s.pickCompanyID(companyID, c)
 	if err != nil {
-		return "", err
+		return "", redirectURI, err
 	}
 
 	buf := make([]byte, 24)
@@ -254,6 +337,7 @@ func (s *AuthService) StartAuthorizationCode(ctx context.Context, userID, client
 		sc = &scope
 	}
 
+	now := time.Now()
 	ac := &entities.AuthCode{
 		Code:                code,
 		UserID:              userID,
@@ -262,33 +346,39 @@ func (s *AuthService) StartAuthorizationCode(ctx context.Context, userID, client
 		CodeChallengeMethod: cm,
 		RedirectURI:         ru,
 		Scopes:              sc,
-		ExpiresAt:           time.Now().Add(s.dep.CodeTTL),
+		Nonce:               strptr(nonce),
+		AuthTime:            &authTime,
+		Resources:           joinResources(resources),
+		ExpiresAt:           now.Add(s.dep.CodeTTL),
 		CompanyID:           strptr(compID),
 	}
 	if err := s.dep.CodeRepo.Save(ctx, ac); err != nil {
-		return "", err
+		return "", redirectURI, err
 	}
 
 	log.Printf(
 		"[AuthService] Saved auth code: code=%s user_id=%s client_id=%s tenant=%s expires_at=%s",
 		ac.Code, ac.UserID, ac.ClientID, optionalString(ac.CompanyID), ac.ExpiresAt.Format(time.RFC3339),
 	)
-	return code, nil
+	return code, redirectURI, nil
 }
 
-func (s *AuthService) ExchangeAuthorizationCode(ctx context.Context, clientID, clientSecret, code, redirectURI, codeVerifier string) (*TokenResponse, error) {
-	c, err := s.dep.ClientRepo.FindByClientID(ctx, clientID)
-	log.Println("Client fetched during code exchange:", c)
+// resources (opsional) mempersempit resource yang disetujui saat authorize.
+func (s *AuthService) ExchangeAuthorizationCode(ctx context.Context, auth ClientAuth, code, redirectURI, codeVerifier string, resources []string) (*TokenResponse, error) {
+	c, err := s.AuthenticateClient(ctx, auth)
 	if err != nil {
-		log.Println("Error finding client during code exchange:", err)
+		log.Println("Error authenticating client during code exchange:", err)
 		return nil, err
 	}
-	if c.Secret != nil && subtle.ConstantTimeCompare([]byte(*c.Secret), []byte(clientSecret)) != 1 {
-		return nil, errors.New("invalid client")
+	if !clientAllowsGrant(c, "authorization_code") {
+		return nil, ErrUnauthorizedClient
 	}
 
 	ac, err := s.dep.CodeRepo.FindValid(ctx, code, time.Now())
-	if err != nil {
+	if err != nil || ac == nil {
+		return nil, errors.New("invalid code")
+	}
+	if ac.ClientID != c.ID {
 		return nil, errors.New("invalid code")
 	}
 
@@ -314,60 +404,51 @@ func (s *AuthService) ExchangeAuthorizationCode(ctx context.Context, clientID, c
 	if ac.Scopes != nil {
 		scope = *ac.Scopes
 	}
-
-	tenant := optionalString(ac.CompanyID)
-
-	at, err := s.dep.KeyStore.SignWithActive(sharedsec.TokenClaims{
-		Scope:    scope,
-		ClientID: c.ClientID,
-		UserID:   ac.UserID,
-		Type:     "access",
-		Audience: []string{c.ClientID},
-		TenantID: tenant,
-	}, s.dep.AccessTTL)
+	resources, err = narrowResources(splitResources(ac.Resources), resources)
 	if err != nil {
 		return nil, err
 	}
-
-	rt, err := s.dep.KeyStore.SignWithActive(sharedsec.TokenClaims{
-		Scope:    scope,
-		ClientID: c.ClientID,
-		UserID:   ac.UserID,
-		Type:     "refresh",
-		Audience: []string{c.ClientID},
-		TenantID: tenant,
-	}, s.dep.RefreshTTL)
-	if err != nil {
+	if resources, scope, err = s.resolveResources(ctx, resources, scope); err != nil {
 		return nil, err
 	}
 
+	tenant := optionalString(ac.CompanyID)
+
 	_ = s.dep.CodeRepo.DeleteByCode(ctx, code)
 
-	now := time.Now()
-	if err := s.dep.TokenRepo.Save(ctx, &entities.Token{
-		UserID:           &ac.UserID,
-		ClientID:         c.ID,
-		AccessToken:      at,
-		RefreshToken:     &rt,
-		Scopes:           &scope,
-		ExpiresAt:        now.Add(s.dep.AccessTTL),
-		RefreshExpiresAt: now.Add(s.dep.RefreshTTL),
-		CompanyID:        tenant,
+	u, err := s.dep.UserRepo.FindByID(ctx, ac.UserID)
+	if err != nil || u == nil {
+		return nil, errors.New("invalid code")
+	}
+	if err := s.requireMFA(ctx, u, mfaChallenge{
+		UserID:    ac.UserID,
+		ClientID:  c.ClientID,
+		Scope:     scope,
+		TenantID:  tenant,
+		Nonce:     optionalString(ac.Nonce),
+		AuthTime:  ac.AuthTime,
+		Resources: resources,
 	}); err != nil {
 		return nil, err
 	}
 
-	return &TokenResponse{
-		AccessToken:  at,
-		TokenType:    "Bearer",
-		ExpiresIn:    int64(s.dep.AccessTTL.Seconds()),
-		RefreshToken: rt,
-		Scope:        scope,
-	}, nil
+	return s.issueTokens(ctx, tokenRequest{
+		Client:      c,
+		GrantType:   "authorization_code",
+		UserID:      ac.UserID,
+		Scope:       scope,
+		TenantID:    tenant,
+		WithRefresh: clientAllowsGrant(c, "refresh_token"),
+		Nonce:       optionalString(ac.Nonce),
+		AuthTime:    ac.AuthTime,
+		Resources:   resources,
+	})
 }
 
-func (s *AuthService) Refresh(ctx context.Context, clientID, refreshToken string) (*TokenResponse, error) {
-	tok, err := s.dep.TokenRepo.FindByRefreshToken(ctx, refreshToken)
+// Refresh merotasi refresh token. scope / resources (opsional) mempersempit access token
+// baru; grant refresh token sendiri tidak berubah.
+func (s *AuthService) Refresh(ctx context.Context, auth ClientAuth, refreshToken, scope string, resources []string) (*TokenResponse, error) {
+	tok, err := s.dep.TokenRepo.FindByRefreshTokenIncludingRevoked(ctx, refreshToken)
 	if err != nil {
 		return nil, errors.New("invalid refresh_token")
 	}
@@ -375,75 +456,84 @@ func (s *AuthService) Refresh(ctx context.Context, clientID, refreshToken string
 		return nil, errors.New("refresh_token_not_found")
 	}
 
-	c, err := s.dep.ClientRepo.FindByClientID(ctx, clientID)
+	// RFC 6749 §6: client confidential wajib autentikasi; hanya client publik (none) cukup client_id
+	c, err := s.AuthenticateClient(ctx, auth)
 	if err != nil {
-		return nil, errors.New("invalid client_id")
+		return nil, err
 	}
 	if tok.ClientID != c.ID {
 		return nil, errors.New("invalid client_id")
 	}
+	if !clientAllowsGrant(c, "refresh_token") {
+		return nil, ErrUnauthorizedClient
+	}
+
+	// token lama dipakai lagi => kemungkinan dicuri, cabut seluruh family
+	if tok.Revoked {
+		s.revokeFamilyOnReuse(ctx, tok)
+		return nil, ErrRefreshTokenReused
+	}
 
 	// cek refresh expiry
 	refreshDeadline := tok.RefreshExpiresAt
 	if refreshDeadline.IsZero() {
-		refreshDeadline = tok.CreatedAt.Add(s.dep.RefreshTTL)
+		refreshDeadline = tok.CreatedAt.Add(s.refreshTTL(c))
 	}
 	if time.Now().After(refreshDeadline) {
 		return nil, errors.New("refresh_token_expired")
 	}
-
-	scope := ""
-	if tok.Scopes != nil {
-		scope = *tok.Scopes
+	if err := checkRefreshBinding(ctx, tok); err != nil {
+		return nil, err
 	}
 
-	tenant := tok.CompanyID
-
-	at, err := s.dep.KeyStore.SignWithActive(sharedsec.TokenClaims{
-		Scope:    scope,
-		ClientID: c.ClientID,
-		Type:     "access",
-		Audience: []string{c.ClientID},
-		TenantID: tenant,
-	}, s.dep.AccessTTL)
+	userID := optionalString(tok.UserID)
+	grant := optionalString(tok.Scopes)
+	if grant != "" {
+		// scope client / grant user bisa sudah dicabut sejak token diterbitkan
+		if grant, err = s.resolveScopes(ctx, c, userID, grant); err != nil {
+			return nil, err
+		}
+	}
+	scope, err = narrowScope(grant, scope)
 	if err != nil {
 		return nil, err
 	}
-
-	newRT, err := s.dep.KeyStore.SignWithActive(sharedsec.TokenClaims{
-		Scope:    scope,
-		ClientID: c.ClientID,
-		Type:     "refresh",
-		Audience: []string{c.ClientID},
-		TenantID: tenant,
-	}, s.dep.RefreshTTL)
+	grantResources := splitResources(tok.Resources)
+	resources, err = narrowResources(grantResources, resources)
 	if err != nil {
 		return nil, err
 	}
-
-	now := time.Now()
-	if err := s.dep.TokenRepo.Save(ctx, &entities.Token{
-		UserID:           tok.UserID,
-		ClientID:         c.ID,
-		AccessToken:      at,
-		RefreshToken:     &newRT,
-		Scopes:           &scope,
-		ExpiresAt:        now.Add(s.dep.AccessTTL),
-		RefreshExpiresAt: now.Add(s.dep.RefreshTTL),
-		CompanyID:        tenant,
-	}); err != nil {
+	if resources, scope, err = s.resolveResources(ctx, resources, scope); err != nil {
 		return nil, err
 	}
 
-	_ = s.dep.TokenRepo.RevokeByRefreshToken(ctx, refreshToken)
+	tenant := tok.CompanyID
+
+	// klaim token secara atomik; kalah balapan = token sudah dirotasi request lain
+	ok, err := s.dep.TokenRepo.MarkRefreshRotated(ctx, tok.RefreshTokenID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		s.revokeFamilyOnReuse(ctx, tok)
+		return nil, ErrRefreshTokenReused
+	}
 
-	return &TokenResponse{
-		AccessToken:  at,
-		TokenType:    "Bearer",
-		ExpiresIn:    int64(s.dep.AccessTTL.Seconds()),
-		RefreshToken: newRT,
+	return s.issueTokens(ctx, tokenRequest{
+		Client:       c,
+		GrantType:    "refresh_token",
+		UserID:       userID,
 		Scope:        scope,
-	}, nil
+		RefreshScope: grant,
+		TenantID:     tenant,
+		WithRefresh:  true,
+		AuthTime:     tok.AuthTime,
+		FamilyID:     tok.FamilyID,
+		ParentID:     &tok.RefreshTokenID,
+
+		Resources:        resources,
+		RefreshResources: grantResources,
+	})
 }
 
 /************** INTROSPECT / REVOKE **************/
@@ -458,18 +548,47 @@ type IntrospectionResult struct {
 	Iat       *int64   `json:"iat,omitempty"`
 	Sub       string   `json:"sub,omitempty"`
 	Aud       []string `json:"aud,omitempty"`
+
+	Act *sharedsec.Actor        `json:"act,omitempty"` // token exchange (RFC 8693)
+	Cnf *sharedsec.Confirmation `json:"cnf,omitempty"` // binding DPoP (RFC 9449 §6.2)
 }
 
+// Introspect (RFC 7662); token tidak aktif dicatat di audit trail.
 func (s *AuthService) Introspect(ctx context.Context, token, tokenTypeHint, callerClientPublicID string) (*IntrospectionResult, error) {
-	if s.isBlacklisted(ctx, token) {
-		return &IntrospectionResult{Active: false}, nil
+	res, err := s.introspect(ctx, token, tokenTypeHint, callerClientPublicID)
+	if err == nil && !res.Active {
+		a := &entities.TokenAudit{
+			Event:     AuditIntrospectionFailed,
+			TokenType: auditTokenType(tokenTypeHint),
+		}
+		if callerClientPublicID != "" {
+			a.Detail = strptr("inactive token presented by client " + callerClientPublicID)
+		}
+		if c, cErr := s.dep.ClientRepo.FindByClientID(ctx, callerClientPublicID); cErr == nil && c != nil {
+			a.ClientID, a.CompanyID = strptr(c.ID), c.CompanyID
+		}
+		s.recordTokenAudit(ctx, a)
 	}
+	return res, err
+}
 
+func (s *AuthService) introspect(ctx context.Context, token, tokenTypeHint, callerClientPublicID string) (*IntrospectionResult, error) {
 	if tokenTypeHint == "" || strings.EqualFold(tokenTypeHint, "access_token") {
 		if t, err := s.dep.TokenRepo.FindByAccessToken(ctx, token); err == nil && t != nil {
+			// aud, act & cnf dari JWT; token hasil exchange ditujukan ke service downstream
+			aud := []string{t.ClientID}
+			var act *sharedsec.Actor
+			var cnf *sharedsec.Confirmation
+			if claims, err := s.dep.KeyStore.Verify(token); err == nil {
+				if s.jtiRevoked(ctx, claims) {
+					return &IntrospectionResult{Active: false}, nil
+				}
+				aud, act, cnf = claims.RegisteredClaims.Audience, claims.Actor, claims.Confirmation
+			}
+
 			if callerClientPublicID != "" {
 				c, err := s.dep.ClientRepo.FindByClientID(ctx, callerClientPublicID)
-				if err != nil || c == nil || t.ClientID != c.ID {
+				if err != nil || c == nil || (t.ClientID != c.ID && !containsString(aud, c.ClientID)) {
 					return &IntrospectionResult{Active: false}, nil
 				}
 			}
@@ -501,7 +620,9 @@ func (s *AuthService) Introspect(ctx context.Context, token, tokenTypeHint, call
 				Exp:       exp,
 				Iat:       iat,
 				Sub:       sub,
-				Aud:       []string{t.ClientID},
+				Aud:       aud,
+				Act:       act,
+				Cnf:       cnf,
 			}, nil
 		}
 	}
@@ -554,118 +675,128 @@ func (s *AuthService) Introspect(ctx context.Context, token, tokenTypeHint, call
 }
 
 func (s *AuthService) Revoke(ctx context.Context, token, tokenTypeHint string) error {
+	s.auditRevoke(ctx, token, tokenTypeHint)
+
 	if strings.EqualFold(tokenTypeHint, "refresh_token") {
 		_ = s.dep.TokenRepo.RevokeByRefreshToken(ctx, token)
 		return nil
 	}
 
-	if err := s.dep.TokenRepo.RevokeByAccessToken(ctx, token); err == nil {
+	// access token: cabut di repo lalu jti ke denylist supaya resource server ikut menolak
+	if t, err := s.dep.TokenRepo.FindByAccessToken(ctx, token); err == nil && t != nil {
+		if err := s.dep.TokenRepo.RevokeByAccessToken(ctx, token); err != nil {
+			return err
+		}
+		if claims, err := s.dep.KeyStore.Verify(token); err == nil && claims.ExpiresAt != nil {
+			s.denyJTI(ctx, claims.ID, claims.ExpiresAt.Time)
+		}
 		return nil
 	}
 
 	_ = s.dep.TokenRepo.RevokeByRefreshToken(ctx, token)
+	return nil
+}
 
-	if strings.EqualFold(tokenTypeHint, "access_token") {
-		if t, err := s.dep.TokenRepo.FindByAccessToken(ctx, token); err == nil && t != nil {
-			ttl := time.Until(t.ExpiresAt)
-			if ttl < 0 {
-				ttl = 0
-			}
-			s.blacklistAccess(ctx, token, ttl)
-		}
+// auditRevoke mencatat pemilik token yang dicabut; token tidak dikenal tidak dicatat.
+func (s *AuthService) auditRevoke(ctx context.Context, token, tokenTypeHint string) {
+	var t *entities.Token
+	tokenType := "access"
+	if !strings.EqualFold(tokenTypeHint, "refresh_token") {
+		t, _ = s.dep.TokenRepo.FindByAccessToken(ctx, token)
 	}
-	return nil
+	if t == nil {
+		t, _ = s.dep.TokenRepo.FindByRefreshToken(ctx, token)
+		tokenType = "refresh"
+	}
+	if t == nil {
+		return
+	}
+	s.recordTokenAudit(ctx, &entities.TokenAudit{
+		Event:     AuditTokenRevoked,
+		CompanyID: strptr(t.CompanyID),
+		ClientID:  strptr(t.ClientID),
+		UserID:    t.UserID,
+		TokenType: tokenType,
+	})
+}
+
+func auditTokenType(hint string) string {
+	switch {
+	case strings.EqualFold(hint, "refresh_token"):
+		return "refresh"
+	case strings.EqualFold(hint, "access_token"):
+		return "access"
+	}
+	return "unknown"
 }
 
 func (s *AuthService) IssueTokenPair(ctx context.Context, userID, clientID, companyID string) (access, refresh string, err error) {
 	client, err := s.dep.ClientRepo.FindByClientID(ctx, clientID)
-	if err != nil {
+	if err != nil || client == nil {
 		return "", "", errors.New("invalid client")
 	}
 
-	scope := "openid profile email offline_access"
-
-	at, err := s.dep.KeyStore.SignWithActive(sharedsec.TokenClaims{
-		Scope:    scope,
-		ClientID: client.ClientID,
-		UserID:   userID,
-		Type:     "access",
-		Audience: []string{client.ClientID},
-		TenantID: companyID,
-	}, s.dep.AccessTTL)
+	scope, err := s.resolveScopes(ctx, client, userID, firstPartyScope)
 	if err != nil {
 		return "", "", err
 	}
-
-	rt, err := s.dep.KeyStore.SignWithActive(sharedsec.TokenClaims{
-		Scope:    scope,
-		ClientID: client.ClientID,
-		UserID:   userID,
-		Type:     "refresh",
-		Audience: []string{client.ClientID},
-		TenantID: companyID,
-	}, s.dep.RefreshTTL)
+	resources, scope, err := s.resolveResources(ctx, nil, scope)
 	if err != nil {
 		return "", "", err
 	}
 
 	now := time.Now()
-	if err := s.dep.TokenRepo.Save(ctx, &entities.Token{
-		UserID:           &userID,
-		ClientID:         client.ID,
-		AccessToken:      at,
-		RefreshToken:     &rt,
-		Scopes:           &scope,
-		ExpiresAt:        now.Add(s.dep.AccessTTL),
-		RefreshExpiresAt: now.Add(s.dep.RefreshTTL),
-		CompanyID:        companyID,
-	}); err != nil {
+	res, err := s.issueTokens(ctx, tokenRequest{
+		Client:      client,
+		GrantType:   "password",
+		UserID:      userID,
+		Scope:       scope,
+		TenantID:    companyID,
+		WithRefresh: true,
+		AuthTime:    &now,
+		Resources:   resources,
+	})
+	if err != nil {
 		return "", "", err
 	}
 
-	return at, rt, nil
+	return res.AccessToken, res.RefreshToken, nil
 }
 
 func (s *AuthService) LoginWithPasswordGrant(ctx context.Context, email, password, clientID, clientSecret string) (map[string]any, error) {
-	log.Println("LoginWithPasswordGrant called with:", email)
-	log.Println("Password received:", password)
-	client, err := s.dep.ClientRepo.FindByClientID(ctx, clientID)
-	if err != nil {
-		return nil, errors.New("invalid client 1")
+	client, err := s.AuthenticateClient(ctx, ClientAuth{ClientID: clientID, ClientSecret: clientSecret})
+	if err != nil || client.AuthMethod == AuthMethodNone {
+		return nil, ErrInvalidClient
 	}
 
-	if client.Secret == nil || *client.Secret != clientSecret {
-		return nil, errors.New("invalid client 2")
+	// validasi email+password di user-service
+	userData, err := s.authenticateUser(ctx, email, password)
+	if err != nil {
+		return nil, err
 	}
+	companyID := optionalString(client.CompanyID)
 
-	// Panggil user-service buat validasi email+password
-	userSvcURL := fmt.Sprintf("%s/internal/users/authenticate", s.dep.UserServiceURL)
-	reqBody, _ := json.Marshal(map[string]string{
-		"email":    email,
-		"password": password,
-	})
-
-	resp, err := http.Post(userSvcURL, "application/json", bytes.NewBuffer(reqBody))
+	u, err := s.dep.UserRepo.FindByID(ctx, userData.ID)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New("invalid user credentials")
+	scope, err := s.resolveScopes(ctx, client, userData.ID, firstPartyScope)
+	if err != nil {
+		return nil, err
 	}
 
-	var userData struct {
-		ID        string `json:"id"`
-		Email     string `json:"email"`
-		CompanyID string `json:"companyId"`
-		RoleID    string `json:"roleId"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&userData); err != nil {
+	now := time.Now()
+	if err := s.requireMFA(ctx, u, mfaChallenge{
+		UserID:   userData.ID,
+		ClientID: clientID,
+		Scope:    scope,
+		TenantID: companyID,
+		AuthTime: &now,
+	}); err != nil {
 		return nil, err
 	}
 
-	access, refresh, err := s.IssueTokenPair(ctx, userData.ID, clientID, userData.CompanyID)
+	access, refresh, err := s.IssueTokenPair(ctx, userData.ID, clientID, companyID)
 	if err != nil {
 		return nil, err
 	}
@@ -678,7 +809,7 @@ func (s *AuthService) LoginWithPasswordGrant(ctx context.Context, email, passwor
 		"user": map[string]any{
 			"id":         userData.ID,
 			"email":      userData.Email,
-			"company_id": userData.CompanyID,
+			"company_id": companyID,
 			"role_id":    userData.RoleID,
 		},
 	}, nil
@@ -688,18 +819,3 @@ func pkceS256(verifier string) string {
 	sum := sha256.Sum256([]byte(verifier))
 	return base64.RawURLEncoding.EncodeToString(sum[:])
 }
-
-func (s *AuthService) blacklistAccess(ctx context.Context, token string, ttl time.Duration) {
-	if s.dep.RDB == nil {
-		return
-	}
-	_ = s.dep.RDB.Set(ctx, "bl:at:"+token, "1", ttl).Err()
-}
-
-func (s *AuthService) isBlacklisted(ctx context.Context, token string) bool {
-	if s.dep.RDB == nil {
-		return false
-	}
-	v, _ := s.dep.RDB.Exists(ctx, "bl:at:"+token).Result()
-	return v == 1
-}
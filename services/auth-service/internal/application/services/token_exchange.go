@@ -0,0 +1,281 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"bkc_microservice/services/auth-service/internal/domain/entities"
+	sharedsec "bkc_microservice/shared/security"
+)
+
+// Token Exchange (RFC 8693): access token user ditukar menjadi token untuk audience
+// downstream dengan scope dipersempit. Dua mode:
+//   - delegasi: subject_token = token user, token baru membawa klaim "act"
+//     (client pemanggil atau pemilik actor_token) di atas rantai actor sebelumnya;
+//   - impersonasi (support staff): subject_token_type user_id, actor_token milik staf
+//     dengan scope support:impersonate; token baru tanpa "act" dan selalu diaudit.
+
+const (
+	GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+	TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+	TokenTypeUserID      = "urn:bkc:params:oauth:token-type:user_id"
+
+	ScopeImpersonate = "support:impersonate"
+
+	impersonationMaxTTL        = 15 * time.Minute
+	eventImpersonationIssued   = "impersonation_token_issued"
+	eventImpersonationRejected = "impersonation_rejected"
+)
+
+var (
+	ErrInvalidSubjectToken = newOAuthError("invalid_grant", "invalid subject_token")
+	ErrInvalidActorToken   = newOAuthError("invalid_grant", "invalid actor_token")
+)
+
+// TokenExchangeRequest — parameter grant token-exchange.
+type TokenExchangeRequest struct {
+	SubjectToken       string
+	SubjectTokenType   string
+	ActorToken         string
+	ActorTokenType     string
+	RequestedTokenType string
+	Audience           []string // client_id service downstream
+	Resource           []string // resource terdaftar (RFC 8707)
+	Scope              string
+}
+
+// ExchangeToken menerbitkan access token baru dari subject_token (RFC 8693 §2).
+func (s *AuthService) ExchangeToken(ctx context.Context, auth ClientAuth, req TokenExchangeRequest) (*TokenResponse, error) {
+	c, err := s.AuthenticateClient(ctx, auth)
+	if err != nil {
+		return nil, err
+	}
+	if c.AuthMethod == AuthMethodNone {
+		return nil, ErrInvalidClient
+	}
+	if !clientAllowsGrant(c, GrantTypeTokenExchange) {
+		return nil, ErrUnauthorizedClient
+	}
+	if req.RequestedTokenType != "" && req.RequestedTokenType != TokenTypeAccessToken {
+		return nil, newOAuthError("invalid_request", "unsupported requested_token_type")
+	}
+	if req.SubjectToken == "" || req.SubjectTokenType == "" {
+		return nil, newOAuthError("invalid_request", "subject_token and subject_token_type are required")
+	}
+	if req.ActorToken != "" && req.ActorTokenType != TokenTypeAccessToken {
+		return nil, newOAuthError("invalid_request", "unsupported actor_token_type")
+	}
+	if err := s.checkExchangeAudience(ctx, req.Audience, req.Resource); err != nil {
+		return nil, err
+	}
+
+	var res *TokenResponse
+	switch req.SubjectTokenType {
+	case TokenTypeAccessToken:
+		res, err = s.delegate(ctx, c, req)
+	case TokenTypeUserID:
+		res, err = s.impersonate(ctx, c, req)
+	default:
+		return nil, newOAuthError("invalid_request", "unsupported subject_token_type")
+	}
+	if err != nil {
+		return nil, err
+	}
+	res.IssuedTokenType = TokenTypeAccessToken
+	return res, nil
+}
+
+func (s *AuthService) delegate(ctx context.Context, c *entities.OAuthClient, req TokenExchangeRequest) (*TokenResponse, error) {
+	subject, err := s.verifyAccessToken(ctx, req.SubjectToken, "")
+	if err != nil {
+		return nil, ErrInvalidSubjectToken
+	}
+	scope, err := narrowScope(subject.Scope, req.Scope)
+	if err != nil {
+		return nil, err
+	}
+	// token turunan dibatasi juga oleh scope client pemanggil; scope terbatas tidak didelegasikan
+	var allowed []string
+	for _, sc := range strings.Fields(scope) {
+		if containsString(clientScopes(c), sc) {
+			allowed = append(allowed, sc)
+		}
+	}
+	if len(allowed) == 0 {
+		return nil, newOAuthError("invalid_scope", "requested scope is not allowed for this client")
+	}
+	if scope, err = s.dropRestrictedScopes(ctx, strings.Join(allowed, " ")); err != nil {
+		return nil, err
+	}
+	audience, scope, err := s.exchangeAudience(ctx, req, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	// actor = pemilik actor_token bila ada, selain itu client pemanggil
+	act := &sharedsec.Actor{Subject: "client:" + c.ClientID, ClientID: c.ClientID, Actor: subject.Actor}
+	if req.ActorToken != "" {
+		actor, err := s.verifyAccessToken(ctx, req.ActorToken, "")
+		if err != nil {
+			return nil, ErrInvalidActorToken
+		}
+		act = &sharedsec.Actor{Subject: actor.Subject, ClientID: actor.ClientID, Actor: subject.Actor}
+	}
+
+	// token hasil exchange tidak boleh hidup lebih lama dari subject_token
+	ttl := s.accessTTL(c)
+	if subject.ExpiresAt != nil {
+		if remaining := time.Until(subject.ExpiresAt.Time); remaining < ttl {
+			ttl = remaining
+		}
+	}
+
+	log.Printf("[AuthService] token exchange client=%s subject=%s actor=%v aud=%v",
+		c.ClientID, subject.Subject, act.Chain(), audience)
+	return s.issueTokens(ctx, tokenRequest{
+		Client:    c,
+		GrantType: GrantTypeTokenExchange,
+		UserID:    subject.UserID,
+		Scope:     scope,
+		TenantID:  subject.TenantID,
+		Audience:  audience,
+		Actor:     act,
+		TTL:       ttl,
+		NoIDToken: true,
+	})
+}
+
+func (s *AuthService) impersonate(ctx context.Context, c *entities.OAuthClient, req TokenExchangeRequest) (*TokenResponse, error) {
+	if req.ActorToken == "" {
+		return nil, newOAuthError("invalid_request", "actor_token is required for impersonation")
+	}
+	staff, err := s.verifyAccessToken(ctx, req.ActorToken, ScopeImpersonate)
+	if err != nil || staff.UserID == "" {
+		s.auditImpersonation(ctx, eventImpersonationRejected, c, staff, req.SubjectToken, "", "")
+		return nil, ErrInvalidActorToken
+	}
+	if staff.UserID == req.SubjectToken {
+		return nil, newOAuthError("invalid_request", "cannot impersonate yourself")
+	}
+
+	target, err := s.dep.UserRepo.FindByID(ctx, req.SubjectToken)
+	if err != nil || target == nil {
+		return nil, ErrInvalidSubjectToken
+	}
+	// akun nonaktif / terkunci tidak boleh dipakai lewat impersonasi
+	if !target.IsActive || accountLocked(target, time.Now()) {
+		s.auditImpersonation(ctx, eventImpersonationRejected, c, staff, target.ID, "", "")
+		return nil, ErrInvalidSubjectToken
+	}
+
+	// scope terbatas (admin, impersonate, dll) tidak pernah ikut token impersonasi
+	scope, err := s.resolveScopes(ctx, c, "", req.Scope)
+	if err != nil {
+		return nil, err
+	}
+	if scope, err = s.dropRestrictedScopes(ctx, scope); err != nil {
+		return nil, err
+	}
+	audience, scope, err := s.exchangeAudience(ctx, req, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.issueTokens(ctx, tokenRequest{
+		Client:    c,
+		GrantType: GrantTypeTokenExchange,
+		UserID:    target.ID,
+		Scope:     scope,
+		TenantID:  staff.TenantID,
+		Audience:  audience,
+		TTL:       impersonationMaxTTL,
+		NoIDToken: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.auditImpersonation(ctx, eventImpersonationIssued, c, staff, target.ID, scope, strings.Join(audience, " "))
+	return res, nil
+}
+
+// checkExchangeAudience — audience / resource wajib; audience harus client terdaftar
+// (service downstream), resource divalidasi registry di exchangeAudience.
+func (s *AuthService) checkExchangeAudience(ctx context.Context, audience, resource []string) error {
+	if len(audience) == 0 && len(resource) == 0 {
+		return newOAuthError("invalid_request", "audience or resource is required")
+	}
+	for _, aud := range audience {
+		t, err := s.dep.ClientRepo.FindByClientID(ctx, aud)
+		if err != nil || t == nil {
+			return newOAuthError("invalid_target", "audience is not a registered client")
+		}
+	}
+	return nil
+}
+
+// exchangeAudience menggabungkan audience dengan resource; resource ikut mempersempit scope.
+func (s *AuthService) exchangeAudience(ctx context.Context, req TokenExchangeRequest, scope string) ([]string, string, error) {
+	if len(req.Resource) == 0 {
+		return req.Audience, scope, nil
+	}
+	resources, scope, err := s.resolveResources(ctx, req.Resource, scope)
+	if err != nil {
+		return nil, "", err
+	}
+	return append(append([]string{}, req.Audience...), resources...), scope, nil
+}
+
+func (s *AuthService) dropRestrictedScopes(ctx context.Context, scope string) (string, error) {
+	if s.dep.ScopeRepo == nil {
+		return scope, nil
+	}
+	restricted, err := s.dep.ScopeRepo.ListRestricted(ctx)
+	if err != nil {
+		return "", err
+	}
+	var out []string
+	for _, sc := range strings.Fields(scope) {
+		if !containsString(restricted, sc) {
+			out = append(out, sc)
+		}
+	}
+	if len(out) == 0 {
+		return "", newOAuthError("invalid_scope", "no scope left for token exchange")
+	}
+	return strings.Join(out, " "), nil
+}
+
+func (s *AuthService) auditImpersonation(ctx context.Context, eventType string, c *entities.OAuthClient, staff *sharedsec.TokenClaims, targetUserID, scope, audience string) {
+	staffID := ""
+	if staff != nil {
+		staffID = staff.UserID
+	}
+	log.Printf("[AuthService] %s staff=%s target=%s client=%s", eventType, staffID, targetUserID, c.ClientID)
+
+	if s.dep.EventRepo == nil {
+		return
+	}
+	raw, _ := json.Marshal(map[string]string{
+		"actor":    staffID,
+		"scope":    scope,
+		"audience": audience,
+	})
+	detail := string(raw)
+	var tenant *string
+	if staff != nil {
+		tenant = strptr(staff.TenantID)
+	}
+	if err := s.dep.EventRepo.Save(ctx, &entities.SecurityEvent{
+		EventType: eventType,
+		CompanyID: tenant,
+		ClientID:  strptr(c.ID),
+		UserID:    strptr(targetUserID),
+		Detail:    &detail,
+	}); err != nil {
+		log.Printf("[AuthService] save security event failed: %v", err)
+	}
+}
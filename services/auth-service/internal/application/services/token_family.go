@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"bkc_microservice/services/auth-service/internal/domain/entities"
+)
+
+var ErrRefreshTokenReused = errors.New("refresh_token_reused")
+
+const eventRefreshTokenReuse = "refresh_token_reuse"
+
+// revokeFamilyOnReuse mencabut seluruh token dalam family dan mencatat security event.
+func (s *AuthService) revokeFamilyOnReuse(ctx context.Context, tok *entities.Token) {
+	familyID := optionalString(tok.FamilyID)
+	if familyID == "" {
+		familyID = tok.RefreshTokenID
+	}
+
+	revoked, err := s.dep.TokenRepo.RevokeFamily(ctx, familyID)
+	if err != nil {
+		log.Printf("[AuthService] revoke family %s failed: %v", familyID, err)
+	}
+	s.denyRevoked(ctx, revoked)
+	s.auditRevokedTokens(ctx, entities.TokenAudit{
+		CompanyID: strptr(tok.CompanyID),
+		ClientID:  strptr(tok.ClientID),
+		UserID:    tok.UserID,
+		GrantType: strptr("refresh_token"),
+	}, AuditReasonReuseDetected, revoked)
+
+	log.Printf("[AuthService] refresh token reuse detected: family=%s client=%s user=%s",
+		familyID, tok.ClientID, optionalString(tok.UserID))
+
+	if s.dep.EventRepo == nil {
+		return
+	}
+	detail := "refresh token " + tok.RefreshTokenID + " presented after rotation"
+	if err := s.dep.EventRepo.Save(ctx, &entities.SecurityEvent{
+		EventType: eventRefreshTokenReuse,
+		CompanyID: strptr(tok.CompanyID),
+		ClientID:  strptr(tok.ClientID),
+		UserID:    tok.UserID,
+		FamilyID:  &familyID,
+		Detail:    &detail,
+	}); err != nil {
+		log.Printf("[AuthService] save security event failed: %v", err)
+	}
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"bkc_microservice/services/auth-service/internal/domain/entities"
+	sharedsec "bkc_microservice/shared/security"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// tokenRequest — parameter penerbitan token untuk semua grant.
+type tokenRequest struct {
+	Client      *entities.OAuthClient
+	GrantType   string // dicatat di audit trail
+	UserID      string // kosong => token milik client (client_credentials)
+	Scope       string
+	TenantID    string
+	WithRefresh bool
+
+	// scope grant refresh token bila lebih luas dari Scope (refresh dengan scope dipersempit)
+	RefreshScope string
+
+	// resource indicators (RFC 8707): aud access token; RefreshResources = grant refresh
+	// token bila lebih luas dari Resources
+	Resources        []string
+	RefreshResources []string
+
+	// token exchange: audience downstream, rantai actor, TTL lebih pendek, tanpa id_token
+	Audience  []string
+	Actor     *sharedsec.Actor
+	TTL       time.Duration // 0 => TTL access token client
+	NoIDToken bool
+
+	// OIDC
+	Nonce    string
+	AuthTime *time.Time
+
+	// rotasi refresh token (nil => family baru)
+	FamilyID *string
+	ParentID *string
+}
+
+// issueTokens menandatangani access token (+ refresh token opsional), menyimpannya,
+// lalu menambahkan id_token bila ada user dan scope memuat "openid".
+func (s *AuthService) issueTokens(ctx context.Context, req tokenRequest) (*TokenResponse, error) {
+	c := req.Client
+	accessTTL, refreshTTL := s.accessTTL(c), s.refreshTTL(c)
+	if req.TTL > 0 && req.TTL < accessTTL {
+		accessTTL = req.TTL
+	}
+	audience := req.Audience
+	if len(audience) == 0 {
+		audience = req.Resources
+	}
+	if len(audience) == 0 {
+		audience = []string{c.ClientID}
+	}
+	cnf, err := tokenConfirmation(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	jti := sharedsec.NewJTI()
+	at, err := s.dep.KeyStore.SignWithActive(sharedsec.TokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{ID: jti},
+
+		Scope:    req.Scope,
+		ClientID: c.ClientID,
+		UserID:   req.UserID,
+		Type:     "access",
+		Audience: audience,
+		TenantID: req.TenantID,
+		Actor:    req.Actor,
+
+		Confirmation: cnf,
+	}, accessTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshScope := req.Scope
+	if req.RefreshScope != "" {
+		refreshScope = req.RefreshScope
+	}
+	refreshResources := req.Resources
+	if len(req.RefreshResources) > 0 {
+		refreshResources = req.RefreshResources
+	}
+
+	var rt string
+	if req.WithRefresh {
+		rt, err = s.dep.KeyStore.SignWithActive(sharedsec.TokenClaims{
+			Scope:    refreshScope,
+			ClientID: c.ClientID,
+			UserID:   req.UserID,
+			Type:     "refresh",
+			Audience: []string{c.ClientID},
+			TenantID: req.TenantID,
+		}, refreshTTL)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	tok := &entities.Token{
+		UserID:      strptr(req.UserID),
+		ClientID:    c.ID,
+		AccessToken: at,
+		AccessJTI:   jti,
+		Scopes:      &req.Scope,
+		ExpiresAt:   now.Add(accessTTL),
+		CompanyID:   req.TenantID,
+		AuthTime:    req.AuthTime,
+		FamilyID:    req.FamilyID,
+		ParentID:    req.ParentID,
+	}
+	if req.WithRefresh {
+		tok.RefreshToken = &rt
+		tok.RefreshExpiresAt = now.Add(refreshTTL)
+		tok.Resources = joinResources(refreshResources)
+		if cnf != nil {
+			tok.DPoPJKT, tok.CertX5T = strptr(cnf.JKT), strptr(cnf.X5T)
+		}
+		if refreshScope != req.Scope {
+			tok.RefreshScopes = &refreshScope
+		}
+	}
+	if err := s.dep.TokenRepo.Save(ctx, tok); err != nil {
+		return nil, err
+	}
+	s.auditIssued(ctx, req, tok)
+
+	tokenType := "Bearer"
+	if cnf != nil && cnf.JKT != "" {
+		tokenType = "DPoP"
+	}
+	res := &TokenResponse{
+		AccessToken:  at,
+		TokenType:    tokenType,
+		ExpiresIn:    int64(accessTTL.Seconds()),
+		RefreshToken: rt,
+		Scope:        req.Scope,
+	}
+
+	if req.UserID != "" && !req.NoIDToken && hasScope(req.Scope, "openid") {
+		idt, err := s.signIDToken(req, at)
+		if err != nil {
+			return nil, err
+		}
+		res.IDToken = idt
+	}
+
+	return res, nil
+}
+
+// tokenConfirmation menentukan cnf token untuk client c: jkt dari proof DPoP request,
+// x5t#S256 dari sertifikat mTLS bila client meminta certificate-bound token.
+func tokenConfirmation(ctx context.Context, c *entities.OAuthClient) (*sharedsec.Confirmation, error) {
+	cnf := sharedsec.Confirmation{JKT: dpopKeyFromContext(ctx)}
+	if cnf.JKT == "" && c.DPoPBound {
+		return nil, ErrDPoPRequired
+	}
+	if c.CertBound {
+		if cnf.X5T = certificateThumbprintFromContext(ctx); cnf.X5T == "" {
+			return nil, ErrClientCertificateRequired
+		}
+	}
+	if cnf == (sharedsec.Confirmation{}) {
+		return nil, nil
+	}
+	return &cnf, nil
+}
+
+// checkRefreshBinding — refresh token yang di-bind (DPoP / mTLS) hanya bisa dipakai
+// dengan proof dari key / sertifikat yang sama.
+func checkRefreshBinding(ctx context.Context, tok *entities.Token) error {
+	if jkt := optionalString(tok.DPoPJKT); jkt != "" && dpopKeyFromContext(ctx) != jkt {
+		return newOAuthError("invalid_dpop_proof", "refresh token is bound to a different DPoP key")
+	}
+	if x5t := optionalString(tok.CertX5T); x5t != "" && certificateThumbprintFromContext(ctx) != x5t {
+		return newOAuthError("invalid_grant", "refresh token is bound to a different client certificate")
+	}
+	return nil
+}
+
+func (s *AuthService) signIDToken(req tokenRequest, accessToken string) (string, error) {
+	claims := sharedsec.IDTokenClaims{
+		Nonce:    req.Nonce,
+		AZP:      req.Client.ClientID,
+		TenantID: req.TenantID,
+		UserID:   req.UserID,
+		Audience: []string{req.Client.ClientID},
+
+		AccessToken: accessToken, // at_hash mengikuti alg key aktif
+
+	}
+	if req.AuthTime != nil {
+		claims.AuthTime = req.AuthTime.Unix()
+	}
+	return s.dep.KeyStore.SignIDToken(claims, s.accessTTL(req.Client))
+}
+
+// hasScope — cek scope (dipisah spasi) memuat nilai tertentu
+func hasScope(scope, want string) bool {
+	for _, sc := range strings.Fields(scope) {
+		if sc == want {
+			return true
+		}
+	}
+	return false
+}
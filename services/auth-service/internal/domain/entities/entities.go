@@ -9,21 +9,56 @@ type User struct {
 	PasswordHash        string
 	salt                string
 	roleID              int
-	isActive            bool
-	isLocked            bool
-	failedLoginAttempts int
-	lastLogin           *time.Time
+	IsActive            bool
+	IsLocked            bool
+	LockedUntil         *time.Time // nil + IsLocked => dikunci admin (tanpa auto-unlock)
+	FailedLoginAttempts int
+	LastLogin           *time.Time
+	TwoFactorEnabled    bool
+	TwoFactorSecret     *string
 	CreatedAt           time.Time
 }
 
 type OAuthClient struct {
-	ID          string
-	ClientID    string
-	Secret      *string
-	RedirectURI *string
-	Scopes      *string
-	CompanyID   *string
-	CreatedAt   time.Time
+	ID           string
+	ClientID     string
+	Name         *string
+	Secret       *string // plaintext lama; dikosongkan setelah di-hash ke oauth_client_secrets
+	RedirectURIs []string
+	Scopes       *string
+
+	PostLogoutRedirectURIs []string // tujuan redirect end_session (OIDC RP-Initiated Logout)
+	BackchannelLogoutURI   *string  // penerima logout_token (OIDC Back-Channel Logout)
+
+	GrantTypes []string // kosong => semua grant diizinkan (client lama)
+	AuthMethod string   // client_secret_basic | client_secret_post | client_secret_jwt | private_key_jwt | none
+	JWKS       *string  // JWKS inline (JSON) untuk private_key_jwt
+	JWKSURI    *string
+	AuthAlg    *string // token_endpoint_auth_signing_alg; nil => semua alg yang didukung
+	AccessTTL  *time.Duration
+	RefreshTTL *time.Duration
+	CompanyID  *string
+	CreatedAt  time.Time
+	UpdatedAt  *time.Time
+
+	RequirePAR                 bool    // authorize hanya lewat pushed authorization request (RFC 9126)
+	RequireSignedRequestObject bool    // authorize wajib request object bertanda tangan (RFC 9101)
+	RequestObjectAlg           *string // request_object_signing_alg; nil => semua alg asimetris
+	DPoPBound                  bool    // token wajib di-bind DPoP (dpop_bound_access_tokens, RFC 9449)
+	TLSAuthAttr                *string // tls_client_auth: subject_dn | san_dns | san_uri | san_ip | san_email
+	TLSAuthValue               *string // nilai yang harus cocok dengan sertifikat client
+	CertBound                  bool    // tls_client_certificate_bound_access_tokens (RFC 8705 §3)
+}
+
+// ClientSecret — secret client dalam bentuk hash bcrypt.
+type ClientSecret struct {
+	ID         string
+	ClientID   string // oauth_clients.id
+	SecretHash string
+	Sealed     *string // plaintext terenkripsi, hanya untuk client_secret_jwt
+	CreatedAt  time.Time
+	ExpiresAt  *time.Time
+	RevokedAt  *time.Time
 }
 
 type AuthCode struct {
@@ -36,6 +71,9 @@ type AuthCode struct {
 	CodeChallengeMethod *string
 	RedirectURI         *string
 	Scopes              *string
+	Nonce               *string
+	AuthTime            *time.Time
+	Resources           *string // resource RFC 8707 (dipisah spasi)
 	ExpiresAt           time.Time
 }
 
@@ -44,10 +82,94 @@ type Token struct {
 	UserID           *string
 	ClientID         string
 	AccessToken      string
+	AccessJTI        string // klaim jti access token (denylist saat dicabut)
 	RefreshToken     *string
-	Scopes           *string
+	Scopes           *string // dari lookup refresh token: scope grant refresh token
+	RefreshScopes    *string // scope refresh token bila berbeda dari access token
+	Resources        *string // resource (RFC 8707) grant refresh token, dipisah spasi
 	CompanyID        string
-	ExpiresAt        time.Time // access token expiry
-	RefreshExpiresAt time.Time // refresh token expiry (baru)
+	ExpiresAt        time.Time  // access token expiry
+	RefreshExpiresAt time.Time  // refresh token expiry (baru)
+	AuthTime         *time.Time // waktu user login (OIDC auth_time)
 	CreatedAt        time.Time
+
+	// refresh token family (rotasi)
+	RefreshTokenID string
+	FamilyID       *string // id refresh token root; nil => root baru
+	ParentID       *string // refresh token yang dirotasi menjadi token ini
+	Revoked        bool    // refresh token sudah dirotasi / dicabut
+	DPoPJKT        *string // thumbprint key DPoP pemegang refresh token; nil => tidak di-bind
+	CertX5T        *string // thumbprint sertifikat mTLS pemegang refresh token; nil => tidak di-bind
+}
+
+// RevokedAccessToken — access token yang dicabut oleh logout; JTI masuk denylist
+// sampai ExpiresAt.
+type RevokedAccessToken struct {
+	JTI            string
+	ClientPublicID string // oauth_clients.client_id
+	ExpiresAt      time.Time
+}
+
+// Consent — scope yang sudah disetujui user untuk client pada satu tenant.
+type Consent struct {
+	ID        string
+	UserID    string
+	ClientID  string // oauth_clients.id
+	CompanyID string // "" => tanpa tenant
+	Scopes    string // dipisah spasi
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	// diisi saat list (join oauth_clients)
+	ClientPublicID string
+	ClientName     *string
+}
+
+// Resource — protected resource (API) yang boleh diminta lewat parameter resource.
+type Resource struct {
+	Identifier string // URI absolut; dipakai sebagai klaim aud
+	Name       string
+	Scopes     string // scope yang diterima resource, dipisah spasi
+	Active     bool
+	CreatedAt  time.Time
+}
+
+// TokenAudit — satu baris audit trail token (oauth_token_audits).
+type TokenAudit struct {
+	ID             string
+	Event          string // issued | refreshed | revoked | introspection_failed
+	CompanyID      *string
+	ClientID       *string // oauth_clients.id
+	ClientPublicID *string // oauth_clients.client_id (hanya hasil query)
+	UserID         *string
+	TokenType      string // access | refresh
+	GrantType      *string
+	IP             *string
+	UserAgent      *string
+	CorrelationID  *string
+	Detail         *string
+	CreatedAt      time.Time
+}
+
+// TokenAuditFilter — kriteria query audit; field kosong / zero tidak dipakai.
+type TokenAuditFilter struct {
+	UserID    string
+	ClientID  string // oauth_clients.id
+	CompanyID string
+	Event     string
+	From      time.Time
+	To        time.Time
+	Limit     int
+	Offset    int
+}
+
+type SecurityEvent struct {
+	ID        string
+	EventType string
+	CompanyID *string
+	ClientID  *string
+	UserID    *string
+	FamilyID  *string
+	Detail    *string
+	CreatedAt time.Time
 }
@@ -11,16 +11,36 @@ type UserRepository interface {
 	FindByEmail(ctx context.Context, email string) (*entities.User, error)
 	FindByID(ctx context.Context, id string) (*entities.User, error)
 	CheckPassword(ctx context.Context, userID string, password string) (bool, error)
+
+	// lockout password grant
+	RecordLoginFailure(ctx context.Context, userID string, threshold int, lockFor time.Duration) (attempts int, lockedUntil *time.Time, err error)
+	RecordLoginSuccess(ctx context.Context, userID string) error
+	Unlock(ctx context.Context, userID string) error
 }
 
 type ClientRepository interface {
 	FindByClientID(ctx context.Context, clientID string) (*entities.OAuthClient, error)
+	Create(ctx context.Context, c *entities.OAuthClient) error
+	Update(ctx context.Context, c *entities.OAuthClient) error
+	UpdateSecret(ctx context.Context, clientID string, secret *string) error
+	SoftDelete(ctx context.Context, clientID string) error
+}
+
+type ClientSecretRepository interface {
+	// ListActive mengembalikan secret yang belum dicabut / kedaluwarsa, terbaru lebih dulu
+	ListActive(ctx context.Context, clientID string, now time.Time) ([]*entities.ClientSecret, error)
+	Create(ctx context.Context, sec *entities.ClientSecret) error
+	SetExpiry(ctx context.Context, id string, expiresAt time.Time) error
+	// RevokeAllExcept mencabut semua secret aktif client selain id yang disebut
+	RevokeAllExcept(ctx context.Context, clientID string, keepIDs ...string) error
 }
 
 type AuthCodeRepository interface {
 	Save(ctx context.Context, ac *entities.AuthCode) error
 	FindValid(ctx context.Context, code string, now time.Time) (*entities.AuthCode, error)
 	DeleteByCode(ctx context.Context, code string) error
+	// DeleteExpired menghapus maksimal limit auth code yang kedaluwarsa sebelum before
+	DeleteExpired(ctx context.Context, before time.Time, limit int) (int64, error)
 }
 
 type TokenRepository interface {
@@ -29,5 +49,59 @@ type TokenRepository interface {
 	FindByAccessToken(ctx context.Context, access string) (*entities.Token, error)
 	RevokeByAccessToken(ctx context.Context, access string) error
 	RevokeByRefreshToken(ctx context.Context, refresh string) error
-	CleanupExpired(ctx context.Context, now time.Time) error
+
+	// janitor: hapus maksimal limit baris per panggilan, kembalikan jumlah yang terhapus
+	DeleteExpiredRefresh(ctx context.Context, before, revokedBefore time.Time, limit int) (int64, error)
+	DeleteExpiredAccess(ctx context.Context, before time.Time, limit int) (int64, error)
+
+	// rotasi refresh token
+	FindByRefreshTokenIncludingRevoked(ctx context.Context, refresh string) (*entities.Token, error)
+	MarkRefreshRotated(ctx context.Context, refreshTokenID string) (bool, error)
+	// RevokeFamily & RevokeByUserClient mengembalikan access token yang belum kedaluwarsa
+	// saat dicabut (jti untuk denylist)
+	RevokeFamily(ctx context.Context, familyID string) ([]*entities.RevokedAccessToken, error)
+
+	// RevokeByUserClient mencabut semua token user untuk client pada tenant (consent dicabut)
+	RevokeByUserClient(ctx context.Context, userID, clientID, companyID string) ([]*entities.RevokedAccessToken, error)
+
+	// RevokeByUser mencabut semua access/refresh token user (clientID = oauth_clients.id,
+	// kosong => semua client) dan mengembalikan token yang masih hidup saat dicabut
+	RevokeByUser(ctx context.Context, userID, clientID string) ([]*entities.RevokedAccessToken, error)
+	// ListClientsByUser — client_id publik yang masih memegang token hidup milik user
+	ListClientsByUser(ctx context.Context, userID string) ([]string, error)
+}
+
+type RecoveryCodeRepository interface {
+	Consume(ctx context.Context, userID, codeHash string) (bool, error)
+}
+
+type ScopeRepository interface {
+	// ListRestricted — scope yang hanya boleh diberikan ke user dengan grant eksplisit
+	ListRestricted(ctx context.Context) ([]string, error)
+	ListUserGrants(ctx context.Context, userID string) ([]string, error)
+}
+
+type ResourceRepository interface {
+	// FindByIdentifier mengembalikan nil, nil jika resource tidak terdaftar
+	FindByIdentifier(ctx context.Context, identifier string) (*entities.Resource, error)
+}
+
+type ConsentRepository interface {
+	// Find mengembalikan nil, nil jika user belum pernah memberi consent
+	Find(ctx context.Context, userID, clientID, companyID string) (*entities.Consent, error)
+	FindByID(ctx context.Context, id string) (*entities.Consent, error)
+	ListByUser(ctx context.Context, userID string) ([]*entities.Consent, error)
+	// Upsert menyimpan scope consent (menimpa scope lama untuk user+client+tenant)
+	Upsert(ctx context.Context, c *entities.Consent) error
+	Delete(ctx context.Context, id string) error
+}
+
+type SecurityEventRepository interface {
+	Save(ctx context.Context, ev *entities.SecurityEvent) error
+}
+
+type TokenAuditRepository interface {
+	Save(ctx context.Context, a *entities.TokenAudit) error
+	// Query mengembalikan audit terbaru lebih dulu
+	Query(ctx context.Context, f entities.TokenAuditFilter) ([]*entities.TokenAudit, error)
 }
@@ -0,0 +1,169 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrAccountLocked      = errors.New("account locked")
+)
+
+// UserServiceClient memanggil endpoint internal user-service.
+type UserServiceClient struct {
+	baseURL     string
+	internalKey string
+	httpClient  *http.Client
+}
+
+// UserProfile adalah data user + profile yang dibutuhkan untuk klaim OIDC.
+type UserProfile struct {
+	ID          string
+	Username    string
+	Email       string
+	FullName    *string
+	DisplayName *string
+	Phone       *string
+	AvatarURL   *string
+	Locale      *string
+	Timezone    *string
+	UpdatedAt   *time.Time
+}
+
+// bentuk respons user-service: response.OK membungkus MeResponse di field "data"
+type meEnvelope struct {
+	Success bool `json:"success"`
+	Data    struct {
+		Data struct {
+			ID        string  `json:"id"`
+			Username  string  `json:"username"`
+			Email     string  `json:"email"`
+			UpdatedAt *string `json:"updatedAt,omitempty"`
+		} `json:"data"`
+		Profile *struct {
+			FullName    *string `json:"fullName,omitempty"`
+			DisplayName *string `json:"displayName,omitempty"`
+			Phone       *string `json:"phone,omitempty"`
+			AvatarURL   *string `json:"avatarUrl,omitempty"`
+			Locale      *string `json:"locale,omitempty"`
+			Timezone    *string `json:"timezone,omitempty"`
+		} `json:"profile,omitempty"`
+	} `json:"data"`
+}
+
+// AuthenticatedUser adalah hasil verifikasi login di user-service.
+type AuthenticatedUser struct {
+	ID               string `json:"id"`
+	Username         string `json:"username"`
+	Email            string `json:"email"`
+	RoleID           int    `json:"roleId"`
+	TwoFactorEnabled bool   `json:"twoFactorEnabled"`
+}
+
+func NewUserServiceClient(baseURL, internalKey string) *UserServiceClient {
+	return &UserServiceClient{
+		baseURL:     baseURL,
+		internalKey: internalKey,
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+	}
+}
+
+// GetUser mengambil user + profile dari user-service. Mengembalikan nil, nil jika user tidak ada.
+func (c *UserServiceClient) GetUser(ctx context.Context, userID string) (*UserProfile, error) {
+	url := fmt.Sprintf("%s/internal/users/%s", c.baseURL, userID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Internal-Api-Key", c.internalKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var env meEnvelope
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	u := &UserProfile{
+		ID:       env.Data.Data.ID,
+		Username: env.Data.Data.Username,
+		Email:    env.Data.Data.Email,
+	}
+	if env.Data.Data.UpdatedAt != nil {
+		if t, err := time.Parse(time.RFC3339, *env.Data.Data.UpdatedAt); err == nil {
+			u.UpdatedAt = &t
+		}
+	}
+	if p := env.Data.Profile; p != nil {
+		u.FullName = p.FullName
+		u.DisplayName = p.DisplayName
+		u.Phone = p.Phone
+		u.AvatarURL = p.AvatarURL
+		u.Locale = p.Locale
+		u.Timezone = p.Timezone
+	}
+	return u, nil
+}
+
+// Authenticate memverifikasi email/username + password lewat user-service.
+func (c *UserServiceClient) Authenticate(ctx context.Context, login, password string) (*AuthenticatedUser, error) {
+	body, err := json.Marshal(map[string]string{"login": login, "password": password})
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/internal/users/authenticate", c.baseURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Internal-Api-Key", c.internalKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized:
+		return nil, ErrInvalidCredentials
+	case http.StatusLocked:
+		return nil, ErrAccountLocked
+	default:
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var env struct {
+		Data AuthenticatedUser `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if env.Data.ID == "" {
+		return nil, ErrInvalidCredentials
+	}
+	return &env.Data, nil
+}
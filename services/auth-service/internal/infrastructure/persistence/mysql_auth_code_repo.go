@@ -18,21 +18,21 @@ func NewMySQLAuthCodeRepo(db *sql.DB) repositories.AuthCodeRepository {
 func (r *MySQLAuthCodeRepo) Save(ctx context.Context, ac *entities.AuthCode) error {
 	_, err := r.db.ExecContext(ctx, `
 		INSERT INTO oauth_auth_codes 
-			(code, user_id, client_id, code_challenge, code_challenge_method, redirect_uri, scopes, expires_at, company_id)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, ac.Code, ac.UserID, ac.ClientID, ac.CodeChallenge, ac.CodeChallengeMethod, ac.RedirectURI, ac.Scopes, ac.ExpiresAt, ac.CompanyID)
+			(code, user_id, client_id, code_challenge, code_challenge_method, redirect_uri, scopes, nonce, auth_time, resources, expires_at, company_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, ac.Code, ac.UserID, ac.ClientID, ac.CodeChallenge, ac.CodeChallengeMethod, ac.RedirectURI, ac.Scopes, ac.Nonce, ac.AuthTime, ac.Resources, ac.ExpiresAt, ac.CompanyID)
 	return err
 }
 
 func (r *MySQLAuthCodeRepo) FindValid(ctx context.Context, code string, now time.Time) (*entities.AuthCode, error) {
 	row := r.db.QueryRowContext(ctx, `
-		SELECT id, code, user_id, client_id, code_challenge, code_challenge_method, redirect_uri, scopes, expires_at, company_id
+		SELECT id, code, user_id, client_id, code_challenge, code_challenge_method, redirect_uri, scopes, nonce, auth_time, resources, expires_at, company_id
 		FROM oauth_auth_codes WHERE code = ? AND expires_at > ?
 	`, code, now)
 
 	var ac entities.AuthCode
 	if err := row.Scan(&ac.ID, &ac.Code, &ac.UserID, &ac.ClientID, &ac.CodeChallenge,
-		&ac.CodeChallengeMethod, &ac.RedirectURI, &ac.Scopes, &ac.ExpiresAt, &ac.CompanyID); err != nil {
+		&ac.CodeChallengeMethod, &ac.RedirectURI, &ac.Scopes, &ac.Nonce, &ac.AuthTime, &ac.Resources, &ac.ExpiresAt, &ac.CompanyID); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
@@ -45,3 +45,11 @@ func (r *MySQLAuthCodeRepo) DeleteByCode(ctx context.Context, code string) error
 	_, err := r.db.ExecContext(ctx, `DELETE FROM oauth_auth_codes WHERE code = ?`, code)
 	return err
 }
+
+func (r *MySQLAuthCodeRepo) DeleteExpired(ctx context.Context, before time.Time, limit int) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM oauth_auth_codes WHERE expires_at < ? LIMIT ?`, before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
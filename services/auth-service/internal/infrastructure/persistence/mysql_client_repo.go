@@ -3,10 +3,15 @@ package persistence
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"strings"
+	"time"
 
 	"bkc_microservice/services/auth-service/internal/domain/entities"
 	"bkc_microservice/services/auth-service/internal/domain/repositories"
+
+	"github.com/google/uuid"
 )
 
 type MySQLClientRepo struct{ db *sql.DB }
@@ -17,32 +22,206 @@ func NewMySQLClientRepo(db *sql.DB) repositories.ClientRepository {
 
 func (r *MySQLClientRepo) FindByClientID(ctx context.Context, clientID string) (*entities.OAuthClient, error) {
 	row := r.db.QueryRowContext(ctx, `
-		SELECT id, client_id, client_secret, redirect_uri, scopes, company_id, created_at
-		FROM oauth_clients WHERE client_id = ?
+		SELECT id, client_id, client_name, client_secret, redirect_uri, redirect_uris,
+		       post_logout_redirect_uris, backchannel_logout_uri, scopes,
+		       grant_types, token_endpoint_auth_method, jwks, jwks_uri, token_endpoint_auth_signing_alg,
+		       require_pushed_authorization_requests, require_signed_request_object, request_object_signing_alg,
+		       dpop_bound_access_tokens, tls_client_auth_attr, tls_client_auth_value,
+		       tls_client_certificate_bound_access_tokens,
+		       access_token_ttl, refresh_token_ttl, company_id, created_at, updated_at
+		FROM oauth_clients WHERE client_id = ? AND deleted_at IS NULL
 	`, clientID)
 
 	var c entities.OAuthClient
-	var secret, redirect, scopes, companyID sql.NullString
+	var name, secret, redirect, redirects, postLogout, backchannel, scopes, grants, jwks, jwksURI, authAlg, requestAlg, tlsAttr, tlsValue, companyID sql.NullString
+	var accessTTL, refreshTTL sql.NullInt64
+	var updatedAt sql.NullTime
 
-	if err := row.Scan(&c.ID, &c.ClientID, &secret, &redirect, &scopes, &companyID, &c.CreatedAt); err != nil {
+	if err := row.Scan(&c.ID, &c.ClientID, &name, &secret, &redirect, &redirects, &postLogout, &backchannel, &scopes,
+		&grants, &c.AuthMethod, &jwks, &jwksURI, &authAlg,
+		&c.RequirePAR, &c.RequireSignedRequestObject, &requestAlg, &c.DPoPBound,
+		&tlsAttr, &tlsValue, &c.CertBound,
+		&accessTTL, &refreshTTL, &companyID, &c.CreatedAt, &updatedAt); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
 		return nil, err
 	}
 
+	if name.Valid {
+		c.Name = &name.String
+	}
 	if secret.Valid {
 		c.Secret = &secret.String
 	}
-	if redirect.Valid {
-		c.RedirectURI = &redirect.String
+	if redirects.Valid && redirects.String != "" {
+		if err := json.Unmarshal([]byte(redirects.String), &c.RedirectURIs); err != nil {
+			return nil, err
+		}
+	} else if redirect.Valid && redirect.String != "" {
+		c.RedirectURIs = []string{redirect.String}
+	}
+	if postLogout.Valid && postLogout.String != "" {
+		if err := json.Unmarshal([]byte(postLogout.String), &c.PostLogoutRedirectURIs); err != nil {
+			return nil, err
+		}
+	}
+	if backchannel.Valid && backchannel.String != "" {
+		c.BackchannelLogoutURI = &backchannel.String
 	}
 	if scopes.Valid {
 		c.Scopes = &scopes.String
 	}
+	if grants.Valid {
+		c.GrantTypes = strings.Fields(grants.String)
+	}
+	if jwks.Valid && jwks.String != "" {
+		c.JWKS = &jwks.String
+	}
+	if jwksURI.Valid && jwksURI.String != "" {
+		c.JWKSURI = &jwksURI.String
+	}
+	if authAlg.Valid && authAlg.String != "" {
+		c.AuthAlg = &authAlg.String
+	}
+	if requestAlg.Valid && requestAlg.String != "" {
+		c.RequestObjectAlg = &requestAlg.String
+	}
+	if tlsAttr.Valid && tlsAttr.String != "" {
+		c.TLSAuthAttr = &tlsAttr.String
+	}
+	if tlsValue.Valid && tlsValue.String != "" {
+		c.TLSAuthValue = &tlsValue.String
+	}
+	if accessTTL.Valid {
+		d := time.Duration(accessTTL.Int64) * time.Second
+		c.AccessTTL = &d
+	}
+	if refreshTTL.Valid {
+		d := time.Duration(refreshTTL.Int64) * time.Second
+		c.RefreshTTL = &d
+	}
 	if companyID.Valid {
 		c.CompanyID = &companyID.String
 	}
+	if updatedAt.Valid {
+		c.UpdatedAt = &updatedAt.Time
+	}
 
 	return &c, nil
 }
+
+func (r *MySQLClientRepo) Create(ctx context.Context, c *entities.OAuthClient) error {
+	redirects, err := json.Marshal(c.RedirectURIs)
+	if err != nil {
+		return err
+	}
+	if c.ID == "" {
+		c.ID = uuid.NewString()
+	}
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO oauth_clients
+		  (id, client_id, client_name, client_secret, redirect_uri, redirect_uris,
+		   post_logout_redirect_uris, backchannel_logout_uri, scopes, grant_types, token_endpoint_auth_method, jwks, jwks_uri, token_endpoint_auth_signing_alg,
+		   require_pushed_authorization_requests, require_signed_request_object, request_object_signing_alg,
+		   dpop_bound_access_tokens, tls_client_auth_attr, tls_client_auth_value,
+		   tls_client_certificate_bound_access_tokens, access_token_ttl, refresh_token_ttl, company_id, created_at)
+		VALUES
+		  (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW())
+	`, c.ID, c.ClientID, c.Name, c.Secret, firstRedirect(c.RedirectURIs), string(redirects),
+		jsonOrNil(c.PostLogoutRedirectURIs), c.BackchannelLogoutURI, c.Scopes,
+		joinOrNil(c.GrantTypes), c.AuthMethod, c.JWKS, c.JWKSURI, c.AuthAlg,
+		c.RequirePAR, c.RequireSignedRequestObject, c.RequestObjectAlg, c.DPoPBound,
+		c.TLSAuthAttr, c.TLSAuthValue, c.CertBound,
+		ttlSeconds(c.AccessTTL), ttlSeconds(c.RefreshTTL), c.CompanyID)
+	return err
+}
+
+func (r *MySQLClientRepo) Update(ctx context.Context, c *entities.OAuthClient) error {
+	redirects, err := json.Marshal(c.RedirectURIs)
+	if err != nil {
+		return err
+	}
+	// service sudah memastikan client ada; RowsAffected bisa 0 jika tidak ada perubahan
+	_, err = r.db.ExecContext(ctx, `
+		UPDATE oauth_clients
+		SET client_name = ?, redirect_uri = ?, redirect_uris = ?,
+		    post_logout_redirect_uris = ?, backchannel_logout_uri = ?, scopes = ?, grant_types = ?,
+		    token_endpoint_auth_method = ?, jwks = ?, jwks_uri = ?, token_endpoint_auth_signing_alg = ?,
+		    require_pushed_authorization_requests = ?, require_signed_request_object = ?, request_object_signing_alg = ?,
+		    dpop_bound_access_tokens = ?, tls_client_auth_attr = ?, tls_client_auth_value = ?,
+		    tls_client_certificate_bound_access_tokens = ?, access_token_ttl = ?, refresh_token_ttl = ?, company_id = ?, updated_at = NOW()
+		WHERE client_id = ? AND deleted_at IS NULL
+	`, c.Name, firstRedirect(c.RedirectURIs), string(redirects),
+		jsonOrNil(c.PostLogoutRedirectURIs), c.BackchannelLogoutURI, c.Scopes, joinOrNil(c.GrantTypes),
+		c.AuthMethod, c.JWKS, c.JWKSURI, c.AuthAlg,
+		c.RequirePAR, c.RequireSignedRequestObject, c.RequestObjectAlg, c.DPoPBound,
+		c.TLSAuthAttr, c.TLSAuthValue, c.CertBound,
+		ttlSeconds(c.AccessTTL), ttlSeconds(c.RefreshTTL), c.CompanyID, c.ClientID)
+	return err
+}
+
+func (r *MySQLClientRepo) UpdateSecret(ctx context.Context, clientID string, secret *string) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE oauth_clients SET client_secret = ?, updated_at = NOW()
+		WHERE client_id = ? AND deleted_at IS NULL
+	`, secret, clientID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+func (r *MySQLClientRepo) SoftDelete(ctx context.Context, clientID string) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE oauth_clients SET deleted_at = NOW()
+		WHERE client_id = ? AND deleted_at IS NULL
+	`, clientID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+func firstRedirect(uris []string) *string {
+	if len(uris) == 0 {
+		return nil
+	}
+	return &uris[0]
+}
+
+func jsonOrNil(vals []string) *string {
+	if len(vals) == 0 {
+		return nil
+	}
+	b, _ := json.Marshal(vals)
+	s := string(b)
+	return &s
+}
+
+func joinOrNil(vals []string) *string {
+	if len(vals) == 0 {
+		return nil
+	}
+	s := strings.Join(vals, " ")
+	return &s
+}
+
+func ttlSeconds(d *time.Duration) *int64 {
+	if d == nil {
+		return nil
+	}
+	v := int64(d.Seconds())
+	return &v
+}
+
+func requireAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
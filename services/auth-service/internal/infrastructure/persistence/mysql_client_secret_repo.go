@@ -0,0 +1,84 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"bkc_microservice/services/auth-service/internal/domain/entities"
+	"bkc_microservice/services/auth-service/internal/domain/repositories"
+
+	"github.com/google/uuid"
+)
+
+type MySQLClientSecretRepo struct{ db *sql.DB }
+
+func NewMySQLClientSecretRepo(db *sql.DB) repositories.ClientSecretRepository {
+	return &MySQLClientSecretRepo{db: db}
+}
+
+func (r *MySQLClientSecretRepo) ListActive(ctx context.Context, clientID string, now time.Time) ([]*entities.ClientSecret, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, client_id, secret_hash, secret_sealed, created_at, expires_at
+		FROM oauth_client_secrets
+		WHERE client_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)
+		ORDER BY created_at DESC
+	`, clientID, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*entities.ClientSecret
+	for rows.Next() {
+		var sec entities.ClientSecret
+		var sealed sql.NullString
+		var expiresAt sql.NullTime
+		if err := rows.Scan(&sec.ID, &sec.ClientID, &sec.SecretHash, &sealed, &sec.CreatedAt, &expiresAt); err != nil {
+			return nil, err
+		}
+		if sealed.Valid {
+			sec.Sealed = &sealed.String
+		}
+		if expiresAt.Valid {
+			sec.ExpiresAt = &expiresAt.Time
+		}
+		out = append(out, &sec)
+	}
+	return out, rows.Err()
+}
+
+func (r *MySQLClientSecretRepo) Create(ctx context.Context, sec *entities.ClientSecret) error {
+	if sec.ID == "" {
+		sec.ID = uuid.NewString()
+	}
+	if sec.CreatedAt.IsZero() {
+		sec.CreatedAt = time.Now()
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO oauth_client_secrets (id, client_id, secret_hash, secret_sealed, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, sec.ID, sec.ClientID, sec.SecretHash, sec.Sealed, sec.CreatedAt, sec.ExpiresAt)
+	return err
+}
+
+func (r *MySQLClientSecretRepo) SetExpiry(ctx context.Context, id string, expiresAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE oauth_client_secrets SET expires_at = ? WHERE id = ? AND revoked_at IS NULL
+	`, expiresAt, id)
+	return err
+}
+
+func (r *MySQLClientSecretRepo) RevokeAllExcept(ctx context.Context, clientID string, keepIDs ...string) error {
+	q := `UPDATE oauth_client_secrets SET revoked_at = NOW() WHERE client_id = ? AND revoked_at IS NULL`
+	args := []any{clientID}
+	if len(keepIDs) > 0 {
+		q += ` AND id NOT IN (?` + strings.Repeat(", ?", len(keepIDs)-1) + `)`
+		for _, id := range keepIDs {
+			args = append(args, id)
+		}
+	}
+	_, err := r.db.ExecContext(ctx, q, args...)
+	return err
+}
@@ -0,0 +1,97 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"bkc_microservice/services/auth-service/internal/domain/entities"
+	"bkc_microservice/services/auth-service/internal/domain/repositories"
+
+	"github.com/google/uuid"
+)
+
+type MySQLConsentRepo struct{ db *sql.DB }
+
+func NewMySQLConsentRepo(db *sql.DB) repositories.ConsentRepository {
+	return &MySQLConsentRepo{db: db}
+}
+
+const consentColumns = `id, user_id, client_id, company_id, scopes, created_at, updated_at`
+
+func (r *MySQLConsentRepo) Find(ctx context.Context, userID, clientID, companyID string) (*entities.Consent, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+consentColumns+`
+		FROM oauth_consents
+		WHERE user_id = ? AND client_id = ? AND company_id = ?
+	`, userID, clientID, companyID)
+	return scanConsent(row)
+}
+
+func (r *MySQLConsentRepo) FindByID(ctx context.Context, id string) (*entities.Consent, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+consentColumns+`
+		FROM oauth_consents
+		WHERE id = ?
+	`, id)
+	return scanConsent(row)
+}
+
+func (r *MySQLConsentRepo) ListByUser(ctx context.Context, userID string) ([]*entities.Consent, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT c.id, c.user_id, c.client_id, c.company_id, c.scopes, c.created_at, c.updated_at,
+		       oc.client_id, oc.name
+		FROM oauth_consents c
+		JOIN oauth_clients oc ON oc.id = c.client_id
+		WHERE c.user_id = ?
+		ORDER BY c.updated_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*entities.Consent
+	for rows.Next() {
+		var c entities.Consent
+		var name sql.NullString
+		if err := rows.Scan(&c.ID, &c.UserID, &c.ClientID, &c.CompanyID, &c.Scopes, &c.CreatedAt, &c.UpdatedAt,
+			&c.ClientPublicID, &name); err != nil {
+			return nil, err
+		}
+		if name.Valid {
+			c.ClientName = &name.String
+		}
+		out = append(out, &c)
+	}
+	return out, rows.Err()
+}
+
+func (r *MySQLConsentRepo) Upsert(ctx context.Context, c *entities.Consent) error {
+	if c.ID == "" {
+		c.ID = uuid.NewString()
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO oauth_consents (id, user_id, client_id, company_id, scopes)
+		VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE scopes = VALUES(scopes), updated_at = CURRENT_TIMESTAMP
+	`, c.ID, c.UserID, c.ClientID, c.CompanyID, c.Scopes)
+	return err
+}
+
+func (r *MySQLConsentRepo) Delete(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM oauth_consents WHERE id = ?`, id)
+	return err
+}
+
+func scanConsent(row *sql.Row) (*entities.Consent, error) {
+	var c entities.Consent
+	err := row.Scan(&c.ID, &c.UserID, &c.ClientID, &c.CompanyID, &c.Scopes, &c.CreatedAt, &c.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
@@ -0,0 +1,31 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+
+	"bkc_microservice/services/auth-service/internal/domain/repositories"
+)
+
+type MySQLRecoveryCodeRepo struct{ db *sql.DB }
+
+func NewMySQLRecoveryCodeRepo(db *sql.DB) repositories.RecoveryCodeRepository {
+	return &MySQLRecoveryCodeRepo{db: db}
+}
+
+// Consume memakai recovery code 2FA (sekali pakai); false jika tidak ada / sudah dipakai
+func (r *MySQLRecoveryCodeRepo) Consume(ctx context.Context, userID, codeHash string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE user_recovery_codes
+		SET used_at = NOW()
+		WHERE user_id = ? AND code_hash = ? AND used_at IS NULL
+	`, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
@@ -0,0 +1,30 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+
+	"bkc_microservice/services/auth-service/internal/domain/entities"
+	"bkc_microservice/services/auth-service/internal/domain/repositories"
+)
+
+type MySQLResourceRepo struct{ db *sql.DB }
+
+func NewMySQLResourceRepo(db *sql.DB) repositories.ResourceRepository {
+	return &MySQLResourceRepo{db: db}
+}
+
+func (r *MySQLResourceRepo) FindByIdentifier(ctx context.Context, identifier string) (*entities.Resource, error) {
+	var res entities.Resource
+	err := r.db.QueryRowContext(ctx, `
+		SELECT identifier, name, scopes, is_active, created_at
+		FROM oauth_resources WHERE identifier = ?
+	`, identifier).Scan(&res.Identifier, &res.Name, &res.Scopes, &res.Active, &res.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
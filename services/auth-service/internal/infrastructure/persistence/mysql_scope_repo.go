@@ -0,0 +1,40 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+
+	"bkc_microservice/services/auth-service/internal/domain/repositories"
+)
+
+type MySQLScopeRepo struct{ db *sql.DB }
+
+func NewMySQLScopeRepo(db *sql.DB) repositories.ScopeRepository {
+	return &MySQLScopeRepo{db: db}
+}
+
+func (r *MySQLScopeRepo) ListRestricted(ctx context.Context) ([]string, error) {
+	return r.queryStrings(ctx, `SELECT name FROM oauth_scopes WHERE restricted = 1`)
+}
+
+func (r *MySQLScopeRepo) ListUserGrants(ctx context.Context, userID string) ([]string, error) {
+	return r.queryStrings(ctx, `SELECT scope FROM oauth_user_scopes WHERE user_id = ?`, userID)
+}
+
+func (r *MySQLScopeRepo) queryStrings(ctx context.Context, q string, args ...any) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
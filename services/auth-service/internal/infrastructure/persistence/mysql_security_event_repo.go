@@ -0,0 +1,25 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+
+	"bkc_microservice/services/auth-service/internal/domain/entities"
+	"bkc_microservice/services/auth-service/internal/domain/repositories"
+)
+
+type MySQLSecurityEventRepo struct{ db *sql.DB }
+
+func NewMySQLSecurityEventRepo(db *sql.DB) repositories.SecurityEventRepository {
+	return &MySQLSecurityEventRepo{db: db}
+}
+
+func (r *MySQLSecurityEventRepo) Save(ctx context.Context, ev *entities.SecurityEvent) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO oauth_security_events
+		  (id, event_type, company_id, client_id, user_id, family_id, detail, created_at)
+		VALUES
+		  (UUID(), ?, ?, ?, ?, ?, ?, NOW())
+	`, ev.EventType, ev.CompanyID, ev.ClientID, ev.UserID, ev.FamilyID, ev.Detail)
+	return err
+}
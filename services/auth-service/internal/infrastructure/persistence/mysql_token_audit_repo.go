@@ -0,0 +1,77 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"bkc_microservice/services/auth-service/internal/domain/entities"
+	"bkc_microservice/services/auth-service/internal/domain/repositories"
+)
+
+type MySQLTokenAuditRepo struct{ db *sql.DB }
+
+func NewMySQLTokenAuditRepo(db *sql.DB) repositories.TokenAuditRepository {
+	return &MySQLTokenAuditRepo{db: db}
+}
+
+func (r *MySQLTokenAuditRepo) Save(ctx context.Context, a *entities.TokenAudit) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO oauth_token_audits
+		  (id, event, company_id, client_id, user_id, token_type, grant_type, ip, user_agent, correlation_id, detail, created_at)
+		VALUES
+		  (UUID(), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW())
+	`, a.Event, a.CompanyID, a.ClientID, a.UserID, a.TokenType, a.GrantType, a.IP, a.UserAgent, a.CorrelationID, a.Detail)
+	return err
+}
+
+func (r *MySQLTokenAuditRepo) Query(ctx context.Context, f entities.TokenAuditFilter) ([]*entities.TokenAudit, error) {
+	var where []string
+	var args []any
+	if f.UserID != "" {
+		where, args = append(where, "a.user_id = ?"), append(args, f.UserID)
+	}
+	if f.ClientID != "" {
+		where, args = append(where, "a.client_id = ?"), append(args, f.ClientID)
+	}
+	if f.CompanyID != "" {
+		where, args = append(where, "a.company_id = ?"), append(args, f.CompanyID)
+	}
+	if f.Event != "" {
+		where, args = append(where, "a.event = ?"), append(args, f.Event)
+	}
+	if !f.From.IsZero() {
+		where, args = append(where, "a.created_at >= ?"), append(args, f.From)
+	}
+	if !f.To.IsZero() {
+		where, args = append(where, "a.created_at < ?"), append(args, f.To)
+	}
+
+	q := `
+		SELECT a.id, a.event, a.company_id, a.client_id, oc.client_id, a.user_id, a.token_type, a.grant_type,
+		       a.ip, a.user_agent, a.correlation_id, a.detail, a.created_at
+		FROM oauth_token_audits a
+		LEFT JOIN oauth_clients oc ON oc.id = a.client_id`
+	if len(where) > 0 {
+		q += "\n\t\tWHERE " + strings.Join(where, " AND ")
+	}
+	q += "\n\t\tORDER BY a.created_at DESC, a.id\n\t\tLIMIT ? OFFSET ?"
+	args = append(args, f.Limit, f.Offset)
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*entities.TokenAudit
+	for rows.Next() {
+		var a entities.TokenAudit
+		if err := rows.Scan(&a.ID, &a.Event, &a.CompanyID, &a.ClientID, &a.ClientPublicID, &a.UserID, &a.TokenType, &a.GrantType,
+			&a.IP, &a.UserAgent, &a.CorrelationID, &a.Detail, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, &a)
+	}
+	return out, rows.Err()
+}
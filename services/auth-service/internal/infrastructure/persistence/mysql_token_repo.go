@@ -19,10 +19,10 @@ func (r *MySQLTokenRepo) Save(ctx context.Context, t *entities.Token) error {
 	// 1) insert access token
 	_, err := r.db.ExecContext(ctx, `
 		INSERT INTO oauth_access_tokens
-		  (id, user_id, client_id, token, scopes, company_id, expires_at, created_at, revoked)
+		  (id, user_id, client_id, token, jti, scopes, company_id, expires_at, auth_time, created_at, revoked)
 		VALUES
-		  (UUID(), ?, ?, ?, ?, ?, ?, NOW(), 0)
-	`, t.UserID, t.ClientID, t.AccessToken, t.Scopes, t.CompanyID, t.ExpiresAt)
+		  (UUID(), ?, ?, ?, ?, ?, ?, ?, ?, NOW(), 0)
+	`, t.UserID, t.ClientID, t.AccessToken, nullIfEmpty(t.AccessJTI), t.Scopes, t.CompanyID, t.ExpiresAt, t.AuthTime)
 	if err != nil {
 		return err
 	}
@@ -45,10 +45,10 @@ func (r *MySQLTokenRepo) Save(ctx context.Context, t *entities.Token) error {
 		}
 		_, err = r.db.ExecContext(ctx, `
 			INSERT INTO oauth_refresh_tokens
-			  (id, access_token_id, token, company_id, expires_at, created_at, revoked)
+			  (id, access_token_id, token, scopes, resources, dpop_jkt, cert_x5t, company_id, expires_at, family_id, parent_id, created_at, revoked)
 			VALUES
-			  (UUID(), ?, ?, ?, ?, NOW(), 0)
-		`, atID, *t.RefreshToken, t.CompanyID, rexp)
+			  (UUID(), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW(), 0)
+		`, atID, *t.RefreshToken, t.RefreshScopes, t.Resources, t.DPoPJKT, t.CertX5T, t.CompanyID, rexp, t.FamilyID, t.ParentID)
 		if err != nil {
 			return err
 		}
@@ -62,10 +62,11 @@ func (r *MySQLTokenRepo) FindByRefreshToken(ctx context.Context, refresh string)
 		SELECT at.id, at.user_id, at.client_id,
 		       at.token      AS access_token,
 		       rt.token      AS refresh_token,
-		       at.scopes,
+		       COALESCE(rt.scopes, at.scopes) AS scopes,
 		       at.expires_at,
 		       rt.expires_at AS refresh_expires_at,
 		       at.company_id,
+		       at.auth_time,
 		       at.created_at
 		FROM oauth_refresh_tokens rt
 		JOIN oauth_access_tokens  at ON at.id = rt.access_token_id
@@ -78,7 +79,7 @@ func (r *MySQLTokenRepo) FindByRefreshToken(ctx context.Context, refresh string)
 	var refreshExp sql.NullTime
 	if err := row.Scan(&t.ID, &t.UserID, &t.ClientID,
 		&t.AccessToken, &t.RefreshToken, &t.Scopes, &t.ExpiresAt, &refreshExp,
-		&t.CompanyID, &t.CreatedAt,
+		&t.CompanyID, &t.AuthTime, &t.CreatedAt,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -100,6 +101,7 @@ func (r *MySQLTokenRepo) FindByAccessToken(ctx context.Context, access string) (
 		       at.expires_at,
 		       NULL        AS refresh_expires_at,
 		       at.company_id,
+		       at.auth_time,
 		       at.created_at
 		FROM oauth_access_tokens at
 		WHERE at.token_sha = UNHEX(SHA2(?,256))
@@ -110,7 +112,7 @@ func (r *MySQLTokenRepo) FindByAccessToken(ctx context.Context, access string) (
 	var refreshExp sql.NullTime
 	if err := row.Scan(&t.ID, &t.UserID, &t.ClientID,
 		&t.AccessToken, &t.RefreshToken, &t.Scopes, &t.ExpiresAt, &refreshExp,
-		&t.CompanyID, &t.CreatedAt,
+		&t.CompanyID, &t.AuthTime, &t.CreatedAt,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -138,18 +140,219 @@ func (r *MySQLTokenRepo) RevokeByRefreshToken(ctx context.Context, refresh strin
 	return err
 }
 
-func (r *MySQLTokenRepo) CleanupExpired(ctx context.Context, now time.Time) error {
-	// hapus refresh token kedaluwarsa
-	if _, err := r.db.ExecContext(ctx, `
+// DeleteExpiredRefresh menghapus refresh token yang kedaluwarsa sebelum before, atau yang
+// dicabut tanpa expiry sebelum revokedBefore. Token dirotasi yang belum kedaluwarsa tetap
+// disimpan untuk deteksi reuse.
+func (r *MySQLTokenRepo) DeleteExpiredRefresh(ctx context.Context, before, revokedBefore time.Time, limit int) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `
 		DELETE FROM oauth_refresh_tokens
-		WHERE expires_at IS NOT NULL AND expires_at < ?
-	`, now); err != nil {
-		return err
+		WHERE (expires_at IS NOT NULL AND expires_at < ?)
+		   OR (expires_at IS NULL AND revoked = 1 AND COALESCE(rotated_at, created_at) < ?)
+		LIMIT ?
+	`, before, revokedBefore, limit)
+	if err != nil {
+		return 0, err
 	}
-	// hapus access token kedaluwarsa (akan CASCADE refresh via FK)
-	_, err := r.db.ExecContext(ctx, `
+	return res.RowsAffected()
+}
+
+// DeleteExpiredAccess menghapus access token kedaluwarsa / dicabut yang tidak lagi dirujuk
+// refresh token (FK CASCADE akan ikut menghapus refresh token yang masih berlaku).
+func (r *MySQLTokenRepo) DeleteExpiredAccess(ctx context.Context, before time.Time, limit int) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `
 		DELETE FROM oauth_access_tokens
-		WHERE expires_at < ?
-	`, now)
-	return err
+		WHERE (expires_at < ? OR revoked = 1)
+		  AND NOT EXISTS (
+		    SELECT 1 FROM oauth_refresh_tokens rt WHERE rt.access_token_id = oauth_access_tokens.id
+		  )
+		LIMIT ?
+	`, before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// FindByRefreshTokenIncludingRevoked juga mengembalikan refresh token yang sudah
+// dirotasi/dicabut (Revoked=true) supaya pemakaian ulang bisa dideteksi.
+// Refresh token aktif yang access token-nya sudah dicabut tetap dianggap tidak ada.
+func (r *MySQLTokenRepo) FindByRefreshTokenIncludingRevoked(ctx context.Context, refresh string) (*entities.Token, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT at.id, at.user_id, at.client_id,
+		       at.token      AS access_token,
+		       rt.token      AS refresh_token,
+		       COALESCE(rt.scopes, at.scopes) AS scopes,
+		       at.expires_at,
+		       rt.expires_at AS refresh_expires_at,
+		       at.company_id,
+		       at.auth_time,
+		       at.created_at,
+		       rt.id,
+		       COALESCE(rt.family_id, rt.id) AS family_id,
+		       rt.parent_id,
+		       rt.revoked,
+		       rt.resources,
+		       rt.dpop_jkt,
+		       rt.cert_x5t
+		FROM oauth_refresh_tokens rt
+		JOIN oauth_access_tokens  at ON at.id = rt.access_token_id
+		WHERE rt.token_sha = UNHEX(SHA2(?,256))
+		  AND (rt.revoked = 1 OR at.revoked = 0)
+	`, refresh)
+
+	var t entities.Token
+	var refreshExp sql.NullTime
+	if err := row.Scan(&t.ID, &t.UserID, &t.ClientID,
+		&t.AccessToken, &t.RefreshToken, &t.Scopes, &t.ExpiresAt, &refreshExp,
+		&t.CompanyID, &t.AuthTime, &t.CreatedAt,
+		&t.RefreshTokenID, &t.FamilyID, &t.ParentID, &t.Revoked, &t.Resources, &t.DPoPJKT, &t.CertX5T,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if refreshExp.Valid {
+		t.RefreshExpiresAt = refreshExp.Time
+	}
+	return &t, nil
+}
+
+// MarkRefreshRotated menandai refresh token sudah dipakai secara atomik.
+// false => token sudah dirotasi oleh request lain (indikasi reuse).
+func (r *MySQLTokenRepo) MarkRefreshRotated(ctx context.Context, refreshTokenID string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE oauth_refresh_tokens
+		SET revoked = 1, rotated_at = NOW()
+		WHERE id = ? AND revoked = 0
+	`, refreshTokenID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// RevokeFamily mencabut semua refresh token dalam satu family beserta access token-nya.
+func (r *MySQLTokenRepo) RevokeFamily(ctx context.Context, familyID string) ([]*entities.RevokedAccessToken, error) {
+	return r.revokeReturning(ctx, `
+		SELECT at.jti, oc.client_id, at.expires_at
+		FROM oauth_refresh_tokens rt
+		JOIN oauth_access_tokens at ON at.id = rt.access_token_id
+		JOIN oauth_clients oc ON oc.id = at.client_id
+		WHERE (rt.family_id = ? OR rt.id = ?) AND at.revoked = 0 AND at.expires_at > NOW()
+		FOR UPDATE
+	`, `
+		UPDATE oauth_refresh_tokens rt
+		JOIN oauth_access_tokens at ON at.id = rt.access_token_id
+		SET rt.revoked = 1, at.revoked = 1
+		WHERE rt.family_id = ? OR rt.id = ?
+	`, familyID, familyID)
+}
+
+func (r *MySQLTokenRepo) RevokeByUserClient(ctx context.Context, userID, clientID, companyID string) ([]*entities.RevokedAccessToken, error) {
+	return r.revokeReturning(ctx, `
+		SELECT at.jti, oc.client_id, at.expires_at
+		FROM oauth_access_tokens at
+		JOIN oauth_clients oc ON oc.id = at.client_id
+		WHERE at.user_id = ? AND at.client_id = ? AND COALESCE(at.company_id, '') = ?
+		  AND at.revoked = 0 AND at.expires_at > NOW()
+		FOR UPDATE
+	`, `
+		UPDATE oauth_access_tokens at
+		LEFT JOIN oauth_refresh_tokens rt ON rt.access_token_id = at.id
+		SET at.revoked = 1, rt.revoked = 1
+		WHERE at.user_id = ? AND at.client_id = ? AND COALESCE(at.company_id, '') = ?
+	`, userID, clientID, companyID)
+}
+
+// RevokeByUser — token "hidup" = access token belum kedaluwarsa atau refresh token-nya masih berlaku.
+func (r *MySQLTokenRepo) RevokeByUser(ctx context.Context, userID, clientID string) ([]*entities.RevokedAccessToken, error) {
+	return r.revokeReturning(ctx, `
+		SELECT at.jti, oc.client_id, at.expires_at
+		FROM oauth_access_tokens at
+		JOIN oauth_clients oc ON oc.id = at.client_id
+		WHERE at.user_id = ? AND (? = '' OR at.client_id = ?) AND at.revoked = 0
+		  AND (at.expires_at > NOW() OR EXISTS (
+		    SELECT 1 FROM oauth_refresh_tokens rt
+		    WHERE rt.access_token_id = at.id AND rt.revoked = 0 AND (rt.expires_at IS NULL OR rt.expires_at > NOW())
+		  ))
+		FOR UPDATE
+	`, `
+		UPDATE oauth_access_tokens at
+		LEFT JOIN oauth_refresh_tokens rt ON rt.access_token_id = at.id
+		SET at.revoked = 1, rt.revoked = 1
+		WHERE at.user_id = ? AND (? = '' OR at.client_id = ?)
+		  AND (at.revoked = 0 OR rt.revoked = 0)
+	`, userID, clientID, clientID)
+}
+
+// revokeReturning menjalankan selectQ (jti, client_id publik, expires_at ... FOR UPDATE)
+// lalu updateQ dalam satu transaksi; kedua query memakai args yang sama.
+func (r *MySQLTokenRepo) revokeReturning(ctx context.Context, selectQ, updateQ string, args ...any) ([]*entities.RevokedAccessToken, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, selectQ, args...)
+	if err != nil {
+		return nil, err
+	}
+	var out []*entities.RevokedAccessToken
+	for rows.Next() {
+		var t entities.RevokedAccessToken
+		var jti sql.NullString
+		if err := rows.Scan(&jti, &t.ClientPublicID, &t.ExpiresAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		t.JTI = jti.String
+		out = append(out, &t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, updateQ, args...); err != nil {
+		return nil, err
+	}
+	return out, tx.Commit()
+}
+
+func (r *MySQLTokenRepo) ListClientsByUser(ctx context.Context, userID string) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT DISTINCT oc.client_id
+		FROM oauth_access_tokens at
+		JOIN oauth_clients oc ON oc.id = at.client_id
+		LEFT JOIN oauth_refresh_tokens rt ON rt.access_token_id = at.id AND rt.revoked = 0
+		WHERE at.user_id = ? AND at.revoked = 0
+		  AND (at.expires_at > NOW() OR (rt.id IS NOT NULL AND (rt.expires_at IS NULL OR rt.expires_at > NOW())))
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
+
+func nullIfEmpty(v string) *string {
+	if v == "" {
+		return nil
+	}
+	return &v
 }
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"log"
+	"time"
 
 	"bkc_microservice/services/auth-service/internal/domain/entities"
 	"bkc_microservice/services/auth-service/internal/domain/repositories"
@@ -18,10 +19,18 @@ func NewMySQLUserRepo(db *sql.DB) repositories.UserRepository {
 	return &MySQLUserRepo{db: db}
 }
 
+const userColumns = `id, email, password_hash, is_active, is_locked, locked_until, failed_login_attempts,
+	last_login, two_factor_enabled, two_factor_secret, created_at`
+
+func scanUser(row *sql.Row, u *entities.User) error {
+	return row.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.IsActive, &u.IsLocked, &u.LockedUntil, &u.FailedLoginAttempts,
+		&u.LastLogin, &u.TwoFactorEnabled, &u.TwoFactorSecret, &u.CreatedAt)
+}
+
 func (r *MySQLUserRepo) FindByEmail(ctx context.Context, email string) (*entities.User, error) {
-	row := r.db.QueryRowContext(ctx, `SELECT id, email, password_hash, created_at FROM users WHERE email = ?`, email)
+	row := r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE email = ?`, email)
 	var u entities.User
-	if err := row.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.CreatedAt); err != nil {
+	if err := scanUser(row, &u); err != nil {
 		if errors.Is(err, sql.ErrNoRows) { // Jika tidak ada baris ditemukan
 			return nil, nil // Mengembalikan pointer nil dan error nil. INI PENTING!
 		}
@@ -31,9 +40,9 @@ func (r *MySQLUserRepo) FindByEmail(ctx context.Context, email string) (*entitie
 }
 
 func (r *MySQLUserRepo) FindByID(ctx context.Context, id string) (*entities.User, error) {
-	row := r.db.QueryRowContext(ctx, `SELECT id, email, password_hash, created_at FROM users WHERE id = ?`, id)
+	row := r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id = ?`, id)
 	var u entities.User
-	if err := row.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.CreatedAt); err != nil {
+	if err := scanUser(row, &u); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
@@ -60,3 +69,63 @@ func (r *MySQLUserRepo) CheckPassword(ctx context.Context, userID string, passwo
 	log.Printf("Password match for userID: %s", userID)
 	return true, nil // Password cocok
 }
+
+// RecordLoginFailure menaikkan failed_login_attempts secara atomik; bila mencapai threshold
+// akun dikunci sampai now+lockFor. Kunci yang sudah lewat locked_until dimulai dari nol.
+func (r *MySQLUserRepo) RecordLoginFailure(ctx context.Context, userID string, threshold int, lockFor time.Duration) (int, *time.Time, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var attempts int
+	var locked bool
+	var lockedUntil sql.NullTime
+	if err := tx.QueryRowContext(ctx, `
+		SELECT failed_login_attempts, is_locked, locked_until FROM users WHERE id = ? FOR UPDATE
+	`, userID).Scan(&attempts, &locked, &lockedUntil); err != nil {
+		return 0, nil, err
+	}
+	now := time.Now()
+	if locked && lockedUntil.Valid && !now.Before(lockedUntil.Time) {
+		// auto-unlock: hitungan dimulai lagi
+		attempts, locked, lockedUntil = 0, false, sql.NullTime{}
+	}
+	attempts++
+	if !locked && threshold > 0 && attempts >= threshold {
+		locked, lockedUntil = true, sql.NullTime{Time: now.Add(lockFor), Valid: true}
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE users SET failed_login_attempts = ?, is_locked = ?, locked_until = ? WHERE id = ?
+	`, attempts, locked, lockedUntil, userID); err != nil {
+		return 0, nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, nil, err
+	}
+	if lockedUntil.Valid {
+		return attempts, &lockedUntil.Time, nil
+	}
+	return attempts, nil, nil
+}
+
+// RecordLoginSuccess mencatat last_login, mereset hitungan gagal dan melepas kunci otomatis yang sudah lewat.
+func (r *MySQLUserRepo) RecordLoginSuccess(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE users
+		SET last_login = NOW(), failed_login_attempts = 0,
+		    is_locked = IF(locked_until IS NOT NULL AND locked_until <= NOW(), 0, is_locked),
+		    locked_until = IF(locked_until IS NOT NULL AND locked_until <= NOW(), NULL, locked_until)
+		WHERE id = ?
+	`, userID)
+	return err
+}
+
+// Unlock melepas kunci (otomatis maupun admin) dan mereset hitungan gagal.
+func (r *MySQLUserRepo) Unlock(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE users SET is_locked = 0, locked_until = NULL, failed_login_attempts = 0 WHERE id = ?
+	`, userID)
+	return err
+}
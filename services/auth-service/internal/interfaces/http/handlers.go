@@ -2,17 +2,26 @@ package http
 
 import (
 	"context"
+	"crypto/rand"
 	"crypto/subtle"
 	"encoding/base64"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"html/template"
 	"log"
+	"math"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
 
 	"bkc_microservice/services/auth-service/internal/application/services"
+	"bkc_microservice/services/auth-service/internal/domain/entities"
+	sharedsec "bkc_microservice/shared/security"
 )
 
 /* ------------------------------
@@ -20,112 +29,733 @@ import (
 ------------------------------ */
 
 type authorizeRequest struct {
-	ResponseType        string `json:"responseType"`
-	ClientID            string `json:"clientId"`
-	RedirectURI         string `json:"redirectUri"`
-	Scope               string `json:"scope"`
-	State               string `json:"state,omitempty"`
-	UserID              string `json:"userId"`
-	CodeChallenge       string `json:"codeChallenge"`
-	CodeChallengeMethod string `json:"codeChallengeMethod"`
-	CompanyID           string `json:"companyId"`
+	services.AuthorizationParams
+}
+
+// authorizeParamsFromForm membaca parameter authorize dari query / form
+// (dipakai /oauth/authorize dan /oauth/par).
+func authorizeParamsFromForm(r *http.Request) services.AuthorizationParams {
+	return services.AuthorizationParams{
+		ResponseType:        r.FormValue("response_type"),
+		ClientID:            r.FormValue("client_id"),
+		RedirectURI:         r.FormValue("redirect_uri"),
+		Scope:               r.FormValue("scope"),
+		State:               r.FormValue("state"),
+		CodeChallenge:       r.FormValue("code_challenge"),
+		CodeChallengeMethod: r.FormValue("code_challenge_method"),
+		CompanyID:           r.FormValue("company_id"),
+		Nonce:               r.FormValue("nonce"),
+		Prompt:              r.FormValue("prompt"),
+		MaxAge:              r.FormValue("max_age"),
+		Resource:            r.Form["resource"],
+	}
+}
+
+// query mengembalikan parameter authorize sebagai query string (nilai kosong dilewati).
+// Request dari PAR / request object cukup membawa client_id + request_uri.
+func (req authorizeRequest) query() string {
+	q := url.Values{}
+	if req.RequestURI != "" {
+		q.Set("client_id", req.ClientID)
+		q.Set("request_uri", req.RequestURI)
+		return q.Encode()
+	}
+	for k, v := range map[string]string{
+		"response_type":         req.ResponseType,
+		"client_id":             req.ClientID,
+		"redirect_uri":          req.RedirectURI,
+		"scope":                 req.Scope,
+		"state":                 req.State,
+		"code_challenge":        req.CodeChallenge,
+		"code_challenge_method": req.CodeChallengeMethod,
+		"company_id":            req.CompanyID,
+		"nonce":                 req.Nonce,
+		"prompt":                req.Prompt,
+		"max_age":               req.MaxAge,
+	} {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	for _, v := range req.Resource {
+		q.Add("resource", v)
+	}
+	return q.Encode()
+}
+
+type consentPage struct {
+	Req        authorizeRequest
+	ClientName string
+	Username   string
+	Missing    []string // scope yang baru diminta
+	Granted    []string // sudah disetujui sebelumnya
+	CSRFToken  string
 }
 
 var consentTmpl = template.Must(template.New("consent").Parse(`
 <!doctype html>
-<html><head><meta charset="utf-8"><title>Consent</title></head>
+<html><head><meta charset="utf-8"><title>Izin Aplikasi</title></head>
 <body>
-  <h2>App "{{.ClientID}}" minta izin</h2>
-  <p>User: {{.UserID}}</p>
-  <p>Scope: {{.Scope}}</p>
+  <h2>Aplikasi "{{.ClientName}}" meminta akses ke akun Anda</h2>
+  <p>Masuk sebagai <b>{{.Username}}</b></p>
+  {{if .Missing}}<p>Izin yang diminta:</p>
+  <ul>{{range .Missing}}<li>{{.}}</li>{{end}}</ul>{{end}}
+  {{if .Granted}}<p>Sudah Anda izinkan sebelumnya:</p>
+  <ul>{{range .Granted}}<li>{{.}}</li>{{end}}</ul>{{end}}
   <form method="POST" action="/oauth/authorize">
-    <input type="hidden" name="response_type" value="code"/>
-    <input type="hidden" name="client_id" value="{{.ClientID}}"/>
-    <input type="hidden" name="redirect_uri" value="{{.RedirectURI}}"/>
-    <input type="hidden" name="scope" value="{{.Scope}}"/>
-    <input type="hidden" name="state" value="{{.State}}"/>
-    <input type="hidden" name="user_id" value="{{.UserID}}"/>
-    <input type="hidden" name="code_challenge" value="{{.CodeChallenge}}"/>
-    <input type="hidden" name="code_challenge_method" value="{{.CodeChallengeMethod}}"/>
-    <input type="hidden" name="company_id" value="{{.CompanyID}}"/>
-    <button type="submit" name="approve" value="1">Allow</button>
-    <button type="submit" name="approve" value="0">Deny</button>
+    <input type="hidden" name="csrf_token" value="{{.CSRFToken}}"/>
+    {{if .Req.RequestURI}}<input type="hidden" name="client_id" value="{{.Req.ClientID}}"/>
+    <input type="hidden" name="request_uri" value="{{.Req.RequestURI}}"/>
+    {{else}}<input type="hidden" name="response_type" value="{{.Req.ResponseType}}"/>
+    <input type="hidden" name="client_id" value="{{.Req.ClientID}}"/>
+    <input type="hidden" name="redirect_uri" value="{{.Req.RedirectURI}}"/>
+    <input type="hidden" name="scope" value="{{.Req.Scope}}"/>
+    <input type="hidden" name="state" value="{{.Req.State}}"/>
+    <input type="hidden" name="code_challenge" value="{{.Req.CodeChallenge}}"/>
+    <input type="hidden" name="code_challenge_method" value="{{.Req.CodeChallengeMethod}}"/>
+    <input type="hidden" name="company_id" value="{{.Req.CompanyID}}"/>
+    <input type="hidden" name="nonce" value="{{.Req.Nonce}}"/>
+    <input type="hidden" name="prompt" value="{{.Req.Prompt}}"/>
+    <input type="hidden" name="max_age" value="{{.Req.MaxAge}}"/>
+    {{range .Req.Resource}}<input type="hidden" name="resource" value="{{.}}"/>{{end}}{{end}}
+    <button type="submit" name="approve" value="1">Izinkan</button>
+    <button type="submit" name="approve" value="0">Tolak</button>
   </form>
 </body></html>`))
 
+// MakeAuthorizeHandler — user diambil dari sesi login (cookie), bukan dari parameter.
+// GET menampilkan consent; POST (submit consent) menerbitkan code.
+// prompt=none tidak pernah menampilkan UI: tanpa sesi valid => error login_required.
+// prompt=login / max_age yang terlampaui memaksa login ulang.
+// request_uri (PAR) / request (JAR) menggantikan parameter query.
 func MakeAuthorizeHandler(s *services.AuthService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
-		var req authorizeRequest
-
-		if r.Method == http.MethodGet {
-			q := r.URL.Query()
-			req = authorizeRequest{
-				ResponseType:        q.Get("response_type"),
-				ClientID:            q.Get("client_id"),
-				RedirectURI:         q.Get("redirect_uri"),
-				Scope:               q.Get("scope"),
-				State:               q.Get("state"),
-				UserID:              q.Get("user_id"),
-				CodeChallenge:       q.Get("code_challenge"),
-				CodeChallengeMethod: q.Get("code_challenge_method"),
-				CompanyID:           q.Get("company_id"),
-			}
+		_ = r.ParseForm()
+
+		params, err := s.ResolveAuthorizationRequest(ctx, authorizeParamsFromForm(r), r.FormValue("request_uri"), r.FormValue("request"))
+		if err != nil {
+			log.Printf("[/oauth/authorize] clientID=%s err=%v", r.FormValue("client_id"), err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req := authorizeRequest{*params}
+
+		// client_id + redirect_uri divalidasi dulu; error di sini tidak di-redirect
+		client, err := s.ValidateAuthorizeRedirect(ctx, req.ClientID, req.RedirectURI)
+		if err != nil {
+			log.Printf("[/oauth/authorize] clientID=%s err=%v", req.ClientID, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.RedirectURI = client.RedirectURI
+
+		if strings.ToLower(req.ResponseType) != "code" {
+			redirectAuthorizeError(w, r, req, services.ErrUnsupportedResponseType)
+			return
+		}
 
-			if strings.ToLower(q.Get("prompt")) != "none" && q.Get("autoapprove") != "1" {
-				w.Header().Set("Content-Type", "text/html; charset=utf-8")
-				_ = consentTmpl.Execute(w, req)
+		prompts := strings.Fields(req.Prompt)
+		promptNone := containsValue(prompts, "none")
+		if promptNone && len(prompts) > 1 {
+			redirectAuthorizeError(w, r, req, &services.OAuthError{Code: "invalid_request", Description: "prompt=none cannot be combined with other values"})
+			return
+		}
+		maxAge := -1
+		if req.MaxAge != "" {
+			n, err := strconv.Atoi(req.MaxAge)
+			if err != nil || n < 0 {
+				redirectAuthorizeError(w, r, req, &services.OAuthError{Code: "invalid_request", Description: "invalid max_age"})
 				return
 			}
-		} else {
-			_ = r.ParseForm()
-			req = authorizeRequest{
-				ResponseType:        r.FormValue("response_type"),
-				ClientID:            r.FormValue("client_id"),
-				RedirectURI:         r.FormValue("redirect_uri"),
-				Scope:               r.FormValue("scope"),
-				State:               r.FormValue("state"),
-				UserID:              r.FormValue("user_id"),
-				CodeChallenge:       r.FormValue("code_challenge"),
-				CodeChallengeMethod: r.FormValue("code_challenge_method"),
-				CompanyID:           r.FormValue("company_id"),
-			}
-			if r.PostFormValue("approve") == "0" {
-				http.Error(w, "access_denied", http.StatusForbidden)
+			maxAge = n
+		}
+
+		sess := currentAuthSession(s, r)
+		// request_uri tidak berubah setelah login: prompt=login terpenuhi oleh login sesudah request diterima
+		forceLogin := containsValue(prompts, "login") && (sess == nil || req.RequestURI == "" || sess.AuthTime < req.RequestedAt)
+		if sess == nil || forceLogin || !sess.Fresh(maxAge, time.Now()) {
+			if promptNone {
+				redirectAuthorizeError(w, r, req, services.ErrLoginRequired)
 				return
 			}
+			// setelah login kembali ke authorize tanpa prompt=login agar tidak berulang
+			next := req
+			next.Prompt = strings.Join(removeValue(prompts, "login"), " ")
+			http.Redirect(w, r, "/oauth/login?return_to="+url.QueryEscape("/oauth/authorize?"+next.query()), http.StatusFound)
+			return
 		}
 
-		if strings.ToLower(req.ResponseType) != "code" {
-			http.Error(w, "unsupported response_type", http.StatusBadRequest)
+		consent, err := s.CheckConsent(ctx, sess.UserID, req.ClientID, req.Scope, req.CompanyID)
+		if err != nil {
+			authorizeFailure(w, r, req, sess.UserID, err)
+			return
+		}
+
+		// consent tersimpan yang sudah mencakup semua scope => layar consent dilewati
+		if r.Method == http.MethodGet && (len(consent.Missing) > 0 || containsValue(prompts, "consent")) {
+			if promptNone {
+				redirectAuthorizeError(w, r, req, services.ErrConsentRequired)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Header().Set("Cache-Control", "no-store")
+			_ = consentTmpl.Execute(w, consentPage{
+				Req:        req,
+				ClientName: client.ClientName,
+				Username:   sess.Username,
+				Missing:    consent.Missing,
+				Granted:    consent.Granted,
+				CSRFToken:  s.SessionCSRFToken(sess),
+			})
 			return
 		}
+		if r.Method == http.MethodPost {
+			if !s.VerifySessionCSRF(sess, r.PostFormValue("csrf_token")) {
+				http.Error(w, "invalid csrf token", http.StatusForbidden)
+				return
+			}
+			if r.PostFormValue("approve") != "1" {
+				s.ConsumeAuthorizationRequest(ctx, req.RequestURI)
+				redirectAuthorizeError(w, r, req, services.ErrAccessDenied)
+				return
+			}
+			if err := s.GrantConsent(ctx, sess.UserID, req.ClientID, req.Scope, req.CompanyID); err != nil {
+				authorizeFailure(w, r, req, sess.UserID, err)
+				return
+			}
+		}
 
-		code, err := s.StartAuthorizationCode(ctx,
-			req.UserID,
+		code, redirectURI, err := s.StartAuthorizationCode(ctx,
+			sess.UserID,
 			req.ClientID,
 			req.RedirectURI,
 			req.Scope,
 			req.CodeChallenge,
 			req.CodeChallengeMethod,
-			req.CompanyID)
+			req.CompanyID,
+			req.Nonce,
+			req.Resource,
+			sess.AuthenticatedAt())
 		if err != nil {
-			log.Printf("[/oauth/authorize] userID=%s clientID=%s err=%v", req.UserID, req.ClientID, err)
+			authorizeFailure(w, r, req, sess.UserID, err)
+			return
+		}
+
+		s.ConsumeAuthorizationRequest(ctx, req.RequestURI)
+		redirectWithParams(w, r, redirectURI, map[string]string{"code": code, "state": req.State})
+	}
+}
+
+/* ------------------------------
+   /oauth/par (RFC 9126)
+------------------------------ */
+
+// MakePushedAuthorizationHandler — client mengirim parameter authorize (atau request
+// object) lewat back-channel dan menerima request_uri untuk /oauth/authorize.
+func MakePushedAuthorizationHandler(s *services.AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.PostFormValue("request_uri") != "" {
+			writeOAuthError(w, http.StatusBadRequest, &services.OAuthError{Code: "invalid_request", Description: "request_uri is not allowed in a pushed authorization request"})
+			return
+		}
+		auth := clientAuthFromRequest(r, services.ClientAuth{
+			ClientID:      r.PostFormValue("client_id"),
+			ClientSecret:  r.PostFormValue("client_secret"),
+			AssertionType: r.PostFormValue("client_assertion_type"),
+			Assertion:     r.PostFormValue("client_assertion"),
+		})
+
+		res, err := s.PushAuthorizationRequest(r.Context(), auth, authorizeParamsFromForm(r), r.PostFormValue("request"))
+		var oe *services.OAuthError
+		switch {
+		case errors.As(err, &oe):
+			writeOAuthError(w, oauthErrorStatus(oe), oe)
+			return
+		case err != nil:
+			log.Printf("[/oauth/par] clientID=%s err=%v", auth.ClientID, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeNoStoreJSON(w, http.StatusCreated, res)
+	}
+}
+
+// authorizeFailure — error protokol dikembalikan ke client lewat redirect_uri
+// (sudah tervalidasi); error lain ditampilkan langsung.
+func authorizeFailure(w http.ResponseWriter, r *http.Request, req authorizeRequest, userID string, err error) {
+	var oe *services.OAuthError
+	if errors.As(err, &oe) {
+		redirectAuthorizeError(w, r, req, oe)
+		return
+	}
+	log.Printf("[/oauth/authorize] userID=%s clientID=%s err=%v", userID, req.ClientID, err)
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
+// redirectAuthorizeError mengembalikan error protokol ke client lewat redirect_uri yang sudah tervalidasi.
+func redirectAuthorizeError(w http.ResponseWriter, r *http.Request, req authorizeRequest, oe *services.OAuthError) {
+	redirectWithParams(w, r, req.RedirectURI, map[string]string{
+		"error":             oe.Code,
+		"error_description": oe.Description,
+		"state":             req.State,
+	})
+}
+
+/* ------------------------------
+   /oauth/login
+------------------------------ */
+
+const (
+	authSessionCookie = "bkc_auth_session"
+	loginCSRFCookie   = "bkc_login_csrf"
+)
+
+type loginPage struct {
+	ReturnTo  string
+	Login     string
+	Error     string
+	CSRFToken string
+}
+
+var loginTmpl = template.Must(template.New("login").Parse(`
+<!doctype html>
+<html><head><meta charset="utf-8"><title>Login</title></head>
+<body>
+  <h2>Login</h2>
+  {{if .Error}}<p style="color:#b00">{{.Error}}</p>{{end}}
+  <form method="POST" action="/oauth/login">
+    <input type="hidden" name="csrf_token" value="{{.CSRFToken}}"/>
+    <input type="hidden" name="return_to" value="{{.ReturnTo}}"/>
+    <label>Email / username <input type="text" name="login" value="{{.Login}}" autocomplete="username" required/></label><br/>
+    <label>Password <input type="password" name="password" autocomplete="current-password" required/></label><br/>
+    <button type="submit">Masuk</button>
+  </form>
+</body></html>`))
+
+// MakeLoginPageHandler — GET /oauth/login menampilkan form login.
+func MakeLoginPageHandler(s *services.AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		returnTo, ok := safeReturnTo(r.URL.Query().Get("return_to"))
+		if !ok {
+			http.Error(w, "invalid return_to", http.StatusBadRequest)
+			return
+		}
+		renderLogin(w, s, http.StatusOK, loginPage{ReturnTo: returnTo})
+	}
+}
+
+// MakeLoginHandler — POST /oauth/login: verifikasi kredensial ke user-service,
+// set cookie sesi lalu kembali ke /oauth/authorize.
+func MakeLoginHandler(s *services.AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		returnTo, ok := safeReturnTo(r.PostFormValue("return_to"))
+		if !ok {
+			http.Error(w, "invalid return_to", http.StatusBadRequest)
+			return
+		}
+		page := loginPage{ReturnTo: returnTo, Login: strings.TrimSpace(r.PostFormValue("login"))}
+
+		c, err := r.Cookie(loginCSRFCookie)
+		if err != nil || subtle.ConstantTimeCompare([]byte(c.Value), []byte(r.PostFormValue("csrf_token"))) != 1 {
+			page.Error = "Sesi login kedaluwarsa, silakan coba lagi."
+			renderLogin(w, s, http.StatusForbidden, page)
+			return
+		}
+
+		sess, cookie, err := s.Login(r.Context(), page.Login, r.PostFormValue("password"))
+		if err != nil {
+			status := http.StatusInternalServerError
+			switch {
+			case errors.Is(err, services.ErrInvalidCredentials):
+				status, page.Error = http.StatusUnauthorized, "Email/username atau password salah."
+			case errors.Is(err, services.ErrAccountLocked):
+				status, page.Error = http.StatusLocked, "Akun Anda terkunci. Hubungi administrator."
+			default:
+				log.Printf("[/oauth/login] err=%v", err)
+				page.Error = "Login gagal, silakan coba lagi."
+			}
+			renderLogin(w, s, status, page)
+			return
+		}
+
+		// sesi lama di browser ini tidak dipakai lagi
+		if old := currentAuthSession(s, r); old != nil {
+			_ = s.Logout(r.Context(), old)
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     authSessionCookie,
+			Value:    cookie,
+			Path:     "/",
+			MaxAge:   int(s.AuthSessionTTL().Seconds()),
+			HttpOnly: true,
+			Secure:   secureCookies(s),
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.SetCookie(w, &http.Cookie{Name: loginCSRFCookie, Path: "/oauth/login", MaxAge: -1})
+		log.Printf("[/oauth/login] user=%s", sess.UserID)
+		http.Redirect(w, r, returnTo, http.StatusSeeOther)
+	}
+}
+
+func renderLogin(w http.ResponseWriter, s *services.AuthService, status int, page loginPage) {
+	buf := make([]byte, 24)
+	_, _ = rand.Read(buf)
+	page.CSRFToken = base64.RawURLEncoding.EncodeToString(buf)
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginCSRFCookie,
+		Value:    page.CSRFToken,
+		Path:     "/oauth/login",
+		MaxAge:   int((15 * time.Minute).Seconds()),
+		HttpOnly: true,
+		Secure:   secureCookies(s),
+		SameSite: http.SameSiteStrictMode,
+	})
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.WriteHeader(status)
+	_ = loginTmpl.Execute(w, page)
+}
+
+// currentAuthSession membaca cookie sesi login; nil jika tidak ada / tidak valid / sudah dicabut.
+func currentAuthSession(s *services.AuthService, r *http.Request) *services.AuthSession {
+	c, err := r.Cookie(authSessionCookie)
+	if err != nil || c.Value == "" {
+		return nil
+	}
+	sess, err := s.LoadSession(r.Context(), c.Value)
+	if err != nil {
+		return nil
+	}
+	return sess
+}
+
+// safeReturnTo hanya menerima path lokal /oauth/authorize & /oauth/device (cegah open redirect).
+func safeReturnTo(v string) (string, bool) {
+	u, err := url.Parse(v)
+	if err != nil || u.Scheme != "" || u.Host != "" || (u.Path != "/oauth/authorize" && u.Path != "/oauth/device") {
+		return "", false
+	}
+	return u.String(), true
+}
+
+func secureCookies(s *services.AuthService) bool {
+	return strings.HasPrefix(s.Dep().PublicURL, "https://")
+}
+
+func containsValue(list []string, v string) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func removeValue(list []string, v string) []string {
+	out := make([]string, 0, len(list))
+	for _, x := range list {
+		if x != v {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+/* ------------------------------
+   /oauth/device_authorization & /oauth/device (RFC 8628)
+------------------------------ */
+
+func MakeDeviceAuthorizationHandler(s *services.AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		auth := clientAuthFromRequest(r, services.ClientAuth{
+			ClientID:      r.PostFormValue("client_id"),
+			ClientSecret:  r.PostFormValue("client_secret"),
+			AssertionType: r.PostFormValue("client_assertion_type"),
+			Assertion:     r.PostFormValue("client_assertion"),
+		})
+
+		res, err := s.StartDeviceAuthorization(r.Context(), auth, r.PostFormValue("scope"), r.PostFormValue("company_id"), r.PostForm["resource"])
+		var oe *services.OAuthError
+		switch {
+		case errors.As(err, &oe):
+			writeOAuthError(w, oauthErrorStatus(oe), oe)
+			return
+		case err != nil:
+			log.Printf("[/oauth/device_authorization] clientID=%s err=%v", auth.ClientID, err)
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		writeNoStoreJSON(w, http.StatusOK, res)
+	}
+}
+
+type devicePage struct {
+	UserCode  string
+	Request   *services.DeviceRequest
+	Username  string
+	CSRFToken string
+	Error     string
+	Done      string
+}
+
+var deviceTmpl = template.Must(template.New("device").Parse(`
+<!doctype html>
+<html><head><meta charset="utf-8"><title>Hubungkan Perangkat</title></head>
+<body>
+  <h2>Hubungkan Perangkat</h2>
+  {{if .Done}}<p>{{.Done}}</p>{{else}}
+  {{if .Error}}<p style="color:#b00">{{.Error}}</p>{{end}}
+  <p>Masuk sebagai <b>{{.Username}}</b></p>
+  {{if .Request}}
+  <p>Perangkat "{{.Request.ClientName}}" dengan kode <b>{{.Request.UserCode}}</b> meminta akses:</p>
+  <ul>{{range .Request.Scopes}}<li>{{.}}</li>{{end}}</ul>
+  <form method="POST" action="/oauth/device">
+    <input type="hidden" name="csrf_token" value="{{.CSRFToken}}"/>
+    <input type="hidden" name="user_code" value="{{.Request.UserCode}}"/>
+    <button type="submit" name="approve" value="1">Izinkan</button>
+    <button type="submit" name="approve" value="0">Tolak</button>
+  </form>
+  {{else}}
+  <form method="GET" action="/oauth/device">
+    <label>Kode perangkat <input type="text" name="user_code" value="{{.UserCode}}" autocomplete="off" required/></label>
+    <button type="submit">Lanjut</button>
+  </form>
+  {{end}}{{end}}
+</body></html>`))
+
+// MakeDevicePageHandler — GET /oauth/device: user login (alur login authorize) lalu
+// memasukkan / mengonfirmasi user_code yang tampil di perangkat.
+func MakeDevicePageHandler(s *services.AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sess := currentAuthSession(s, r)
+		if sess == nil {
+			http.Redirect(w, r, "/oauth/login?return_to="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+			return
+		}
 
-		u, _ := url.Parse(req.RedirectURI)
-		q := u.Query()
-		q.Set("code", code)
-		if req.State != "" {
-			q.Set("state", req.State)
+		page := devicePage{UserCode: r.URL.Query().Get("user_code"), Username: sess.Username}
+		status := http.StatusOK
+		if page.UserCode != "" {
+			req, err := s.LookupDeviceRequest(r.Context(), page.UserCode)
+			if err != nil {
+				status, page.Error = http.StatusNotFound, "Kode tidak valid atau sudah kedaluwarsa."
+			} else {
+				page.Request = req
+				page.CSRFToken = s.SessionCSRFToken(sess)
+			}
+		}
+		renderDevice(w, status, page)
+	}
+}
+
+// MakeDeviceDecisionHandler — POST /oauth/device: simpan keputusan user atas user_code.
+func MakeDeviceDecisionHandler(s *services.AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		sess := currentAuthSession(s, r)
+		if sess == nil {
+			http.Redirect(w, r, "/oauth/login?return_to="+url.QueryEscape("/oauth/device"), http.StatusSeeOther)
+			return
+		}
+		if !s.VerifySessionCSRF(sess, r.PostFormValue("csrf_token")) {
+			http.Error(w, "invalid csrf token", http.StatusForbidden)
+			return
+		}
+
+		approve := r.PostFormValue("approve") == "1"
+		page := devicePage{Username: sess.Username}
+		err := s.DecideDeviceRequest(r.Context(), sess, r.PostFormValue("user_code"), approve)
+		switch {
+		case errors.Is(err, services.ErrInvalidUserCode):
+			page.Error = "Kode tidak valid atau sudah kedaluwarsa."
+			renderDevice(w, http.StatusNotFound, page)
+		case err != nil:
+			log.Printf("[/oauth/device] user=%s err=%v", sess.UserID, err)
+			page.Error = "Gagal memproses permintaan, silakan coba lagi."
+			renderDevice(w, http.StatusBadRequest, page)
+		case approve:
+			page.Done = "Perangkat berhasil dihubungkan. Silakan kembali ke perangkat Anda."
+			renderDevice(w, http.StatusOK, page)
+		default:
+			page.Done = "Permintaan perangkat ditolak."
+			renderDevice(w, http.StatusOK, page)
+		}
+	}
+}
+
+func renderDevice(w http.ResponseWriter, status int, page devicePage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.WriteHeader(status)
+	_ = deviceTmpl.Execute(w, page)
+}
+
+/* ------------------------------
+   /oauth/end_session, /oauth/logout_all (OIDC RP-Initiated / Back-Channel Logout)
+------------------------------ */
+
+type logoutPage struct {
+	Username  string
+	CSRFToken string
+	Request   services.EndSessionRequest
+	Done      bool
+}
+
+var logoutTmpl = template.Must(template.New("logout").Parse(`
+<!doctype html>
+<html><head><meta charset="utf-8"><title>Logout</title></head>
+<body>
+  <h2>Logout</h2>
+  {{if .Done}}<p>Anda sudah keluar.</p>{{else}}
+  <p>Keluar dari akun <b>{{.Username}}</b>?</p>
+  <form method="POST" action="/oauth/end_session">
+    <input type="hidden" name="csrf_token" value="{{.CSRFToken}}"/>
+    <input type="hidden" name="client_id" value="{{.Request.ClientID}}"/>
+    <input type="hidden" name="id_token_hint" value="{{.Request.IDTokenHint}}"/>
+    <input type="hidden" name="post_logout_redirect_uri" value="{{.Request.PostLogoutRedirectURI}}"/>
+    <input type="hidden" name="state" value="{{.Request.State}}"/>
+    <button type="submit" name="confirm" value="1">Keluar</button>
+  </form>
+  {{end}}
+</body></html>`))
+
+// MakeEndSessionHandler — GET/POST /oauth/end_session: akhiri sesi browser, kirim
+// back-channel logout, lalu redirect ke post_logout_redirect_uri yang terdaftar.
+func MakeEndSessionHandler(s *services.AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		sess := currentAuthSession(s, r)
+		req := services.EndSessionRequest{
+			IDTokenHint:           r.FormValue("id_token_hint"),
+			ClientID:              r.FormValue("client_id"),
+			PostLogoutRedirectURI: r.FormValue("post_logout_redirect_uri"),
+			State:                 r.FormValue("state"),
+		}
+		// konfirmasi hanya sah lewat POST form dengan CSRF token sesi
+		if r.Method == http.MethodPost && r.PostFormValue("confirm") == "1" {
+			if sess != nil && !s.VerifySessionCSRF(sess, r.PostFormValue("csrf_token")) {
+				http.Error(w, "invalid csrf token", http.StatusForbidden)
+				return
+			}
+			req.Confirmed = true
+		}
+
+		redirect, err := s.EndSession(r.Context(), req, sess)
+		var oe *services.OAuthError
+		switch {
+		case errors.Is(err, services.ErrLogoutConfirmationRequired):
+			renderLogout(w, http.StatusOK, logoutPage{Username: sess.Username, CSRFToken: s.SessionCSRFToken(sess), Request: req})
+			return
+		case errors.As(err, &oe):
+			writeOAuthError(w, http.StatusBadRequest, oe)
+			return
+		case err != nil:
+			log.Printf("[/oauth/end_session] err=%v", err)
+			writeOAuthError(w, http.StatusInternalServerError, &services.OAuthError{Code: "server_error"})
+			return
+		}
+
+		clearAuthSessionCookie(w, s)
+		if redirect != "" {
+			http.Redirect(w, r, redirect, http.StatusSeeOther)
+			return
+		}
+		renderLogout(w, http.StatusOK, logoutPage{Done: true})
+	}
+}
+
+func renderLogout(w http.ResponseWriter, status int, page logoutPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.WriteHeader(status)
+	_ = logoutTmpl.Execute(w, page)
+}
+
+func clearAuthSessionCookie(w http.ResponseWriter, s *services.AuthService) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     authSessionCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   secureCookies(s),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// MakeLogoutAllHandler — POST /oauth/logout_all: user (Bearer access token miliknya)
+// mencabut semua token & sesinya; client_id (opsional) membatasi ke satu client.
+func MakeLogoutAllHandler(s *services.AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scheme, token := accessToken(r)
+		claims, err := s.AuthenticateBearer(r, scheme, token, "")
+		if err == nil && claims.UserID == "" {
+			err = services.ErrInvalidToken
+		}
+		if err != nil {
+			writeInvalidToken(w, s, err)
+			return
+		}
+		clientID := r.FormValue("client_id")
+		res, err := s.LogoutEverywhere(r.Context(), claims.UserID, clientID)
+		if err != nil {
+			writeClientError(w, "/oauth/logout_all", err)
+			return
+		}
+		if clientID == "" {
+			clearAuthSessionCookie(w, s)
+		}
+		writeNoStoreJSON(w, http.StatusOK, res)
+	}
+}
+
+// MakeAdminLogoutUserHandler — POST /oauth/admin/users/{user_id}/logout (scope oauth:admin).
+func MakeAdminLogoutUserHandler(s *services.AuthService) http.HandlerFunc {
+	return requireAdminScope(s, func(w http.ResponseWriter, r *http.Request) {
+		res, err := s.LogoutEverywhere(r.Context(), mux.Vars(r)["user_id"], r.FormValue("client_id"))
+		if err != nil {
+			writeClientError(w, "/oauth/admin/users/logout", err)
+			return
+		}
+		writeNoStoreJSON(w, http.StatusOK, res)
+	})
+}
+
+// MakeInternalLogoutUserHandler — POST /internal/users/{user_id}/logout (X-Internal-Api-Key):
+// cabut semua token & sesi user, dipanggil user-service setelah password berubah.
+func MakeInternalLogoutUserHandler(s *services.AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res, err := s.LogoutEverywhere(r.Context(), mux.Vars(r)["user_id"], "")
+		if err != nil {
+			writeClientError(w, "/internal/users/logout", err)
+			return
 		}
-		u.RawQuery = q.Encode()
-		http.Redirect(w, r, u.String(), http.StatusFound)
+		writeNoStoreJSON(w, http.StatusOK, res)
 	}
 }
 
+// MakeAdminUnlockUserHandler — POST /oauth/admin/users/{user_id}/unlock (scope oauth:admin).
+func MakeAdminUnlockUserHandler(s *services.AuthService) http.HandlerFunc {
+	return requireAdminScope(s, func(w http.ResponseWriter, r *http.Request) {
+		err := s.UnlockUser(r.Context(), mux.Vars(r)["user_id"])
+		switch {
+		case errors.Is(err, services.ErrUserNotFound):
+			writeOAuthError(w, http.StatusNotFound, &services.OAuthError{Code: "invalid_request", Description: err.Error()})
+		case err != nil:
+			writeClientError(w, "/oauth/admin/users/unlock", err)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+}
+
 /* ------------------------------
    /oauth/token
 ------------------------------ */
@@ -142,6 +772,24 @@ type tokenForm struct {
 	RefreshToken string `json:"refreshToken,omitempty"`
 	Scope        string `json:"scope,omitempty"`
 	CompanyID    string `json:"companyId,omitempty"`
+	DeviceCode   string `json:"deviceCode,omitempty"`
+	MFAToken     string `json:"mfaToken,omitempty"`
+	OTP          string `json:"otp,omitempty"`
+	RecoveryCode string `json:"recoveryCode,omitempty"`
+
+	// resource indicators (RFC 8707), boleh berulang
+	Resource []string `json:"resource,omitempty"`
+
+	// token exchange (RFC 8693)
+	SubjectToken       string   `json:"subjectToken,omitempty"`
+	SubjectTokenType   string   `json:"subjectTokenType,omitempty"`
+	ActorToken         string   `json:"actorToken,omitempty"`
+	ActorTokenType     string   `json:"actorTokenType,omitempty"`
+	RequestedTokenType string   `json:"requestedTokenType,omitempty"`
+	Audience           []string `json:"audience,omitempty"`
+
+	ClientAssertionType string `json:"clientAssertionType,omitempty"`
+	ClientAssertion     string `json:"clientAssertion,omitempty"`
 }
 
 func MakeTokenHandler(s *services.AuthService) http.HandlerFunc {
@@ -169,38 +817,177 @@ func MakeTokenHandler(s *services.AuthService) http.HandlerFunc {
 				RefreshToken: r.FormValue("refresh_token"),
 				Scope:        r.FormValue("scope"),
 				CompanyID:    r.FormValue("company_id"),
+				DeviceCode:   r.FormValue("device_code"),
+				MFAToken:     r.FormValue("mfa_token"),
+				OTP:          r.FormValue("otp"),
+				RecoveryCode: r.FormValue("recovery_code"),
+				Resource:     r.Form["resource"],
+
+				SubjectToken:       r.FormValue("subject_token"),
+				SubjectTokenType:   r.FormValue("subject_token_type"),
+				ActorToken:         r.FormValue("actor_token"),
+				ActorTokenType:     r.FormValue("actor_token_type"),
+				RequestedTokenType: r.FormValue("requested_token_type"),
+				Audience:           r.Form["audience"],
+
+				ClientAssertionType: r.FormValue("client_assertion_type"),
+				ClientAssertion:     r.FormValue("client_assertion"),
 			}
 		}
 
-		var (
-			res *services.TokenResponse
-			err error
-		)
+		auth := clientAuthFromRequest(r, services.ClientAuth{
+			ClientID:      req.ClientID,
+			ClientSecret:  req.ClientSecret,
+			AssertionType: req.ClientAssertionType,
+			Assertion:     req.ClientAssertion,
+		})
+
+		// DPoP (RFC 9449): token di-bind ke key proof; nonce server dikirim lewat DPoP-Nonce
+		if n := s.DPoPNonce(); n != "" {
+			w.Header().Set("DPoP-Nonce", n)
+		}
+		jkt, err := s.VerifyTokenDPoP(ctx, r.Header.Values("DPoP"))
+		if err != nil {
+			writeOAuthError(w, http.StatusBadRequest, err)
+			return
+		}
+		ctx = services.WithDPoPKey(ctx, jkt)
+		ctx = services.WithClientCertificate(ctx, auth.Certificates)
+
+		var res *services.TokenResponse
 
 		switch strings.ToLower(req.GrantType) {
 		case "client_credentials":
-			res, err = s.IssueClientCredentials(ctx, req.ClientID, req.ClientSecret, req.Scope, req.CompanyID)
+			res, err = s.IssueClientCredentials(ctx, auth, req.Scope, req.CompanyID, req.Resource)
 		case "password":
-			res, err = s.IssuePassword(ctx, req.ClientID, req.ClientSecret, req.Username, req.Password, req.Scope, req.CompanyID)
+			res, err = s.IssuePassword(ctx, auth, req.Username, req.Password, req.Scope, req.CompanyID, req.Resource)
 		case "authorization_code":
-			res, err = s.ExchangeAuthorizationCode(ctx, req.ClientID, req.ClientSecret, req.Code, req.RedirectURI, req.CodeVerifier)
+			res, err = s.ExchangeAuthorizationCode(ctx, auth, req.Code, req.RedirectURI, req.CodeVerifier, req.Resource)
 		case "refresh_token":
-			res, err = s.Refresh(ctx, req.ClientID, req.RefreshToken)
+			res, err = s.Refresh(ctx, auth, req.RefreshToken, req.Scope, req.Resource)
+		case services.GrantTypeTokenExchange:
+			res, err = s.ExchangeToken(ctx, auth, services.TokenExchangeRequest{
+				SubjectToken:       req.SubjectToken,
+				SubjectTokenType:   req.SubjectTokenType,
+				ActorToken:         req.ActorToken,
+				ActorTokenType:     req.ActorTokenType,
+				RequestedTokenType: req.RequestedTokenType,
+				Audience:           req.Audience,
+				Resource:           req.Resource,
+				Scope:              req.Scope,
+			})
+		case services.GrantTypeDeviceCode:
+			res, err = s.ExchangeDeviceCode(ctx, auth, req.DeviceCode)
+		case services.GrantTypeMFAOTP:
+			res, err = s.CompleteMFA(ctx, auth, req.MFAToken, req.OTP, req.RecoveryCode)
 		default:
 			http.Error(w, "unsupported grant_type", http.StatusBadRequest)
 			return
 		}
 
-		if err != nil {
+		var mfaErr *services.MFARequiredError
+		var throttled *services.LoginThrottledError
+		var oe *services.OAuthError
+		switch {
+		case errors.As(err, &mfaErr):
+			writeMFARequired(w, mfaErr)
+			return
+		case errors.As(err, &throttled):
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			writeOAuthError(w, http.StatusTooManyRequests, &services.OAuthError{Code: "invalid_grant", Description: "too many failed login attempts"})
+			return
+		case errors.As(err, &oe):
+			writeOAuthError(w, oauthErrorStatus(oe), oe)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if strings.HasPrefix(ct, "application/json") {
+			writeNoStoreJSON(w, http.StatusOK, newLegacyTokenResponse(res))
+			return
+		}
+		writeNoStoreJSON(w, http.StatusOK, res)
+	}
+}
+
+// legacyTokenResponse — client lama yang mengirim request JSON (camelCase) tetap menerima
+// field camelCase di samping nama RFC 6749.
+type legacyTokenResponse struct {
+	*services.TokenResponse
+	LegacyAccessToken     string `json:"accessToken"`
+	LegacyTokenType       string `json:"tokenType"`
+	LegacyExpiresIn       int64  `json:"expiresIn"`
+	LegacyRefreshToken    string `json:"refreshToken,omitempty"`
+	LegacyIssuedTokenType string `json:"issuedTokenType,omitempty"`
+}
+
+func newLegacyTokenResponse(res *services.TokenResponse) legacyTokenResponse {
+	return legacyTokenResponse{
+		TokenResponse:         res,
+		LegacyAccessToken:     res.AccessToken,
+		LegacyTokenType:       res.TokenType,
+		LegacyExpiresIn:       res.ExpiresIn,
+		LegacyRefreshToken:    res.RefreshToken,
+		LegacyIssuedTokenType: res.IssuedTokenType,
+	}
+}
+
+/* ------------------------------
+   /oauth/mfa/challenge
+------------------------------ */
+
+// MakeMFAChallengeHandler mengirim OTP email untuk mfa_token (alternatif TOTP).
+func MakeMFAChallengeHandler(s *services.AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var mfaToken string
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			var body struct {
+				MFAToken string `json:"mfaToken"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(w, "invalid json", http.StatusBadRequest)
+				return
+			}
+			mfaToken = body.MFAToken
+		} else {
+			_ = r.ParseForm()
+			mfaToken = r.FormValue("mfa_token")
+		}
+		if strings.TrimSpace(mfaToken) == "" {
+			http.Error(w, "mfa_token required", http.StatusBadRequest)
+			return
+		}
+
+		err := s.SendMFAChallenge(r.Context(), mfaToken)
+		switch {
+		case errors.Is(err, services.ErrOTPResendTooSoon):
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		case err != nil:
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(res)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"challengeType": services.MFAMethodEmail,
+		})
 	}
 }
 
+func writeMFARequired(w http.ResponseWriter, e *services.MFARequiredError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error":       "mfa_required",
+		"mfa_token":   e.MFAToken,
+		"mfa_methods": e.Methods,
+		"expires_in":  e.ExpiresIn,
+	})
+}
+
 /* ------------------------------
    /oauth/introspect
 ------------------------------ */
@@ -215,8 +1002,8 @@ func MakeIntrospectHandler(s *services.AuthService) http.HandlerFunc {
 		ctx := r.Context()
 		_ = r.ParseForm()
 
-		cid, csec, ok := parseBasicAuth(r)
-		if !ok || verifyClient(ctx, s, cid, csec) != nil {
+		cid, err := verifyClient(ctx, s, r)
+		if err != nil {
 			w.Header().Set("WWW-Authenticate", `Basic realm="oauth2"`)
 			http.Error(w, "invalid_client", http.StatusUnauthorized)
 			return
@@ -251,8 +1038,7 @@ func MakeRevokeHandler(s *services.AuthService) http.HandlerFunc {
 		ctx := r.Context()
 		_ = r.ParseForm()
 
-		cid, csec, ok := parseBasicAuth(r)
-		if !ok || verifyClient(ctx, s, cid, csec) != nil {
+		if _, err := verifyClient(ctx, s, r); err != nil {
 			w.Header().Set("WWW-Authenticate", `Basic realm="oauth2"`)
 			http.Error(w, "invalid_client", http.StatusUnauthorized)
 			return
@@ -272,10 +1058,435 @@ func MakeRevokeHandler(s *services.AuthService) http.HandlerFunc {
 	}
 }
 
+/* ------------------------------
+   /oauth/userinfo
+------------------------------ */
+
+func MakeUserInfoHandler(s *services.AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scheme, token := accessToken(r)
+		if token == "" && r.Method == http.MethodPost {
+			_ = r.ParseForm()
+			scheme, token = "Bearer", r.PostFormValue("access_token")
+		}
+		if token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="oauth2"`)
+			http.Error(w, "invalid_token", http.StatusUnauthorized)
+			return
+		}
+
+		claims, err := s.UserInfo(r, scheme, token)
+		switch {
+		case errors.Is(err, services.ErrInvalidToken):
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		case errors.Is(err, services.ErrInvalidDPoPProof), errors.Is(err, services.ErrUseDPoPNonce):
+			writeInvalidToken(w, s, err)
+			return
+		case errors.Is(err, services.ErrInsufficientScope):
+			w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		case err != nil:
+			log.Printf("[/oauth/userinfo] err=%v", err)
+			http.Error(w, "server_error", http.StatusBadGateway)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(claims)
+	}
+}
+
+/* ------------------------------
+   /oauth/register (RFC 7591 / 7592)
+------------------------------ */
+
+// requireAdminScope — endpoint manajemen client hanya untuk access token ber-scope oauth:admin
+func requireAdminScope(s *services.AuthService, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scheme, token := accessToken(r)
+		if token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="oauth2"`)
+			writeOAuthError(w, http.StatusUnauthorized, services.ErrInvalidToken)
+			return
+		}
+		_, err := s.AuthenticateBearer(r, scheme, token, services.ScopeOAuthAdmin)
+		switch {
+		case errors.Is(err, services.ErrInsufficientScope):
+			w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+services.ScopeOAuthAdmin+`"`)
+			writeOAuthError(w, http.StatusForbidden, err)
+			return
+		case err != nil:
+			writeInvalidToken(w, s, err)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func MakeRegisterClientHandler(s *services.AuthService) http.HandlerFunc {
+	return requireAdminScope(s, func(w http.ResponseWriter, r *http.Request) {
+		var md services.ClientMetadata
+		if err := json.NewDecoder(r.Body).Decode(&md); err != nil {
+			writeOAuthError(w, http.StatusBadRequest, &services.OAuthError{Code: "invalid_client_metadata", Description: "invalid json"})
+			return
+		}
+		info, err := s.RegisterClient(r.Context(), md)
+		if err != nil {
+			writeClientError(w, "/oauth/register", err)
+			return
+		}
+		writeNoStoreJSON(w, http.StatusCreated, info)
+	})
+}
+
+func MakeGetClientHandler(s *services.AuthService) http.HandlerFunc {
+	return requireAdminScope(s, func(w http.ResponseWriter, r *http.Request) {
+		info, err := s.GetClient(r.Context(), mux.Vars(r)["client_id"])
+		if err != nil {
+			writeClientError(w, "/oauth/register", err)
+			return
+		}
+		writeNoStoreJSON(w, http.StatusOK, info)
+	})
+}
+
+func MakeUpdateClientHandler(s *services.AuthService) http.HandlerFunc {
+	return requireAdminScope(s, func(w http.ResponseWriter, r *http.Request) {
+		var md services.ClientMetadata
+		if err := json.NewDecoder(r.Body).Decode(&md); err != nil {
+			writeOAuthError(w, http.StatusBadRequest, &services.OAuthError{Code: "invalid_client_metadata", Description: "invalid json"})
+			return
+		}
+		info, err := s.UpdateClient(r.Context(), mux.Vars(r)["client_id"], md)
+		if err != nil {
+			writeClientError(w, "/oauth/register", err)
+			return
+		}
+		writeNoStoreJSON(w, http.StatusOK, info)
+	})
+}
+
+func MakeDeleteClientHandler(s *services.AuthService) http.HandlerFunc {
+	return requireAdminScope(s, func(w http.ResponseWriter, r *http.Request) {
+		if err := s.DeleteClient(r.Context(), mux.Vars(r)["client_id"]); err != nil {
+			writeClientError(w, "/oauth/register", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func MakeRotateClientSecretHandler(s *services.AuthService) http.HandlerFunc {
+	return requireAdminScope(s, func(w http.ResponseWriter, r *http.Request) {
+		info, err := s.RotateClientSecret(r.Context(), mux.Vars(r)["client_id"])
+		if err != nil {
+			writeClientError(w, "/oauth/register", err)
+			return
+		}
+		writeNoStoreJSON(w, http.StatusOK, info)
+	})
+}
+
+/* ------------------------------
+   /oauth/admin/audits — audit trail token
+------------------------------ */
+
+// MakeTokenAuditHandler — query audit per user / client / tenant / rentang waktu;
+// format=csv (atau Accept: text/csv) => export CSV tanpa paging.
+func MakeTokenAuditHandler(s *services.AuthService) http.HandlerFunc {
+	return requireAdminScope(s, func(w http.ResponseWriter, r *http.Request) {
+		qv := r.URL.Query()
+		q := services.TokenAuditQuery{
+			UserID:   qv.Get("user_id"),
+			ClientID: qv.Get("client_id"),
+			TenantID: qv.Get("tenant_id"),
+			Event:    qv.Get("event"),
+		}
+		var err error
+		if q.From, err = parseAuditTime(qv.Get("from")); err != nil {
+			writeOAuthError(w, http.StatusBadRequest, &services.OAuthError{Code: "invalid_request", Description: "invalid from"})
+			return
+		}
+		if q.To, err = parseAuditTime(qv.Get("to")); err != nil {
+			writeOAuthError(w, http.StatusBadRequest, &services.OAuthError{Code: "invalid_request", Description: "invalid to"})
+			return
+		}
+		q.Limit, _ = strconv.Atoi(qv.Get("limit"))
+		q.Offset, _ = strconv.Atoi(qv.Get("offset"))
+		export := qv.Get("format") == "csv" || strings.Contains(r.Header.Get("Accept"), "text/csv")
+
+		audits, err := s.QueryTokenAudits(r.Context(), q, export)
+		if err != nil {
+			writeClientError(w, "/oauth/admin/audits", err)
+			return
+		}
+		if !export {
+			writeNoStoreJSON(w, http.StatusOK, map[string]any{"audits": tokenAuditViews(audits)})
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="token-audits.csv"`)
+		w.Header().Set("Cache-Control", "no-store")
+		cw := csv.NewWriter(w)
+		_ = cw.Write([]string{"id", "event", "created_at", "tenant_id", "client_id", "user_id", "token_type", "grant_type", "ip", "user_agent", "correlation_id", "detail"})
+		for _, a := range audits {
+			_ = cw.Write([]string{a.ID, a.Event, a.CreatedAt.UTC().Format(time.RFC3339), deref(a.CompanyID), deref(a.ClientPublicID),
+				deref(a.UserID), a.TokenType, deref(a.GrantType), csvCell(deref(a.IP)), csvCell(deref(a.UserAgent)),
+				csvCell(deref(a.CorrelationID)), csvCell(deref(a.Detail))})
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			log.Printf("[/oauth/admin/audits] csv err=%v", err)
+		}
+	})
+}
+
+type tokenAuditView struct {
+	ID            string    `json:"id"`
+	Event         string    `json:"event"`
+	CreatedAt     time.Time `json:"createdAt"`
+	TenantID      string    `json:"tenantId,omitempty"`
+	ClientID      string    `json:"clientId,omitempty"`
+	UserID        string    `json:"userId,omitempty"`
+	TokenType     string    `json:"tokenType"`
+	GrantType     string    `json:"grantType,omitempty"`
+	IP            string    `json:"ip,omitempty"`
+	UserAgent     string    `json:"userAgent,omitempty"`
+	CorrelationID string    `json:"correlationId,omitempty"`
+	Detail        string    `json:"detail,omitempty"`
+}
+
+func tokenAuditViews(audits []*entities.TokenAudit) []tokenAuditView {
+	out := make([]tokenAuditView, 0, len(audits))
+	for _, a := range audits {
+		out = append(out, tokenAuditView{
+			ID: a.ID, Event: a.Event, CreatedAt: a.CreatedAt, TenantID: deref(a.CompanyID), ClientID: deref(a.ClientPublicID),
+			UserID: deref(a.UserID), TokenType: a.TokenType, GrantType: deref(a.GrantType), IP: deref(a.IP),
+			UserAgent: deref(a.UserAgent), CorrelationID: deref(a.CorrelationID), Detail: deref(a.Detail),
+		})
+	}
+	return out
+}
+
+// parseAuditTime menerima RFC 3339 atau tanggal (YYYY-MM-DD, UTC); kosong => zero time.
+func parseAuditTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", v)
+}
+
+// csvCell menetralkan nilai dari request (user agent dll.) yang bisa dibaca sebagai formula spreadsheet
+func csvCell(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
+
+func deref(p *string) string {
+	if p == nil {
+		return ""
+	}
+	return *p
+}
+
+/* ------------------------------
+   /oauth/admin/cleanup — janitor token
+------------------------------ */
+
+// MakeCleanupStatusHandler mengembalikan run janitor terakhir (dari replica mana pun).
+func MakeCleanupStatusHandler(s *services.AuthService) http.HandlerFunc {
+	return requireAdminScope(s, func(w http.ResponseWriter, r *http.Request) {
+		run, err := s.LastCleanupRun(r.Context())
+		if err != nil {
+			log.Printf("[/oauth/admin/cleanup] err=%v", err)
+			writeOAuthError(w, http.StatusInternalServerError, &services.OAuthError{Code: "server_error"})
+			return
+		}
+		writeNoStoreJSON(w, http.StatusOK, map[string]any{"lastRun": run})
+	})
+}
+
+// MakeCleanupTriggerHandler menjalankan janitor sekarang; 409 jika sedang berjalan.
+func MakeCleanupTriggerHandler(s *services.AuthService) http.HandlerFunc {
+	return requireAdminScope(s, func(w http.ResponseWriter, r *http.Request) {
+		run, err := s.CleanupExpired(r.Context(), "manual")
+		switch {
+		case errors.Is(err, services.ErrJanitorBusy):
+			writeOAuthError(w, http.StatusConflict, &services.OAuthError{Code: "cleanup_in_progress", Description: err.Error()})
+		case err != nil && run == nil:
+			log.Printf("[/oauth/admin/cleanup] err=%v", err)
+			writeOAuthError(w, http.StatusInternalServerError, &services.OAuthError{Code: "server_error"})
+		default:
+			// run parsial (error di tengah) tetap dikembalikan dengan completed=false
+			writeNoStoreJSON(w, http.StatusOK, run)
+		}
+	})
+}
+
+func writeClientError(w http.ResponseWriter, route string, err error) {
+	var oe *services.OAuthError
+	switch {
+	case errors.As(err, &oe):
+		writeOAuthError(w, http.StatusBadRequest, oe)
+	case errors.Is(err, services.ErrClientNotFound):
+		writeOAuthError(w, http.StatusNotFound, &services.OAuthError{Code: "invalid_client", Description: err.Error()})
+	default:
+		log.Printf("[%s] err=%v", route, err)
+		writeOAuthError(w, http.StatusInternalServerError, &services.OAuthError{Code: "server_error"})
+	}
+}
+
+/* ------------------------------
+   /oauth/consents
+------------------------------ */
+
+// consentOwner — user pemilik access token (token client_credentials ditolak).
+func consentOwner(s *services.AuthService, w http.ResponseWriter, r *http.Request) (string, bool) {
+	scheme, token := accessToken(r)
+	claims, err := s.AuthenticateBearer(r, scheme, token, "")
+	if err == nil && claims.UserID == "" {
+		err = services.ErrInvalidToken
+	}
+	if err != nil {
+		writeInvalidToken(w, s, err)
+		return "", false
+	}
+	return claims.UserID, true
+}
+
+func MakeListConsentsHandler(s *services.AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := consentOwner(s, w, r)
+		if !ok {
+			return
+		}
+		list, err := s.ListConsents(r.Context(), userID)
+		if err != nil {
+			log.Printf("[/oauth/consents] user=%s err=%v", userID, err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		writeNoStoreJSON(w, http.StatusOK, map[string]any{"consents": list})
+	}
+}
+
+func MakeRevokeConsentHandler(s *services.AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := consentOwner(s, w, r)
+		if !ok {
+			return
+		}
+		err := s.RevokeConsent(r.Context(), userID, mux.Vars(r)["id"])
+		switch {
+		case errors.Is(err, services.ErrConsentNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case err != nil:
+			log.Printf("[/oauth/consents] user=%s err=%v", userID, err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+}
+
+/* ------------------------------
+   /.well-known/openid-configuration
+------------------------------ */
+
+func MakeDiscoveryHandler(s *services.AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(s.Discovery())
+	}
+}
+
 /* ------------------------------
    Helpers
 ------------------------------ */
 
+// writeOAuthError menulis body error OAuth2 {"error", "error_description"}.
+func writeOAuthError(w http.ResponseWriter, status int, err error) {
+	body := map[string]string{"error": err.Error()}
+	var oe *services.OAuthError
+	if errors.As(err, &oe) {
+		body["error"] = oe.Code
+		if oe.Description != "" {
+			body["error_description"] = oe.Description
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// oauthErrorStatus — invalid_client 401 (RFC 6749 §5.2), selainnya 400
+func oauthErrorStatus(e *services.OAuthError) int {
+	if e.Code == "invalid_client" {
+		return http.StatusUnauthorized
+	}
+	return http.StatusBadRequest
+}
+
+// redirectWithParams menambahkan query (nilai kosong dilewati) ke redirect_uri lalu redirect 302.
+func redirectWithParams(w http.ResponseWriter, r *http.Request, redirectURI string, params map[string]string) {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	q := u.Query()
+	for k, v := range params {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	u.RawQuery = q.Encode()
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+func writeNoStoreJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// accessToken — skema ("Bearer" / "DPoP") dan access token dari header Authorization.
+func accessToken(r *http.Request) (string, string) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || (!strings.EqualFold(scheme, "Bearer") && !strings.EqualFold(scheme, "DPoP")) {
+		return "", ""
+	}
+	return scheme, strings.TrimSpace(token)
+}
+
+// writeInvalidToken — 401 untuk access token yang ditolak; proof DPoP yang gagal dijawab
+// dengan challenge DPoP (RFC 9449 §7.1) beserta nonce baru bila diwajibkan.
+func writeInvalidToken(w http.ResponseWriter, s *services.AuthService, err error) {
+	if !errors.Is(err, services.ErrInvalidDPoPProof) && !errors.Is(err, services.ErrUseDPoPNonce) {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		writeOAuthError(w, http.StatusUnauthorized, services.ErrInvalidToken)
+		return
+	}
+	var oe *services.OAuthError
+	errors.As(err, &oe)
+	w.Header().Set("WWW-Authenticate", `DPoP error="`+oe.Code+`", algs="`+strings.Join(sharedsec.DPoPSigningAlgs, " ")+`"`)
+	if n := s.DPoPNonce(); n != "" {
+		w.Header().Set("DPoP-Nonce", n)
+	}
+	writeOAuthError(w, http.StatusUnauthorized, err)
+}
+
 func parseBasicAuth(r *http.Request) (string, string, bool) {
 	ah := r.Header.Get("Authorization")
 	if !strings.HasPrefix(ah, "Basic ") {
@@ -293,16 +1504,34 @@ func parseBasicAuth(r *http.Request) (string, string, bool) {
 	return p[0], p[1], true
 }
 
-func verifyClient(ctx context.Context, s *services.AuthService, clientID, clientSecret string) error {
-	c, err := s.Dep().ClientRepo.FindByClientID(ctx, clientID)
-	if err != nil {
-		return err
+// clientAuthFromRequest melengkapi kredensial client dari header Basic (client_secret_basic)
+// dan form client_assertion (private_key_jwt / client_secret_jwt).
+func clientAuthFromRequest(r *http.Request, auth services.ClientAuth) services.ClientAuth {
+	if cid, csec, ok := parseBasicAuth(r); ok && auth.ClientID == "" && auth.ClientSecret == "" {
+		auth.ClientID, auth.ClientSecret = cid, csec
+	}
+	if auth.Assertion == "" {
+		auth.AssertionType = r.PostFormValue("client_assertion_type")
+		auth.Assertion = r.PostFormValue("client_assertion")
+	}
+	if r.TLS != nil {
+		auth.Certificates = r.TLS.PeerCertificates
 	}
-	if c.Secret == nil {
-		return errors.New("client has no secret")
+	return auth
+}
+
+// verifyClient mengautentikasi client confidential (introspect / revoke) dan mengembalikan client_id-nya.
+func verifyClient(ctx context.Context, s *services.AuthService, r *http.Request) (string, error) {
+	auth := clientAuthFromRequest(r, services.ClientAuth{
+		ClientID:     r.PostFormValue("client_id"),
+		ClientSecret: r.PostFormValue("client_secret"),
+	})
+	c, err := s.AuthenticateClient(ctx, auth)
+	if err != nil {
+		return "", err
 	}
-	if subtle.ConstantTimeCompare([]byte(*c.Secret), []byte(clientSecret)) != 1 {
-		return errors.New("invalid client secret")
+	if c.AuthMethod == services.AuthMethodNone {
+		return "", errors.New("client has no secret")
 	}
-	return nil
+	return c.ClientID, nil
 }
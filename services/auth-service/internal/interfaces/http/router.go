@@ -1,10 +1,11 @@
 package http
 
 import (
-	"encoding/base64"
+	"crypto/subtle"
 	"encoding/json"
-	"math/big"
+	"net"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -18,30 +19,57 @@ func NewRouter(s *services.AuthService) http.Handler {
 	r := mux.NewRouter()
 
 	r.Use(shhttp.Recovery)
+	r.Use(requestMeta(s.Dep().TrustedProxies))
 
 	rl := shmw.RateLimitSlidingWindow(s.Dep().RDB, "rl:auth:token", 60, time.Minute)
+	rlMFA := shmw.RateLimitSlidingWindow(s.Dep().RDB, "rl:auth:mfa", 10, time.Minute)
+	rlLogin := shmw.RateLimitSlidingWindow(s.Dep().RDB, "rl:auth:login", 10, time.Minute)
+	rlDevice := shmw.RateLimitSlidingWindow(s.Dep().RDB, "rl:auth:device", 20, time.Minute)
+	rlDeviceVerify := shmw.RateLimitSlidingWindow(s.Dep().RDB, "rl:auth:device_verify", 20, time.Minute)
 
 	// r.HandleFunc("/auth/login", LoginHandler(s)).Methods(http.MethodPost)
 
 	r.HandleFunc("/oauth/authorize", MakeAuthorizeHandler(s)).Methods(http.MethodGet, http.MethodPost)
+	r.Handle("/oauth/par", rl(http.HandlerFunc(MakePushedAuthorizationHandler(s)))).Methods(http.MethodPost)
+	r.HandleFunc("/oauth/login", MakeLoginPageHandler(s)).Methods(http.MethodGet)
+	r.Handle("/oauth/login", rlLogin(http.HandlerFunc(MakeLoginHandler(s)))).Methods(http.MethodPost)
 	r.Handle("/oauth/token", rl(http.HandlerFunc(MakeTokenHandler(s)))).Methods(http.MethodPost)
+	r.Handle("/oauth/device_authorization", rlDevice(http.HandlerFunc(MakeDeviceAuthorizationHandler(s)))).Methods(http.MethodPost)
+	r.Handle("/oauth/device", rlDeviceVerify(http.HandlerFunc(MakeDevicePageHandler(s)))).Methods(http.MethodGet)
+	r.Handle("/oauth/device", rlDeviceVerify(http.HandlerFunc(MakeDeviceDecisionHandler(s)))).Methods(http.MethodPost)
+	r.Handle("/oauth/mfa/challenge", rlMFA(http.HandlerFunc(MakeMFAChallengeHandler(s)))).Methods(http.MethodPost)
 	r.HandleFunc("/oauth/introspect", MakeIntrospectHandler(s)).Methods(http.MethodPost)
 	r.HandleFunc("/oauth/revoke", MakeRevokeHandler(s)).Methods(http.MethodPost)
+	r.HandleFunc("/oauth/userinfo", MakeUserInfoHandler(s)).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/oauth/register", MakeRegisterClientHandler(s)).Methods(http.MethodPost)
+	r.HandleFunc("/oauth/register/{client_id}", MakeGetClientHandler(s)).Methods(http.MethodGet)
+	r.HandleFunc("/oauth/register/{client_id}", MakeUpdateClientHandler(s)).Methods(http.MethodPut)
+	r.HandleFunc("/oauth/register/{client_id}", MakeDeleteClientHandler(s)).Methods(http.MethodDelete)
+	r.HandleFunc("/oauth/register/{client_id}/secret", MakeRotateClientSecretHandler(s)).Methods(http.MethodPost)
+	r.HandleFunc("/oauth/end_session", MakeEndSessionHandler(s)).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/oauth/logout_all", MakeLogoutAllHandler(s)).Methods(http.MethodPost)
+	r.HandleFunc("/oauth/admin/users/{user_id}/logout", MakeAdminLogoutUserHandler(s)).Methods(http.MethodPost)
+	r.HandleFunc("/oauth/admin/users/{user_id}/unlock", MakeAdminUnlockUserHandler(s)).Methods(http.MethodPost)
+	r.HandleFunc("/oauth/admin/audits", MakeTokenAuditHandler(s)).Methods(http.MethodGet)
+	r.HandleFunc("/oauth/admin/cleanup", MakeCleanupStatusHandler(s)).Methods(http.MethodGet)
+	r.HandleFunc("/oauth/admin/cleanup", MakeCleanupTriggerHandler(s)).Methods(http.MethodPost)
+	r.HandleFunc("/oauth/consents", MakeListConsentsHandler(s)).Methods(http.MethodGet)
+	r.HandleFunc("/oauth/consents/{id}", MakeRevokeConsentHandler(s)).Methods(http.MethodDelete)
+	r.HandleFunc("/.well-known/openid-configuration", MakeDiscoveryHandler(s)).Methods(http.MethodGet)
 
+	// key upcoming + active + retiring; verifier perlu mengenali key sebelum dipakai
 	r.HandleFunc("/oauth/jwks", func(w http.ResponseWriter, _ *http.Request) {
-		keys := make([]map[string]string, 0, len(s.Dep().KeyStore.Keys))
-		for kid, k := range s.Dep().KeyStore.Keys {
-			n := base64.RawURLEncoding.EncodeToString(k.Pub.N.Bytes())
-			e := base64.RawURLEncoding.EncodeToString(new(big.Int).SetInt64(int64(k.Pub.E)).Bytes())
-			keys = append(keys, map[string]string{
-				"kty": "RSA", "use": "sig", "alg": "RS256",
-				"n": n, "e": e, "kid": kid,
-			})
-		}
 		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+		// key baru dipublikasikan sebagai upcoming sebelum aktif, jadi cache 5 menit aman
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		_ = json.NewEncoder(w).Encode(s.Dep().KeyStore.JWKS())
 	}).Methods(http.MethodGet)
 
+	// service-to-service (user-service: revoke sesi setelah ganti / reset password)
+	internal := r.PathPrefix("/internal").Subrouter()
+	internal.Use(requireInternalKey)
+	internal.HandleFunc("/users/{user_id}/logout", MakeInternalLogoutUserHandler(s)).Methods(http.MethodPost)
+
 	r.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte(`{"status":"ok"}`))
@@ -49,3 +77,33 @@ func NewRouter(s *services.AuthService) http.Handler {
 
 	return r
 }
+
+// requestMeta menyematkan IP / user agent / correlation id ke context untuk audit trail;
+// RemoteIP (throttling) hanya mempercayai X-Forwarded-For dari proxy tepercaya (gateway)
+func requestMeta(trusted []*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := services.WithRequestMeta(r.Context(), services.RequestMeta{
+				IP:            shhttp.ForwardedIP(r),
+				RemoteIP:      shhttp.ClientIP(r, trusted),
+				UserAgent:     r.UserAgent(),
+				CorrelationID: r.Header.Get("X-Correlation-Id"),
+			})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// requireInternalKey membatasi /internal untuk service lain (header X-Internal-Api-Key ==
+// env INTERNAL_API_KEY, sama seperti user-service). Env kosong => semua ditolak.
+func requireInternalKey(next http.Handler) http.Handler {
+	key := os.Getenv("INTERNAL_API_KEY")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := r.Header.Get("X-Internal-Api-Key")
+		if key == "" || subtle.ConstantTimeCompare([]byte(got), []byte(key)) != 1 {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,5 @@
+ALTER TABLE oauth_access_tokens DROP COLUMN auth_time;
+
+ALTER TABLE oauth_auth_codes
+  DROP COLUMN auth_time,
+  DROP COLUMN nonce;
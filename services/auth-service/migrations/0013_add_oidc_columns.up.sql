@@ -0,0 +1,6 @@
+ALTER TABLE oauth_auth_codes
+  ADD COLUMN nonce     VARCHAR(255) NULL,
+  ADD COLUMN auth_time TIMESTAMP NULL;
+
+ALTER TABLE oauth_access_tokens
+  ADD COLUMN auth_time TIMESTAMP NULL;
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS oauth_security_events;
+
+DROP INDEX idx_ort_family ON oauth_refresh_tokens;
+
+ALTER TABLE oauth_refresh_tokens
+  DROP COLUMN rotated_at,
+  DROP COLUMN parent_id,
+  DROP COLUMN family_id;
@@ -0,0 +1,22 @@
+-- refresh token families: family_id = id refresh token pertama (NULL => token itu sendiri root)
+ALTER TABLE oauth_refresh_tokens
+  ADD COLUMN family_id  CHAR(36) NULL,
+  ADD COLUMN parent_id  CHAR(36) NULL,
+  ADD COLUMN rotated_at TIMESTAMP NULL;
+
+CREATE INDEX idx_ort_family ON oauth_refresh_tokens(family_id);
+
+CREATE TABLE IF NOT EXISTS oauth_security_events (
+  id         CHAR(36) PRIMARY KEY DEFAULT (UUID()),
+  event_type VARCHAR(64) NOT NULL,
+  company_id CHAR(36),
+  client_id  CHAR(36),
+  user_id    CHAR(36),
+  family_id  CHAR(36),
+  detail     TEXT,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  INDEX idx_ose_type (event_type),
+  INDEX idx_ose_user (user_id),
+  INDEX idx_ose_created (created_at)
+);
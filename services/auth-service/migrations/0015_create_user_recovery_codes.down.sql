@@ -0,0 +1,3 @@
+ALTER TABLE users MODIFY two_factor_secret VARCHAR(255);
+
+DROP TABLE IF EXISTS user_recovery_codes;
@@ -0,0 +1,15 @@
+-- kode pemulihan 2FA (hash SHA-256, sekali pakai)
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+  id         CHAR(36) PRIMARY KEY DEFAULT (UUID()),
+  user_id    CHAR(36) NOT NULL,
+  code_hash  CHAR(64) NOT NULL,
+  used_at    TIMESTAMP NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  CONSTRAINT uq_user_recovery_codes UNIQUE (user_id, code_hash),
+  CONSTRAINT fk_user_recovery_codes_user
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- two_factor_secret sekarang disimpan terenkripsi (AES-GCM, base64) sehingga lebih panjang
+ALTER TABLE users MODIFY two_factor_secret VARCHAR(512);
@@ -0,0 +1,9 @@
+ALTER TABLE oauth_clients
+  DROP COLUMN deleted_at,
+  DROP COLUMN updated_at,
+  DROP COLUMN refresh_token_ttl,
+  DROP COLUMN access_token_ttl,
+  DROP COLUMN token_endpoint_auth_method,
+  DROP COLUMN grant_types,
+  DROP COLUMN redirect_uris,
+  DROP COLUMN client_name;
@@ -0,0 +1,15 @@
+-- metadata client untuk dynamic client registration (RFC 7591/7592)
+ALTER TABLE oauth_clients
+  ADD COLUMN client_name                VARCHAR(255) NULL AFTER client_id,
+  ADD COLUMN redirect_uris              JSON NULL AFTER redirect_uri,
+  ADD COLUMN grant_types                TEXT NULL AFTER scopes,
+  ADD COLUMN token_endpoint_auth_method VARCHAR(50) NOT NULL DEFAULT 'client_secret_basic' AFTER grant_types,
+  ADD COLUMN access_token_ttl           INT NULL COMMENT 'detik; NULL = default server',
+  ADD COLUMN refresh_token_ttl          INT NULL COMMENT 'detik; NULL = default server',
+  ADD COLUMN updated_at                 TIMESTAMP NULL,
+  ADD COLUMN deleted_at                 TIMESTAMP NULL;
+
+-- redirect_uri tunggal lama dipindah ke redirect_uris
+UPDATE oauth_clients
+SET redirect_uris = JSON_ARRAY(redirect_uri)
+WHERE redirect_uri IS NOT NULL AND redirect_uri <> '' AND redirect_uris IS NULL;
@@ -0,0 +1,21 @@
+-- secret client disimpan sebagai hash bcrypt; maksimal dua aktif selama masa rotasi
+CREATE TABLE IF NOT EXISTS oauth_client_secrets (
+  id          CHAR(36) PRIMARY KEY DEFAULT (UUID()),
+  client_id   CHAR(36) NOT NULL,
+  secret_hash VARCHAR(255) NOT NULL,
+  created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  expires_at  TIMESTAMP NULL COMMENT 'NULL = tidak kedaluwarsa',
+  revoked_at  TIMESTAMP NULL,
+
+  INDEX idx_oauth_client_secrets_client (client_id, revoked_at),
+  CONSTRAINT fk_oauth_client_secrets_client
+    FOREIGN KEY (client_id) REFERENCES oauth_clients(id) ON DELETE CASCADE
+);
+
+-- client lama tanpa secret adalah public client
+UPDATE oauth_clients
+SET token_endpoint_auth_method = 'none'
+WHERE client_secret IS NULL OR client_secret = '';
+
+-- oauth_clients.client_secret (plaintext) tetap ada untuk data lama dan
+-- dikosongkan setelah di-hash saat pertama kali berhasil dipakai
@@ -0,0 +1,7 @@
+ALTER TABLE oauth_client_secrets
+  DROP COLUMN secret_sealed;
+
+ALTER TABLE oauth_clients
+  DROP COLUMN token_endpoint_auth_signing_alg,
+  DROP COLUMN jwks_uri,
+  DROP COLUMN jwks;
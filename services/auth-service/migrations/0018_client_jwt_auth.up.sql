@@ -0,0 +1,9 @@
+-- autentikasi client dengan JWT assertion (RFC 7523): private_key_jwt / client_secret_jwt
+ALTER TABLE oauth_clients
+  ADD COLUMN jwks                            JSON NULL AFTER token_endpoint_auth_method,
+  ADD COLUMN jwks_uri                        VARCHAR(512) NULL AFTER jwks,
+  ADD COLUMN token_endpoint_auth_signing_alg VARCHAR(10) NULL AFTER jwks_uri;
+
+-- client_secret_jwt butuh secret asli untuk verifikasi HMAC: disimpan terenkripsi (AES-GCM)
+ALTER TABLE oauth_client_secrets
+  ADD COLUMN secret_sealed TEXT NULL AFTER secret_hash;
@@ -0,0 +1,5 @@
+ALTER TABLE oauth_refresh_tokens
+  DROP COLUMN scopes;
+
+DROP TABLE IF EXISTS oauth_user_scopes;
+DROP TABLE IF EXISTS oauth_scopes;
@@ -0,0 +1,35 @@
+-- registry scope; restricted = hanya untuk user yang diberi grant di oauth_user_scopes
+CREATE TABLE IF NOT EXISTS oauth_scopes (
+  name        VARCHAR(100) PRIMARY KEY,
+  description VARCHAR(255) NULL,
+  restricted  TINYINT(1) NOT NULL DEFAULT 0,
+  created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS oauth_user_scopes (
+  user_id    CHAR(36) NOT NULL,
+  scope      VARCHAR(100) NOT NULL,
+  granted_by CHAR(36) NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (user_id, scope),
+  CONSTRAINT fk_oauth_user_scopes_user
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  CONSTRAINT fk_oauth_user_scopes_scope
+    FOREIGN KEY (scope) REFERENCES oauth_scopes(name) ON DELETE CASCADE
+);
+
+INSERT INTO oauth_scopes (name, description, restricted) VALUES
+  ('openid',            'OpenID Connect',                      0),
+  ('profile',           'Profil dasar user',                   0),
+  ('email',             'Alamat email',                        0),
+  ('phone',             'Nomor telepon',                       0),
+  ('offline_access',    'Refresh token',                       0),
+  ('profile:sensitive', 'Data profil sensitif',                1),
+  ('oauth:admin',       'Manajemen client OAuth',              1)
+ON DUPLICATE KEY UPDATE description = VALUES(description), restricted = VALUES(restricted);
+
+-- scope grant refresh token (NULL = sama dengan access token); access token hasil
+-- refresh boleh lebih sempit tanpa mengubah grant refresh token (RFC 6749 §6)
+ALTER TABLE oauth_refresh_tokens
+  ADD COLUMN scopes TEXT NULL AFTER token;
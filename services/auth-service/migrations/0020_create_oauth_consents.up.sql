@@ -0,0 +1,16 @@
+-- persetujuan (consent) user per client + tenant; company_id '' = tanpa tenant
+CREATE TABLE IF NOT EXISTS oauth_consents (
+  id         CHAR(36) PRIMARY KEY DEFAULT (UUID()),
+  user_id    CHAR(36) NOT NULL,
+  client_id  CHAR(36) NOT NULL,
+  company_id CHAR(36) NOT NULL DEFAULT '',
+  scopes     TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  UNIQUE KEY uq_oauth_consents (user_id, client_id, company_id),
+  CONSTRAINT fk_oauth_consents_user
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  CONSTRAINT fk_oauth_consents_client
+    FOREIGN KEY (client_id) REFERENCES oauth_clients(id) ON DELETE CASCADE
+);
@@ -0,0 +1 @@
+DELETE FROM oauth_scopes WHERE name = 'support:impersonate';
@@ -0,0 +1,4 @@
+-- scope terbatas untuk support staff: token exchange mode impersonasi (selalu diaudit)
+INSERT INTO oauth_scopes (name, description, restricted) VALUES
+  ('support:impersonate', 'Impersonasi user oleh support staff', 1)
+ON DUPLICATE KEY UPDATE description = VALUES(description), restricted = VALUES(restricted);
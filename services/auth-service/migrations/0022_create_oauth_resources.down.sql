@@ -0,0 +1,3 @@
+ALTER TABLE oauth_refresh_tokens DROP COLUMN resources;
+ALTER TABLE oauth_auth_codes DROP COLUMN resources;
+DROP TABLE IF EXISTS oauth_resources;
@@ -0,0 +1,19 @@
+-- registry protected resource (RFC 8707); identifier = nilai parameter "resource"
+-- sekaligus klaim "aud" token; scopes = scope yang diterima resource (dipisah spasi)
+CREATE TABLE IF NOT EXISTS oauth_resources (
+  identifier VARCHAR(255) PRIMARY KEY,
+  name       VARCHAR(100) NOT NULL,
+  scopes     TEXT NOT NULL,
+  is_active  TINYINT(1) NOT NULL DEFAULT 1,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- resource yang disetujui saat authorize / grant refresh token (dipisah spasi)
+ALTER TABLE oauth_auth_codes
+  ADD COLUMN resources TEXT NULL;
+ALTER TABLE oauth_refresh_tokens
+  ADD COLUMN resources TEXT NULL AFTER scopes;
+
+INSERT INTO oauth_resources (identifier, name, scopes) VALUES
+  ('https://api.bkc.local/user', 'User API (api-gateway)', 'profile email phone profile:sensitive')
+ON DUPLICATE KEY UPDATE name = VALUES(name), scopes = VALUES(scopes);
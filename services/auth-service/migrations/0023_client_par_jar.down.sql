@@ -0,0 +1,4 @@
+ALTER TABLE oauth_clients
+  DROP COLUMN request_object_signing_alg,
+  DROP COLUMN require_signed_request_object,
+  DROP COLUMN require_pushed_authorization_requests;
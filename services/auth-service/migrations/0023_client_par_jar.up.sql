@@ -0,0 +1,5 @@
+-- Pushed Authorization Requests (RFC 9126) & JWT-secured authorization request (RFC 9101)
+ALTER TABLE oauth_clients
+  ADD COLUMN require_pushed_authorization_requests TINYINT(1) NOT NULL DEFAULT 0 AFTER token_endpoint_auth_signing_alg,
+  ADD COLUMN require_signed_request_object         TINYINT(1) NOT NULL DEFAULT 0 AFTER require_pushed_authorization_requests,
+  ADD COLUMN request_object_signing_alg            VARCHAR(10) NULL AFTER require_signed_request_object;
@@ -0,0 +1,5 @@
+ALTER TABLE oauth_refresh_tokens
+  DROP COLUMN dpop_jkt;
+
+ALTER TABLE oauth_clients
+  DROP COLUMN dpop_bound_access_tokens;
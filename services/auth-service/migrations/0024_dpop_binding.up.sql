@@ -0,0 +1,6 @@
+-- DPoP (RFC 9449): client yang selalu memakai DPoP + binding refresh token ke key DPoP
+ALTER TABLE oauth_clients
+  ADD COLUMN dpop_bound_access_tokens TINYINT(1) NOT NULL DEFAULT 0 AFTER request_object_signing_alg;
+
+ALTER TABLE oauth_refresh_tokens
+  ADD COLUMN dpop_jkt VARCHAR(64) NULL AFTER resources;
@@ -0,0 +1,7 @@
+ALTER TABLE oauth_refresh_tokens
+  DROP COLUMN cert_x5t;
+
+ALTER TABLE oauth_clients
+  DROP COLUMN tls_client_certificate_bound_access_tokens,
+  DROP COLUMN tls_client_auth_value,
+  DROP COLUMN tls_client_auth_attr;
@@ -0,0 +1,8 @@
+-- Mutual-TLS client authentication & certificate-bound token (RFC 8705)
+ALTER TABLE oauth_clients
+  ADD COLUMN tls_client_auth_attr                       VARCHAR(20)  NULL AFTER dpop_bound_access_tokens,
+  ADD COLUMN tls_client_auth_value                      VARCHAR(512) NULL AFTER tls_client_auth_attr,
+  ADD COLUMN tls_client_certificate_bound_access_tokens TINYINT(1)   NOT NULL DEFAULT 0 AFTER tls_client_auth_value;
+
+ALTER TABLE oauth_refresh_tokens
+  ADD COLUMN cert_x5t VARCHAR(64) NULL AFTER dpop_jkt;
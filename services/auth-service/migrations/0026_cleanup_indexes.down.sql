@@ -0,0 +1 @@
+DROP INDEX idx_oac_expires ON oauth_auth_codes;
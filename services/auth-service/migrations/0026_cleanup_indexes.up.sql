@@ -0,0 +1,2 @@
+-- janitor token: DELETE auth code kedaluwarsa per batch
+CREATE INDEX idx_oac_expires ON oauth_auth_codes(expires_at);
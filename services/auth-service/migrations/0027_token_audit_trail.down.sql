@@ -0,0 +1,13 @@
+DROP INDEX idx_ota_created         ON oauth_token_audits;
+DROP INDEX idx_ota_company_created ON oauth_token_audits;
+DROP INDEX idx_ota_client_created  ON oauth_token_audits;
+DROP INDEX idx_ota_user_created    ON oauth_token_audits;
+
+ALTER TABLE oauth_token_audits
+  DROP COLUMN detail,
+  DROP COLUMN correlation_id,
+  DROP COLUMN grant_type,
+  DROP COLUMN event;
+
+ALTER TABLE oauth_token_audits
+  RENAME COLUMN created_at TO issued_at;
@@ -0,0 +1,14 @@
+-- audit trail token: penerbitan, refresh, revocation, introspection gagal
+ALTER TABLE oauth_token_audits
+  RENAME COLUMN issued_at TO created_at;
+
+ALTER TABLE oauth_token_audits
+  ADD COLUMN event          VARCHAR(32)  NOT NULL DEFAULT 'issued' AFTER id,
+  ADD COLUMN grant_type     VARCHAR(100) NULL AFTER token_type,
+  ADD COLUMN correlation_id VARCHAR(64)  NULL AFTER user_agent,
+  ADD COLUMN detail         TEXT         NULL AFTER correlation_id;
+
+CREATE INDEX idx_ota_user_created    ON oauth_token_audits(user_id, created_at);
+CREATE INDEX idx_ota_client_created  ON oauth_token_audits(client_id, created_at);
+CREATE INDEX idx_ota_company_created ON oauth_token_audits(company_id, created_at);
+CREATE INDEX idx_ota_created         ON oauth_token_audits(created_at);
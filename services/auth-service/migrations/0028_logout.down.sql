@@ -0,0 +1,8 @@
+ALTER TABLE oauth_clients
+  DROP COLUMN backchannel_logout_uri,
+  DROP COLUMN post_logout_redirect_uris;
+
+DROP INDEX idx_oat_user_revoked ON oauth_access_tokens;
+
+ALTER TABLE oauth_access_tokens
+  DROP COLUMN jti;
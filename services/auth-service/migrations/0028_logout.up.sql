@@ -0,0 +1,9 @@
+-- logout: jti access token (denylist Redis) + metadata logout client (OIDC RP-Initiated / Back-Channel Logout)
+ALTER TABLE oauth_access_tokens
+  ADD COLUMN jti VARCHAR(64) NULL AFTER token;
+
+CREATE INDEX idx_oat_user_revoked ON oauth_access_tokens(user_id, revoked);
+
+ALTER TABLE oauth_clients
+  ADD COLUMN post_logout_redirect_uris TEXT NULL AFTER redirect_uris,
+  ADD COLUMN backchannel_logout_uri    TEXT NULL AFTER post_logout_redirect_uris;
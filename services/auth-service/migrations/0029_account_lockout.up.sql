@@ -0,0 +1,3 @@
+-- lockout akun password grant: is_locked + locked_until (NULL => dikunci admin, tanpa auto-unlock)
+ALTER TABLE users
+  ADD COLUMN locked_until DATETIME NULL AFTER is_locked;
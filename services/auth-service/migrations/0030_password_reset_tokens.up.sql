@@ -0,0 +1,14 @@
+-- token reset password (hash SHA-256, sekali pakai, kedaluwarsa); satu token aktif per user
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+  id         CHAR(36) PRIMARY KEY DEFAULT (UUID()),
+  user_id    CHAR(36) NOT NULL,
+  token_hash CHAR(64) NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  used_at    TIMESTAMP NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  CONSTRAINT uq_password_reset_tokens_hash UNIQUE (token_hash),
+  INDEX idx_password_reset_tokens_user (user_id),
+  CONSTRAINT fk_password_reset_tokens_user
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -13,6 +13,8 @@ import (
 	shcfg "bkc_microservice/shared/config"
 	shdb "bkc_microservice/shared/database"
 	shhttp "bkc_microservice/shared/http"
+	shnotify "bkc_microservice/shared/notify"
+	shsec "bkc_microservice/shared/security"
 
 	appsvc "bkc_microservice/services/user-service/internal/application/services"
 	"bkc_microservice/services/user-service/internal/infrastructure/clients"
@@ -69,6 +71,14 @@ func main() {
 	profileRepo := persistence.NewMySQLUserProfileRepository(pool)
 	settingsRepo := persistence.NewMySQLUserSettingsRepository(pool)
 	rpRepo := persistence.NewMySQLRolePermissionsRepository(pool)
+	recoveryRepo := persistence.NewMySQLRecoveryCodeRepository(pool)
+	resetRepo := persistence.NewMySQLPasswordResetRepository(pool)
+
+	// === 2FA secret encryption key (32 byte, base64) ===
+	secretBox, err := shsec.NewSecretBoxFromBase64(os.Getenv("MFA_ENCRYPTION_KEY"))
+	if err != nil {
+		log.Fatalf("invalid MFA_ENCRYPTION_KEY: %v", err)
+	}
 
 	// === Setup Services ===
 	// User Service
@@ -97,11 +107,44 @@ func main() {
 		rpRepo,
 	)
 
+	// Two Factor Service
+	twoFactorService := appsvc.NewTwoFactorService(
+		userRepo,
+		settingsRepo,
+		recoveryRepo,
+		secretBox,
+		envOr("MFA_ISSUER", "BKC"),
+		rdb,
+	)
+
+	// Credential Service (login auth-service)
+	credentialService := appsvc.NewCredentialService(
+		userRepo,
+		appsvc.NewPasswordService(),
+	)
+
+	// Account Password Service (ganti / lupa / reset password); sesi dicabut lewat auth-service
+	passwordService := appsvc.NewAccountPasswordService(
+		userRepo,
+		resetRepo,
+		appsvc.NewPasswordService(),
+		shnotify.NewLogNotifier(),
+		clients.NewAuthServiceClient(envOr("AUTH_SERVICE_URL", "http://auth-service:9001"), os.Getenv("INTERNAL_API_KEY")),
+		appsvc.PasswordResetConfig{
+			URL:      envOr("PASSWORD_RESET_URL", "http://localhost:3000/reset-password"),
+			TokenTTL: parseDurationOr(os.Getenv("PASSWORD_RESET_TTL"), 30*time.Minute),
+		},
+		rdb,
+	)
+
 	// === Setup HTTP Router & Middlewares ===
 	router := httpif.NewRouter(
 		userService,
 		roleService,
 		permService,
+		twoFactorService,
+		credentialService,
+		passwordService,
 		logger,
 		rdb,
 	)
@@ -142,3 +185,17 @@ func main() {
 	}
 	log.Println("user-service stopped cleanly")
 }
+
+func envOr(k, def string) string {
+	if v := os.Getenv(k); v != "" {
+		return v
+	}
+	return def
+}
+
+func parseDurationOr(v string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(v); err == nil && d > 0 {
+		return d
+	}
+	return def
+}
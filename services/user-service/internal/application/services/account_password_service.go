@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"bkc_microservice/services/user-service/internal/domain/repositories"
+	"bkc_microservice/shared/notify"
+	"bkc_microservice/shared/validation"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrInvalidResetToken = errors.New("invalid or expired reset token")
+	ErrPasswordUnchanged = errors.New("new password must differ from current password")
+	ErrWeakPassword      = errors.New("weak password")
+)
+
+const (
+	defaultResetTokenTTL = 30 * time.Minute
+	resetRequestCooldown = time.Minute // email reset paling sering sekali per menit per user
+)
+
+// SessionRevoker mencabut semua token & sesi login user (auth-service /internal/users/{id}/logout)
+type SessionRevoker interface {
+	RevokeUserSessions(ctx context.Context, userID string) error
+}
+
+// PasswordResetConfig — link di email reset: URL + "?token=<token>"
+type PasswordResetConfig struct {
+	URL      string
+	TokenTTL time.Duration
+}
+
+// AccountPasswordService mengelola ganti password (/me/password) dan lupa / reset password
+// (/password/forgot, /password/reset). Setiap perubahan password mencabut semua sesi user.
+type AccountPasswordService interface {
+	ChangePassword(ctx context.Context, userID string, req *ChangePasswordRequest) error
+	RequestReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, req *ResetPasswordRequest) error
+}
+
+type accountPasswordServiceImpl struct {
+	userRepo  repositories.UserRepository
+	resetRepo repositories.PasswordResetRepository
+	passwords PasswordService
+	validator *validation.Validator
+	notifier  notify.Notifier
+	sessions  SessionRevoker
+	cfg       PasswordResetConfig
+	rdb       *redis.Client
+}
+
+func NewAccountPasswordService(
+	userRepo repositories.UserRepository,
+	resetRepo repositories.PasswordResetRepository,
+	passwords PasswordService,
+	notifier notify.Notifier,
+	sessions SessionRevoker,
+	cfg PasswordResetConfig,
+	rdb *redis.Client,
+) AccountPasswordService {
+	if cfg.TokenTTL <= 0 {
+		cfg.TokenTTL = defaultResetTokenTTL
+	}
+	return &accountPasswordServiceImpl{
+		userRepo:  userRepo,
+		resetRepo: resetRepo,
+		passwords: passwords,
+		validator: validation.NewValidator(),
+		notifier:  notifier,
+		sessions:  sessions,
+		cfg:       cfg,
+		rdb:       rdb,
+	}
+}
+
+// ChangePassword mewajibkan password saat ini; token reset yang masih berlaku ikut dibatalkan
+func (s *accountPasswordServiceImpl) ChangePassword(ctx context.Context, userID string, req *ChangePasswordRequest) error {
+	user, err := s.userRepo.FindCredentialsByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !s.passwords.VerifyPassword(req.CurrentPassword, user.PasswordHash) {
+		return ErrInvalidPassword
+	}
+	if req.NewPassword == req.CurrentPassword {
+		return ErrPasswordUnchanged
+	}
+	if err := s.setPassword(ctx, userID, req.NewPassword); err != nil {
+		return err
+	}
+	if err := s.resetRepo.DeleteAll(ctx, userID); err != nil {
+		log.Printf("[AccountPasswordService] Failed to delete reset tokens for %s: %v", userID, err)
+	}
+	log.Printf("[AccountPasswordService] Password changed for user %s", userID)
+	return nil
+}
+
+// RequestReset selalu sukses untuk email yang tidak dikenal supaya keberadaan akun tidak bocor
+func (s *accountPasswordServiceImpl) RequestReset(ctx context.Context, email string) error {
+	user, err := s.userRepo.FindByEmail(strings.TrimSpace(email))
+	if err != nil {
+		if err.Error() == "user not found" {
+			return nil
+		}
+		return err
+	}
+
+	if s.rdb != nil {
+		ok, err := s.rdb.SetNX(ctx, "pwreset:cooldown:"+user.ID, 1, resetRequestCooldown).Result()
+		if err == nil && !ok {
+			return nil
+		}
+	}
+
+	token, err := newResetToken()
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(s.cfg.TokenTTL)
+	if err := s.resetRepo.Replace(ctx, user.ID, hashResetToken(token), expiresAt); err != nil {
+		return err
+	}
+
+	msg := notify.Message{
+		To:      user.Email,
+		Subject: "Reset password",
+		Body: fmt.Sprintf("Buka link berikut untuk membuat password baru (berlaku %d menit):\n%s\n\nAbaikan email ini jika Anda tidak meminta reset password.",
+			int(s.cfg.TokenTTL.Minutes()), s.resetLink(token)),
+	}
+	if err := s.notifier.Send(ctx, msg); err != nil {
+		// jangan bocorkan ke pemanggil; user bisa meminta ulang setelah cooldown
+		log.Printf("[AccountPasswordService] Failed to send reset email for %s: %v", user.ID, err)
+	}
+	return nil
+}
+
+// ResetPassword memakai token sekali pakai dari RequestReset
+func (s *accountPasswordServiceImpl) ResetPassword(ctx context.Context, req *ResetPasswordRequest) error {
+	if err := s.validator.ValidatePassword(req.NewPassword); err != nil {
+		return fmt.Errorf("%w: %v", ErrWeakPassword, err)
+	}
+	userID, err := s.resetRepo.Consume(ctx, hashResetToken(strings.TrimSpace(req.Token)))
+	if err != nil {
+		return err
+	}
+	if userID == "" {
+		return ErrInvalidResetToken
+	}
+	if err := s.setPassword(ctx, userID, req.NewPassword); err != nil {
+		return err
+	}
+	if err := s.resetRepo.DeleteAll(ctx, userID); err != nil {
+		log.Printf("[AccountPasswordService] Failed to delete reset tokens for %s: %v", userID, err)
+	}
+	log.Printf("[AccountPasswordService] Password reset for user %s", userID)
+	return nil
+}
+
+// setPassword menyimpan hash baru (+ last_password_change) lalu mencabut semua sesi user
+func (s *accountPasswordServiceImpl) setPassword(ctx context.Context, userID, password string) error {
+	if err := s.validator.ValidatePassword(password); err != nil {
+		return fmt.Errorf("%w: %v", ErrWeakPassword, err)
+	}
+	hash, err := s.passwords.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdatePassword(ctx, userID, hash); err != nil {
+		return err
+	}
+
+	if s.rdb != nil {
+		_ = s.rdb.Del(ctx, userBundleCacheKey(userID)).Err()
+	}
+	if s.sessions != nil {
+		// password sudah tersimpan; kegagalan revoke hanya di-log agar bisa ditindaklanjuti
+		if err := s.sessions.RevokeUserSessions(ctx, userID); err != nil {
+			log.Printf("[AccountPasswordService] Failed to revoke sessions for %s: %v", userID, err)
+		}
+	}
+	return nil
+}
+
+func (s *accountPasswordServiceImpl) resetLink(token string) string {
+	sep := "?"
+	if strings.Contains(s.cfg.URL, "?") {
+		sep = "&"
+	}
+	return s.cfg.URL + sep + "token=" + url.QueryEscape(token)
+}
+
+func newResetToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashResetToken — yang disimpan hanya SHA-256 hex token
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"bkc_microservice/services/user-service/internal/domain/repositories"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrAccountLocked      = errors.New("account locked")
+)
+
+// CredentialService memverifikasi login user untuk auth-service (/internal/users/authenticate)
+type CredentialService interface {
+	Authenticate(ctx context.Context, login, password string) (*AuthenticatedUserResponse, error)
+}
+
+type credentialServiceImpl struct {
+	userRepo  repositories.UserRepository
+	passwords PasswordService
+}
+
+func NewCredentialService(userRepo repositories.UserRepository, passwords PasswordService) CredentialService {
+	return &credentialServiceImpl{
+		userRepo:  userRepo,
+		passwords: passwords,
+	}
+}
+
+// Authenticate mencocokkan email/username + password. User tidak ditemukan dan password
+// salah sama-sama menghasilkan ErrInvalidCredentials agar tidak membocorkan akun yang ada.
+func (s *credentialServiceImpl) Authenticate(ctx context.Context, login, password string) (*AuthenticatedUserResponse, error) {
+	user, err := s.userRepo.FindCredentialsByLogin(ctx, login)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	if user.IsLocked {
+		return nil, ErrAccountLocked
+	}
+
+	if !s.passwords.VerifyPassword(password, user.PasswordHash) {
+		if err := s.userRepo.UpdateLoginAttempts(ctx, user.ID, user.FailedLoginAttempts+1); err != nil {
+			log.Printf("[CredentialService] update login attempts failed user=%s: %v", user.ID, err)
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := s.userRepo.UpdateLastLogin(ctx, user.ID); err != nil {
+		log.Printf("[CredentialService] update last login failed user=%s: %v", user.ID, err)
+	}
+
+	return &AuthenticatedUserResponse{
+		ID:               user.ID,
+		Username:         user.Username,
+		Email:            user.Email,
+		RoleID:           user.RoleID,
+		TwoFactorEnabled: user.TwoFactorEnabled,
+	}, nil
+}
@@ -51,6 +51,38 @@ type AssignPermissionsRequest struct {
 	PermissionIDs []int `json:"permissionIds" validate:"required,min=1"`
 }
 
+type ConfirmTwoFactorRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"` // TOTP atau recovery code
+}
+
+type RegenerateRecoveryCodesRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type AuthenticateRequest struct {
+	Login    string `json:"login"` // email atau username
+	Password string `json:"password" validate:"required"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"required,min=8"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"newPassword" validate:"required,min=8"`
+}
+
 // ==================== RESPONSE DTOs ====================
 
 type UserResponse struct {
@@ -98,3 +130,27 @@ type PaginatedResponse struct {
 	Page  int         `json:"page"`
 	Size  int         `json:"size"`
 }
+
+type TwoFactorStatusResponse struct {
+	Enabled                bool `json:"enabled"`
+	PendingEnrollment      bool `json:"pendingEnrollment"`
+	RecoveryCodesRemaining int  `json:"recoveryCodesRemaining"`
+}
+
+type TwoFactorEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauthUrl"`
+	QRCodePNG  string `json:"qrCodePng"` // base64 PNG
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+type AuthenticatedUserResponse struct {
+	ID               string `json:"id"`
+	Username         string `json:"username"`
+	Email            string `json:"email"`
+	RoleID           int    `json:"roleId"`
+	TwoFactorEnabled bool   `json:"twoFactorEnabled"`
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+
+	"bkc_microservice/services/user-service/internal/domain/repositories"
+	"bkc_microservice/services/user-service/internal/infrastructure/persistence"
+	"bkc_microservice/shared/mfa"
+	sharedsec "bkc_microservice/shared/security"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrTwoFactorAlreadyEnabled = errors.New("two factor already enabled")
+	ErrTwoFactorNotEnabled     = errors.New("two factor not enabled")
+	ErrTwoFactorNotPending     = errors.New("two factor enrollment not started")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two factor code")
+	ErrInvalidPassword         = errors.New("invalid password")
+)
+
+const qrCodeSize = 256
+
+// TwoFactorService mengelola enrollment TOTP dan recovery code untuk /me/2fa
+type TwoFactorService interface {
+	Status(ctx context.Context, userID string) (*TwoFactorStatusResponse, error)
+	StartEnrollment(ctx context.Context, userID string) (*TwoFactorEnrollResponse, error)
+	ConfirmEnrollment(ctx context.Context, userID, code string) (*RecoveryCodesResponse, error)
+	Disable(ctx context.Context, userID string, req *DisableTwoFactorRequest) error
+	RegenerateRecoveryCodes(ctx context.Context, userID, code string) (*RecoveryCodesResponse, error)
+}
+
+type twoFactorServiceImpl struct {
+	userRepo     repositories.UserRepository
+	settingsRepo repositories.UserSettingsRepository
+	recoveryRepo repositories.RecoveryCodeRepository
+	totp         *mfa.TOTPService
+	box          *sharedsec.SecretBox
+	issuer       string
+	RedisClient  *redis.Client
+}
+
+func NewTwoFactorService(
+	userRepo repositories.UserRepository,
+	settingsRepo repositories.UserSettingsRepository,
+	recoveryRepo repositories.RecoveryCodeRepository,
+	box *sharedsec.SecretBox,
+	issuer string,
+	redisClient *redis.Client,
+) TwoFactorService {
+	return &twoFactorServiceImpl{
+		userRepo:     userRepo,
+		settingsRepo: settingsRepo,
+		recoveryRepo: recoveryRepo,
+		totp:         &mfa.TOTPService{},
+		box:          box,
+		issuer:       issuer,
+		RedisClient:  redisClient,
+	}
+}
+
+func (s *twoFactorServiceImpl) Status(ctx context.Context, userID string) (*TwoFactorStatusResponse, error) {
+	user, err := s.userRepo.FindCredentialsByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &TwoFactorStatusResponse{
+		Enabled:           user.TwoFactorEnabled,
+		PendingEnrollment: !user.TwoFactorEnabled && user.TwoFactorSecret != nil && *user.TwoFactorSecret != "",
+	}
+	if user.TwoFactorEnabled {
+		n, err := s.recoveryRepo.CountUnused(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		resp.RecoveryCodesRemaining = n
+	}
+	return resp, nil
+}
+
+// StartEnrollment membuat secret baru (disimpan terenkripsi, belum aktif sampai dikonfirmasi)
+func (s *twoFactorServiceImpl) StartEnrollment(ctx context.Context, userID string) (*TwoFactorEnrollResponse, error) {
+	user, err := s.userRepo.FindCredentialsByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, otpURL, err := s.totp.GenerateSecret(user.Email, s.issuer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+	png, err := s.totp.QRCodePNG(otpURL, qrCodeSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render qr code: %w", err)
+	}
+
+	sealed, err := s.box.Seal(secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt secret: %w", err)
+	}
+	if err := s.userRepo.UpdateTwoFactor(ctx, userID, false, &sealed); err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorEnrollResponse{
+		Secret:     secret,
+		OTPAuthURL: otpURL,
+		QRCodePNG:  base64.StdEncoding.EncodeToString(png),
+	}, nil
+}
+
+// ConfirmEnrollment mengaktifkan 2FA setelah kode pertama valid dan mengembalikan recovery code
+func (s *twoFactorServiceImpl) ConfirmEnrollment(ctx context.Context, userID, code string) (*RecoveryCodesResponse, error) {
+	user, err := s.userRepo.FindCredentialsByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TwoFactorSecret == nil || *user.TwoFactorSecret == "" {
+		return nil, ErrTwoFactorNotPending
+	}
+
+	secret, err := s.box.Open(*user.TwoFactorSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+	if !s.totp.Validate(code, secret) {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	if err := s.userRepo.UpdateTwoFactor(ctx, userID, true, user.TwoFactorSecret); err != nil {
+		return nil, err
+	}
+	s.syncSettings(ctx, userID, true)
+
+	return s.issueRecoveryCodes(ctx, userID)
+}
+
+// Disable mematikan 2FA; butuh password dan kode TOTP / recovery code
+func (s *twoFactorServiceImpl) Disable(ctx context.Context, userID string, req *DisableTwoFactorRequest) error {
+	user, err := s.userRepo.FindCredentialsByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)) != nil {
+		return ErrInvalidPassword
+	}
+	if !user.TwoFactorEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	ok, err := s.verifyCode(ctx, userID, user.TwoFactorSecret, req.Code, true)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+
+	if err := s.userRepo.UpdateTwoFactor(ctx, userID, false, nil); err != nil {
+		return err
+	}
+	if err := s.recoveryRepo.DeleteAll(ctx, userID); err != nil {
+		return err
+	}
+	s.syncSettings(ctx, userID, false)
+	return nil
+}
+
+// RegenerateRecoveryCodes mengganti seluruh recovery code (kode lama tidak berlaku)
+func (s *twoFactorServiceImpl) RegenerateRecoveryCodes(ctx context.Context, userID, code string) (*RecoveryCodesResponse, error) {
+	user, err := s.userRepo.FindCredentialsByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TwoFactorEnabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	ok, err := s.verifyCode(ctx, userID, user.TwoFactorSecret, code, false)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	return s.issueRecoveryCodes(ctx, userID)
+}
+
+func (s *twoFactorServiceImpl) issueRecoveryCodes(ctx context.Context, userID string) (*RecoveryCodesResponse, error) {
+	codes, err := mfa.GenerateRecoveryCodes(mfa.RecoveryCodeCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+	}
+	hashes := make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = mfa.HashRecoveryCode(c)
+	}
+	if err := s.recoveryRepo.ReplaceAll(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// verifyCode menerima TOTP; jika allowRecovery, recovery code juga diterima (dan dipakai habis)
+func (s *twoFactorServiceImpl) verifyCode(ctx context.Context, userID string, sealed *string, code string, allowRecovery bool) (bool, error) {
+	if code == "" {
+		return false, nil
+	}
+	if sealed != nil && *sealed != "" {
+		secret, err := s.box.Open(*sealed)
+		if err != nil {
+			return false, fmt.Errorf("failed to decrypt secret: %w", err)
+		}
+		if s.totp.Validate(code, secret) {
+			return true, nil
+		}
+	}
+	if !allowRecovery {
+		return false, nil
+	}
+	return s.recoveryRepo.Consume(ctx, userID, mfa.HashRecoveryCode(code))
+}
+
+// syncSettings menjaga user_settings.two_fa_enabled sama dengan users.two_factor_enabled
+func (s *twoFactorServiceImpl) syncSettings(ctx context.Context, userID string, enabled bool) {
+	v := "false"
+	if enabled {
+		v = "true"
+	}
+	if err := s.settingsRepo.SetValue(ctx, userID, persistence.SettingTwoFAEnabled, v); err != nil {
+		log.Printf("[TwoFactorService] Failed to sync settings for %s: %v", userID, err)
+	}
+
+	if s.RedisClient != nil {
+		_ = s.RedisClient.Del(ctx, userBundleCacheKey(userID)).Err()
+	}
+}
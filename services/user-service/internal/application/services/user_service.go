@@ -254,6 +254,7 @@ func (s *userServiceImpl) GetCurrentUserBundle(ctx context.Context, userID strin
 		if settings.Language != "" {
 			resp.Settings["language"] = settings.Language
 		}
+		resp.Settings["twoFaEnabled"] = fmt.Sprintf("%t", settings.TwoFAEnabled)
 	} else {
 		resp.Settings = make(map[string]string)
 	}
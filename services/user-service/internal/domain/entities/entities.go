@@ -16,6 +16,8 @@ type User struct {
 	IsLocked            bool          `json:"is_locked"`
 	FailedLoginAttempts int           `json:"failed_login_attempts"`
 	LastLogin           *time.Time    `json:"last_login"`
+	TwoFactorEnabled    bool          `json:"two_factor_enabled"`
+	TwoFactorSecret     *string       `json:"-"` // terenkripsi (shared/security.SecretBox)
 	CreatedAt           time.Time     `json:"created_at"`
 	UpdatedAt           *time.Time    `json:"updated_at"`
 	Role                *Role         `json:"role"`
@@ -93,6 +95,15 @@ type UserSettings struct {
 	UpdatedAt     *time.Time
 }
 
+// RecoveryCode represents a one-time 2FA recovery code (hashed)
+type RecoveryCode struct {
+	ID        string
+	UserID    string
+	CodeHash  string
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
 // SycCoreUser represents sycrone core user entity
 type SycCoreUser struct {
 	ID            string    `json:"id"`
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"time"
 
 	"bkc_microservice/services/user-service/internal/domain/entities"
 )
@@ -17,6 +18,10 @@ type UserRepository interface {
 	Delete(ctx context.Context, id string) error
 	UpdateLoginAttempts(ctx context.Context, userID string, attempts int) error
 	UpdateLastLogin(ctx context.Context, userID string) error
+	FindCredentialsByID(ctx context.Context, id string) (*entities.User, error)
+	FindCredentialsByLogin(ctx context.Context, login string) (*entities.User, error)
+	UpdateTwoFactor(ctx context.Context, userID string, enabled bool, secret *string) error
+	UpdatePassword(ctx context.Context, userID, passwordHash string) error
 }
 
 // RoleRepository defines role persistence operations
@@ -75,6 +80,22 @@ type UserSettingsRepository interface {
 	Create(ctx context.Context, settings *entities.UserSettings) error
 	Update(ctx context.Context, settings *entities.UserSettings) error
 	Delete(ctx context.Context, userID string) error
+	SetValue(ctx context.Context, userID, key, value string) error
+}
+
+// RecoveryCodeRepository defines 2FA recovery code operations
+type RecoveryCodeRepository interface {
+	ReplaceAll(ctx context.Context, userID string, codeHashes []string) error
+	Consume(ctx context.Context, userID, codeHash string) (bool, error)
+	CountUnused(ctx context.Context, userID string) (int, error)
+	DeleteAll(ctx context.Context, userID string) error
+}
+
+// PasswordResetRepository defines password reset token operations
+type PasswordResetRepository interface {
+	Replace(ctx context.Context, userID, tokenHash string, expiresAt time.Time) error
+	Consume(ctx context.Context, tokenHash string) (string, error)
+	DeleteAll(ctx context.Context, userID string) error
 }
 
 // SycCoreUserRepository defines sycrone core user operations
@@ -0,0 +1,48 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// AuthServiceClient memanggil endpoint internal auth-service.
+type AuthServiceClient struct {
+	baseURL     string
+	internalKey string
+	httpClient  *http.Client
+}
+
+func NewAuthServiceClient(baseURL, internalKey string) *AuthServiceClient {
+	return &AuthServiceClient{
+		baseURL:     baseURL,
+		internalKey: internalKey,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// RevokeUserSessions mencabut semua access/refresh token dan sesi login user di auth-service.
+func (c *AuthServiceClient) RevokeUserSessions(ctx context.Context, userID string) error {
+	endpoint := fmt.Sprintf("%s/internal/users/%s/logout", c.baseURL, url.PathEscape(userID))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Internal-Api-Key", c.internalKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
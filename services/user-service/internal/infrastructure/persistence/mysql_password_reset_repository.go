@@ -0,0 +1,82 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// MySQLPasswordResetRepository implements PasswordResetRepository interface
+type MySQLPasswordResetRepository struct {
+	db *sql.DB
+}
+
+func NewMySQLPasswordResetRepository(db *sql.DB) *MySQLPasswordResetRepository {
+	return &MySQLPasswordResetRepository{db: db}
+}
+
+// Replace menyimpan token baru dan menghapus token lama user (hanya token terakhir yang berlaku)
+func (r *MySQLPasswordResetRepository) Replace(ctx context.Context, userID, tokenHash string, expiresAt time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM password_reset_tokens WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("failed to delete reset tokens: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO password_reset_tokens (id, user_id, token_hash, expires_at, created_at)
+		VALUES (UUID(), ?, ?, ?, NOW())
+	`, userID, tokenHash, expiresAt); err != nil {
+		return fmt.Errorf("failed to insert reset token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit reset token: %w", err)
+	}
+	return nil
+}
+
+// Consume menandai token terpakai dan mengembalikan user_id; "" jika token tidak ada,
+// sudah dipakai atau kedaluwarsa
+func (r *MySQLPasswordResetRepository) Consume(ctx context.Context, tokenHash string) (string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var userID string
+	err = tx.QueryRowContext(ctx, `
+		SELECT user_id FROM password_reset_tokens
+		WHERE token_hash = ? AND used_at IS NULL AND expires_at > NOW()
+		FOR UPDATE
+	`, tokenHash).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query reset token: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE password_reset_tokens SET used_at = NOW() WHERE token_hash = ?
+	`, tokenHash); err != nil {
+		return "", fmt.Errorf("failed to consume reset token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit reset token: %w", err)
+	}
+	return userID, nil
+}
+
+func (r *MySQLPasswordResetRepository) DeleteAll(ctx context.Context, userID string) error {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM password_reset_tokens WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("failed to delete reset tokens: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// MySQLRecoveryCodeRepository implements RecoveryCodeRepository interface
+type MySQLRecoveryCodeRepository struct {
+	db *sql.DB
+}
+
+func NewMySQLRecoveryCodeRepository(db *sql.DB) *MySQLRecoveryCodeRepository {
+	return &MySQLRecoveryCodeRepository{db: db}
+}
+
+// ReplaceAll menghapus kode lama dan menyimpan set kode baru dalam satu transaction
+func (r *MySQLRecoveryCodeRepository) ReplaceAll(ctx context.Context, userID string, codeHashes []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_recovery_codes WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	for _, h := range codeHashes {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO user_recovery_codes (id, user_id, code_hash, created_at)
+			VALUES (UUID(), ?, ?, NOW())
+		`, userID, h); err != nil {
+			return fmt.Errorf("failed to insert recovery code: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit recovery codes: %w", err)
+	}
+	return nil
+}
+
+// Consume menandai kode terpakai; false jika kode tidak ada / sudah dipakai
+func (r *MySQLRecoveryCodeRepository) Consume(ctx context.Context, userID, codeHash string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE user_recovery_codes
+		SET used_at = NOW()
+		WHERE user_id = ? AND code_hash = ? AND used_at IS NULL
+	`, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to consume recovery code: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rowsAffected == 1, nil
+}
+
+func (r *MySQLRecoveryCodeRepository) CountUnused(ctx context.Context, userID string) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM user_recovery_codes
+		WHERE user_id = ? AND used_at IS NULL
+	`, userID).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return n, nil
+}
+
+func (r *MySQLRecoveryCodeRepository) DeleteAll(ctx context.Context, userID string) error {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM user_recovery_codes WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	return nil
+}
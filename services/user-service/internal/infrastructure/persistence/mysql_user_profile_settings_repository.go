@@ -168,7 +168,8 @@ func (r *MySQLUserProfileRepository) Delete(ctx context.Context, userID string)
 	return nil
 }
 
-// MySQLUserSettingsRepository implements UserSettingsRepository interface
+// MySQLUserSettingsRepository implements UserSettingsRepository interface.
+// Tabel user_settings berbentuk key/value (user_id, k, v) — lihat migration 0007.
 type MySQLUserSettingsRepository struct {
 	db *sql.DB
 }
@@ -177,31 +178,62 @@ func NewMySQLUserSettingsRepository(db *sql.DB) *MySQLUserSettingsRepository {
 	return &MySQLUserSettingsRepository{db: db}
 }
 
+// key pada kolom user_settings.k
+const (
+	SettingThemeMode     = "theme_mode"
+	SettingLanguage      = "language"
+	SettingTwoFAEnabled  = "two_fa_enabled"
+	SettingNotifications = "notifications"
+)
+
 func (r *MySQLUserSettingsRepository) FindByUserID(userID string) (*entities.UserSettings, error) {
-	query := `
-		SELECT id, user_id, theme_mode, language, two_fa_enabled, notifications, created_at, updated_at
+	rows, err := r.db.Query(`
+		SELECT k, v, created_at, updated_at
 		FROM user_settings
 		WHERE user_id = ?
-	`
-
-	settings := &entities.UserSettings{}
-	err := r.db.QueryRow(query, userID).Scan(
-		&settings.ID,
-		&settings.UserID,
-		&settings.ThemeMode,
-		&settings.Language,
-		&settings.TwoFAEnabled,
-		&settings.Notifications,
-		&settings.CreatedAt,
-		&settings.UpdatedAt,
-	)
-
-	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("user settings not found")
-	}
+	`, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query settings: %w", err)
 	}
+	defer rows.Close()
+
+	settings := &entities.UserSettings{UserID: userID}
+	found := false
+	for rows.Next() {
+		var k string
+		var v sql.NullString
+		var createdAt, updatedAt time.Time
+		if err := rows.Scan(&k, &v, &createdAt, &updatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan settings: %w", err)
+		}
+		found = true
+
+		if settings.CreatedAt.IsZero() || createdAt.Before(settings.CreatedAt) {
+			settings.CreatedAt = createdAt
+		}
+		if settings.UpdatedAt == nil || updatedAt.After(*settings.UpdatedAt) {
+			u := updatedAt
+			settings.UpdatedAt = &u
+		}
+
+		switch k {
+		case SettingThemeMode:
+			settings.ThemeMode = v.String
+		case SettingLanguage:
+			settings.Language = v.String
+		case SettingTwoFAEnabled:
+			settings.TwoFAEnabled = parseBoolSetting(v.String)
+		case SettingNotifications:
+			settings.Notifications = parseBoolSetting(v.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate settings: %w", err)
+	}
+
+	if !found {
+		return nil, fmt.Errorf("user settings not found")
+	}
 
 	return settings, nil
 }
@@ -210,71 +242,45 @@ func (r *MySQLUserSettingsRepository) Create(ctx context.Context, settings *enti
 	if settings == nil {
 		return fmt.Errorf("settings is required")
 	}
-
-	query := `
-		INSERT INTO user_settings (user_id, theme_mode, language, two_fa_enabled, notifications, created_at)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`
-
-	result, err := r.db.ExecContext(
-		ctx,
-		query,
-		settings.UserID,
-		settings.ThemeMode,
-		settings.Language,
-		settings.TwoFAEnabled,
-		settings.Notifications,
-		time.Now(),
-	)
-
-	if err != nil {
-		return fmt.Errorf("failed to create settings: %w", err)
-	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return fmt.Errorf("failed to get last insert id: %w", err)
-	}
-
-	settings.ID = int(id)
-	return nil
+	return r.upsertAll(ctx, settings)
 }
 
 func (r *MySQLUserSettingsRepository) Update(ctx context.Context, settings *entities.UserSettings) error {
 	if settings == nil {
 		return fmt.Errorf("settings is required")
 	}
+	return r.upsertAll(ctx, settings)
+}
 
-	query := `
-		UPDATE user_settings
-		SET theme_mode = ?, language = ?, two_fa_enabled = ?, notifications = ?, updated_at = ?
-		WHERE user_id = ?
-	`
-
-	result, err := r.db.ExecContext(
-		ctx,
-		query,
-		settings.ThemeMode,
-		settings.Language,
-		settings.TwoFAEnabled,
-		settings.Notifications,
-		time.Now(),
-		settings.UserID,
-	)
-
-	if err != nil {
-		return fmt.Errorf("failed to update settings: %w", err)
+// SetValue meng-upsert satu key setting
+func (r *MySQLUserSettingsRepository) SetValue(ctx context.Context, userID, key, value string) error {
+	if userID == "" {
+		return fmt.Errorf("user ID is required")
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO user_settings (id, user_id, k, v)
+		VALUES (UUID(), ?, ?, ?)
+		ON DUPLICATE KEY UPDATE v = VALUES(v)
+	`, userID, key, value)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return fmt.Errorf("failed to save setting %s: %w", key, err)
 	}
+	return nil
+}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("user settings not found")
+func (r *MySQLUserSettingsRepository) upsertAll(ctx context.Context, settings *entities.UserSettings) error {
+	values := map[string]string{
+		SettingThemeMode:     settings.ThemeMode,
+		SettingLanguage:      settings.Language,
+		SettingTwoFAEnabled:  formatBoolSetting(settings.TwoFAEnabled),
+		SettingNotifications: formatBoolSetting(settings.Notifications),
+	}
+	for k, v := range values {
+		if err := r.SetValue(ctx, settings.UserID, k, v); err != nil {
+			return err
+		}
 	}
-
 	return nil
 }
 
@@ -301,3 +307,14 @@ func (r *MySQLUserSettingsRepository) Delete(ctx context.Context, userID string)
 
 	return nil
 }
+
+func parseBoolSetting(v string) bool {
+	return v == "true" || v == "1"
+}
+
+func formatBoolSetting(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
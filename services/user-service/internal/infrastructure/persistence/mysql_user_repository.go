@@ -332,3 +332,120 @@ func (r *MySQLUserRepository) UpdateLastLogin(ctx context.Context, userID string
 
 	return nil
 }
+
+// FindCredentialsByID mengambil data sensitif (password hash & 2FA) untuk re-autentikasi
+func (r *MySQLUserRepository) FindCredentialsByID(ctx context.Context, id string) (*entities.User, error) {
+	query := `
+		SELECT id, username, email, password_hash, two_factor_enabled, two_factor_secret
+		FROM users
+		WHERE id = ? AND is_active = true
+	`
+
+	user := &entities.User{}
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.PasswordHash,
+		&user.TwoFactorEnabled,
+		&user.TwoFactorSecret,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("user not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user: %w", err)
+	}
+
+	return user, nil
+}
+
+// FindCredentialsByLogin mengambil user aktif berdasarkan email atau username
+// beserta password hash dan status lock untuk login (auth-service); kunci otomatis
+// lockout yang sudah melewati locked_until dianggap terbuka
+func (r *MySQLUserRepository) FindCredentialsByLogin(ctx context.Context, login string) (*entities.User, error) {
+	query := `
+		SELECT id, username, email, password_hash, role_id, is_active,
+		       is_locked AND (locked_until IS NULL OR locked_until > NOW()) AS is_locked,
+		       failed_login_attempts, two_factor_enabled
+		FROM users
+		WHERE (email = ? OR username = ?) AND is_active = true
+		LIMIT 1
+	`
+
+	user := &entities.User{}
+	err := r.db.QueryRowContext(ctx, query, login, login).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.PasswordHash,
+		&user.RoleID,
+		&user.IsActive,
+		&user.IsLocked,
+		&user.FailedLoginAttempts,
+		&user.TwoFactorEnabled,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("user not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user: %w", err)
+	}
+
+	return user, nil
+}
+
+func (r *MySQLUserRepository) UpdateTwoFactor(ctx context.Context, userID string, enabled bool, secret *string) error {
+	if userID == "" {
+		return fmt.Errorf("user ID is required")
+	}
+
+	query := "UPDATE users SET two_factor_enabled = ?, two_factor_secret = ?, updated_at = ? WHERE id = ?"
+
+	result, err := r.db.ExecContext(ctx, query, enabled, secret, time.Now(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to update two factor: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+// UpdatePassword menyimpan hash baru + last_password_change, mereset hitungan gagal login
+// dan melepas kunci otomatis lockout (kunci admin tanpa locked_until tetap)
+func (r *MySQLUserRepository) UpdatePassword(ctx context.Context, userID, passwordHash string) error {
+	if userID == "" {
+		return fmt.Errorf("user ID is required")
+	}
+
+	query := `
+		UPDATE users
+		SET password_hash = ?, last_password_change = NOW(), failed_login_attempts = 0,
+		    is_locked = IF(locked_until IS NULL, is_locked, FALSE), locked_until = NULL,
+		    updated_at = NOW()
+		WHERE id = ?
+	`
+	result, err := r.db.ExecContext(ctx, query, passwordHash, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"bkc_microservice/services/user-service/internal/application/services"
+	"bkc_microservice/services/user-service/internal/interfaces/http/response"
+	"bkc_microservice/services/user-service/internal/shared"
+)
+
+type CredentialHandler struct {
+	credentialService services.CredentialService
+	logger            shared.Logger
+}
+
+func NewCredentialHandler(credentialService services.CredentialService, logger shared.Logger) *CredentialHandler {
+	return &CredentialHandler{
+		credentialService: credentialService,
+		logger:            logger,
+	}
+}
+
+// Authenticate godoc
+// POST /internal/users/authenticate
+// Dipakai halaman login auth-service; dilindungi X-Internal-Api-Key
+func (h *CredentialHandler) Authenticate(w http.ResponseWriter, r *http.Request) {
+	var req services.AuthenticateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+	req.Login = strings.TrimSpace(req.Login)
+	if req.Login == "" || req.Password == "" {
+		response.BadRequest(w, "Login and password are required")
+		return
+	}
+
+	user, err := h.credentialService.Authenticate(r.Context(), req.Login, req.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidCredentials):
+			response.Unauthorized(w, err.Error())
+		case errors.Is(err, services.ErrAccountLocked):
+			response.Locked(w, err.Error())
+		default:
+			h.logger.Error("Authenticate", "Failed to authenticate user", err)
+			response.InternalServerError(w, err.Error())
+		}
+		return
+	}
+
+	response.OK(w, user)
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"bkc_microservice/services/user-service/internal/application/services"
+	"bkc_microservice/services/user-service/internal/interfaces/http/response"
+	"bkc_microservice/services/user-service/internal/shared"
+)
+
+type PasswordHandler struct {
+	passwordService services.AccountPasswordService
+	logger          shared.Logger
+}
+
+func NewPasswordHandler(passwordService services.AccountPasswordService, logger shared.Logger) *PasswordHandler {
+	return &PasswordHandler{
+		passwordService: passwordService,
+		logger:          logger,
+	}
+}
+
+// ChangePassword godoc
+// POST /me/password
+// Semua sesi & token user dicabut setelah password berubah
+func (h *PasswordHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req services.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		response.BadRequest(w, "Current password and new password are required")
+		return
+	}
+
+	if err := h.passwordService.ChangePassword(r.Context(), userID, &req); err != nil {
+		h.writeError(w, "ChangePassword", err)
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// ForgotPassword godoc
+// POST /password/forgot
+// Selalu 202 (email tidak dikenal juga) agar keberadaan akun tidak bocor
+func (h *PasswordHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req services.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Email) == "" {
+		response.BadRequest(w, "Email is required")
+		return
+	}
+
+	if err := h.passwordService.RequestReset(r.Context(), req.Email); err != nil {
+		h.logger.Error("ForgotPassword", "Failed to request password reset", err)
+		response.InternalServerError(w, "failed to process request")
+		return
+	}
+
+	response.Success(w, http.StatusAccepted, map[string]string{
+		"message": "If the email is registered, a reset link has been sent",
+	}, nil)
+}
+
+// ResetPassword godoc
+// POST /password/reset
+func (h *PasswordHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req services.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+	if req.Token == "" || req.NewPassword == "" {
+		response.BadRequest(w, "Token and new password are required")
+		return
+	}
+
+	if err := h.passwordService.ResetPassword(r.Context(), &req); err != nil {
+		h.writeError(w, "ResetPassword", err)
+		return
+	}
+
+	response.NoContent(w)
+}
+
+func (h *PasswordHandler) writeError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, services.ErrWeakPassword), errors.Is(err, services.ErrPasswordUnchanged),
+		errors.Is(err, services.ErrInvalidResetToken):
+		response.BadRequest(w, err.Error())
+	case errors.Is(err, services.ErrInvalidPassword):
+		response.Unauthorized(w, err.Error())
+	case err.Error() == "user not found":
+		response.NotFound(w, "User not found")
+	default:
+		h.logger.Error(op, "Password operation failed", err)
+		response.InternalServerError(w, err.Error())
+	}
+}
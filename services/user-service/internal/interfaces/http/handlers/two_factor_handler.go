@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"bkc_microservice/services/user-service/internal/application/services"
+	"bkc_microservice/services/user-service/internal/interfaces/http/response"
+	"bkc_microservice/services/user-service/internal/middleware"
+	"bkc_microservice/services/user-service/internal/shared"
+)
+
+type TwoFactorHandler struct {
+	twoFactorService services.TwoFactorService
+	logger           shared.Logger
+}
+
+func NewTwoFactorHandler(twoFactorService services.TwoFactorService, logger shared.Logger) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		twoFactorService: twoFactorService,
+		logger:           logger,
+	}
+}
+
+// Status godoc
+// GET /me/2fa
+func (h *TwoFactorHandler) Status(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	status, err := h.twoFactorService.Status(r.Context(), userID)
+	if err != nil {
+		h.writeError(w, "Status", err)
+		return
+	}
+
+	response.OK(w, status)
+}
+
+// Enroll godoc
+// POST /me/2fa/enroll
+// Mengembalikan otpauth URL + QR PNG (base64); 2FA aktif setelah /me/2fa/confirm
+func (h *TwoFactorHandler) Enroll(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	res, err := h.twoFactorService.StartEnrollment(r.Context(), userID)
+	if err != nil {
+		h.writeError(w, "Enroll", err)
+		return
+	}
+
+	response.OK(w, res)
+}
+
+// Confirm godoc
+// POST /me/2fa/confirm
+func (h *TwoFactorHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req services.ConfirmTwoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+	if req.Code == "" {
+		response.BadRequest(w, "Code is required")
+		return
+	}
+
+	res, err := h.twoFactorService.ConfirmEnrollment(r.Context(), userID, req.Code)
+	if err != nil {
+		h.writeError(w, "Confirm", err)
+		return
+	}
+
+	response.OK(w, res)
+}
+
+// Disable godoc
+// POST /me/2fa/disable
+func (h *TwoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req services.DisableTwoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+	if req.Password == "" || req.Code == "" {
+		response.BadRequest(w, "Password and code are required")
+		return
+	}
+
+	if err := h.twoFactorService.Disable(r.Context(), userID, &req); err != nil {
+		h.writeError(w, "Disable", err)
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// RegenerateRecoveryCodes godoc
+// POST /me/2fa/recovery-codes
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req services.RegenerateRecoveryCodesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+	if req.Code == "" {
+		response.BadRequest(w, "Code is required")
+		return
+	}
+
+	res, err := h.twoFactorService.RegenerateRecoveryCodes(r.Context(), userID, req.Code)
+	if err != nil {
+		h.writeError(w, "RegenerateRecoveryCodes", err)
+		return
+	}
+
+	response.OK(w, res)
+}
+
+func (h *TwoFactorHandler) writeError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, services.ErrTwoFactorAlreadyEnabled), errors.Is(err, services.ErrTwoFactorNotEnabled):
+		response.Conflict(w, err.Error())
+	case errors.Is(err, services.ErrTwoFactorNotPending), errors.Is(err, services.ErrInvalidTwoFactorCode):
+		response.BadRequest(w, err.Error())
+	case errors.Is(err, services.ErrInvalidPassword):
+		response.Unauthorized(w, err.Error())
+	case err.Error() == "user not found":
+		response.NotFound(w, "User not found")
+	default:
+		h.logger.Error(op, "Two factor operation failed", err)
+		response.InternalServerError(w, err.Error())
+	}
+}
+
+func currentUserID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok || claims.UserID == "" {
+		response.Unauthorized(w, "missing user claims")
+		return "", false
+	}
+	return claims.UserID, true
+}
@@ -156,6 +156,30 @@ func (h *UserHandler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 	response.OK(w, maskedData)
 }
 
+// GetInternalUser godoc
+// GET /internal/users/:id
+// Dipakai auth-service (OIDC userinfo); dilindungi X-Internal-Api-Key
+func (h *UserHandler) GetInternalUser(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		response.BadRequest(w, "User ID is required")
+		return
+	}
+
+	user, err := h.userService.GetCurrentUserBundle(r.Context(), id)
+	if err != nil {
+		if err.Error() == "user not found" {
+			response.NotFound(w, "User not found")
+			return
+		}
+		h.logger.Error("GetInternalUser", "Failed to get user", err)
+		response.InternalServerError(w, err.Error())
+		return
+	}
+
+	response.OK(w, user)
+}
+
 // CreateUser godoc
 // POST /api/v1/users
 func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
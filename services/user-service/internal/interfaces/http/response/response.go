@@ -84,6 +84,11 @@ func Conflict(w http.ResponseWriter, message string) {
 	Error(w, http.StatusConflict, "CONFLICT", message, "")
 }
 
+// Locked for locked accounts
+func Locked(w http.ResponseWriter, message string) {
+	Error(w, http.StatusLocked, "ACCOUNT_LOCKED", message, "")
+}
+
 // Created for successful creation
 func Created(w http.ResponseWriter, data interface{}) {
 	Success(w, http.StatusCreated, data, nil)
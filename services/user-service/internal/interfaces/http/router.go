@@ -18,6 +18,9 @@ func NewRouter(
 	userService services.UserService,
 	roleService services.RoleService,
 	permService services.PermissionService,
+	twoFactorService services.TwoFactorService,
+	credentialService services.CredentialService,
+	passwordService services.AccountPasswordService,
 	logger shared.Logger,
 	rdb *redis.Client,
 ) http.Handler {
@@ -27,6 +30,9 @@ func NewRouter(
 	userHandler := handlers.NewUserHandler(userService, logger)
 	roleHandler := handlers.NewRoleHandler(roleService, logger)
 	permissionHandler := handlers.NewPermissionHandler(permService, logger)
+	twoFactorHandler := handlers.NewTwoFactorHandler(twoFactorService, logger)
+	credentialHandler := handlers.NewCredentialHandler(credentialService, logger)
+	passwordHandler := handlers.NewPasswordHandler(passwordService, logger)
 
 	// ==================== HEALTH CHECK (NO AUTH) ====================
 	r.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
@@ -35,6 +41,16 @@ func NewRouter(
 		w.Write([]byte(`{"status":"ok"}`))
 	}).Methods(http.MethodGet)
 
+	// ==================== INTERNAL ROUTES (SERVICE-TO-SERVICE) ====================
+	internalRouter := r.PathPrefix("/internal").Subrouter()
+	internalRouter.Use(middleware.RequireInternalKey)
+	internalRouter.HandleFunc("/users/authenticate", credentialHandler.Authenticate).Methods(http.MethodPost)
+	internalRouter.HandleFunc("/users/{id}", userHandler.GetInternalUser).Methods(http.MethodGet)
+
+	// ==================== PASSWORD RESET (PUBLIC, VIA GATEWAY) ====================
+	r.HandleFunc("/password/forgot", passwordHandler.ForgotPassword).Methods(http.MethodPost)
+	r.HandleFunc("/password/reset", passwordHandler.ResetPassword).Methods(http.MethodPost)
+
 	// ==================== AUTHENTICATED ROUTES ====================
 	authenticatedRouter := r.PathPrefix("/").Subrouter()
 	authenticatedRouter.Use(middleware.InjectClaimsFromGateway)
@@ -48,6 +64,16 @@ func NewRouter(
 		),
 	).Methods(http.MethodGet)
 
+	// ==================== 2FA (TOTP) ====================
+	authenticatedRouter.HandleFunc("/me/2fa", twoFactorHandler.Status).Methods(http.MethodGet)
+	authenticatedRouter.HandleFunc("/me/2fa/enroll", twoFactorHandler.Enroll).Methods(http.MethodPost)
+	authenticatedRouter.HandleFunc("/me/2fa/confirm", twoFactorHandler.Confirm).Methods(http.MethodPost)
+	authenticatedRouter.HandleFunc("/me/2fa/disable", twoFactorHandler.Disable).Methods(http.MethodPost)
+	authenticatedRouter.HandleFunc("/me/2fa/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes).Methods(http.MethodPost)
+
+	// ==================== PASSWORD ====================
+	authenticatedRouter.HandleFunc("/me/password", passwordHandler.ChangePassword).Methods(http.MethodPost)
+
 	// ==================== API V1 ROUTES ====================
 	apiRouter := r.PathPrefix("/api/v1").Subrouter()
 
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+)
+
+// RequireInternalKey membatasi endpoint /internal hanya untuk service lain
+// (header X-Internal-Api-Key == env INTERNAL_API_KEY). Jika env kosong, semua ditolak.
+func RequireInternalKey(next http.Handler) http.Handler {
+	key := os.Getenv("INTERNAL_API_KEY")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := r.Header.Get("X-Internal-Api-Key")
+		if key == "" || subtle.ConstantTimeCompare([]byte(got), []byte(key)) != 1 {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -15,6 +16,15 @@ type ServerCfg struct {
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
 	IdleTimeout  time.Duration
+
+	// TLS opsional; TLSCertFile kosong => plain HTTP
+	TLSCertFile     string
+	TLSKeyFile      string
+	TLSClientCAFile string // CA sertifikat client (mTLS)
+	TLSClientAuth   string // none | request | require_any | verify_if_given | require
+
+	// proxy tepercaya (IP / CIDR); hanya hop X-Forwarded-For dari proxy ini yang dipercaya
+	TrustedProxies []string
 }
 
 type DBcfg struct {
@@ -32,6 +42,15 @@ type JWTcfg struct {
 	AccessTTL      time.Duration
 	RefreshTTL     time.Duration
 	AuthCodeTTL    time.Duration
+
+	ClientSecretTTL   time.Duration // 0 = secret client tidak kedaluwarsa
+	ClientSecretGrace time.Duration // masa tumpang tindih secret lama saat rotasi
+	SessionTTL        time.Duration // umur sesi login browser auth-service
+
+	// rotasi signing key: direktori <kid>.pem (+ <kid>.json); kosong => PrivateKeyPath tunggal
+	KeysDir           string
+	KeyRetireGrace    time.Duration // key pensiun tetap dipublikasikan selama ini
+	KeyReloadInterval time.Duration // interval baca ulang KeysDir
 }
 
 type RedisConfig struct {
@@ -46,6 +65,21 @@ type RateLimitConfig struct {
 	MaxRequests int           // requests per window
 }
 
+// CleanupCfg — janitor token / auth code kedaluwarsa (auth-service)
+type CleanupCfg struct {
+	Interval         time.Duration // 0 => janitor terjadwal mati (trigger manual tetap bisa)
+	BatchSize        int           // baris per DELETE
+	RevokedRetention time.Duration // refresh token dicabut tanpa expiry disimpan selama ini (deteksi reuse)
+}
+
+// LockoutCfg — lockout akun & throttling login password grant (auth-service)
+type LockoutCfg struct {
+	Threshold    int           // gagal berturut-turut sebelum akun dikunci; 0 => lockout mati
+	Duration     time.Duration // lama kunci otomatis sebelum auto-unlock
+	ThrottleBase time.Duration // jeda setelah gagal pertama per username+IP, dua kali lipat tiap gagal
+	ThrottleMax  time.Duration // batas atas jeda
+}
+
 type Config struct {
 	Server            ServerCfg
 	DB                DBcfg
@@ -55,6 +89,8 @@ type Config struct {
 	SyncCBSServiceURL string
 	Redis             RedisConfig
 	RateLimit         RateLimitConfig
+	Cleanup           CleanupCfg
+	Lockout           LockoutCfg
 }
 
 func tryLoadDotEnv() {
@@ -88,6 +124,14 @@ func resolvePath(p string) string {
 	return p
 }
 
+// optionalPath — resolvePath untuk path opsional; kosong tetap kosong
+func optionalPath(p string) string {
+	if p == "" {
+		return ""
+	}
+	return resolvePath(p)
+}
+
 func getEnv(key, def string) string {
 	if v, ok := os.LookupEnv(key); ok && v != "" {
 		return v
@@ -111,6 +155,9 @@ func MustLoad() *Config {
 	accessTTL := parseDurOr(getEnv("OAUTH2_ACCESS_TOKEN_EXPIRATION", "15m"), 15*time.Minute)
 	refreshTTL := parseDurOr(getEnv("OAUTH2_REFRESH_TOKEN_EXPIRATION", "720h"), 720*time.Hour)
 	authCodeTTL := parseDurOr(getEnv("OAUTH2_AUTH_CODE_EXPIRATION", "10m"), 10*time.Minute)
+	clientSecretTTL := parseDurOr(getEnv("OAUTH2_CLIENT_SECRET_EXPIRATION", "0s"), 0)
+	clientSecretGrace := parseDurOr(getEnv("OAUTH2_CLIENT_SECRET_ROTATION_GRACE", "24h"), 24*time.Hour)
+	sessionTTL := parseDurOr(getEnv("OAUTH2_SESSION_EXPIRATION", "12h"), 12*time.Hour)
 
 	userSvcURL := getEnv("USER_SERVICE_URL", "http://user-service:9002")
 	syncCBSSvcURL := getEnv("SYNC_CBS_SERVICE_URL", "http://sync-cbs-service:9003")
@@ -122,6 +169,13 @@ func MustLoad() *Config {
 			ReadTimeout:  readTimeout,
 			WriteTimeout: writeTimeout,
 			IdleTimeout:  idleTimeout,
+
+			TLSCertFile:     optionalPath(getEnv("SERVER_TLS_CERT_FILE", "")),
+			TLSKeyFile:      optionalPath(getEnv("SERVER_TLS_KEY_FILE", "")),
+			TLSClientCAFile: optionalPath(getEnv("SERVER_TLS_CLIENT_CA_FILE", "")),
+			TLSClientAuth:   getEnv("SERVER_TLS_CLIENT_AUTH", ""),
+
+			TrustedProxies: splitList(getEnv("TRUSTED_PROXIES", "")),
 		},
 		DB: DBcfg{
 			Host:     dbHost,
@@ -137,6 +191,14 @@ func MustLoad() *Config {
 			AccessTTL:      accessTTL,
 			RefreshTTL:     refreshTTL,
 			AuthCodeTTL:    authCodeTTL,
+
+			ClientSecretTTL:   clientSecretTTL,
+			ClientSecretGrace: clientSecretGrace,
+			SessionTTL:        sessionTTL,
+
+			KeysDir:           optionalPath(getEnv("JWT_KEYS_DIR", "")),
+			KeyRetireGrace:    parseDurOr(getEnv("JWT_KEY_RETIRE_GRACE", "24h"), 24*time.Hour),
+			KeyReloadInterval: parseDurOr(getEnv("JWT_KEY_RELOAD_INTERVAL", "1m"), time.Minute),
 		},
 
 		UserServiceURL:    userSvcURL,
@@ -152,6 +214,17 @@ func MustLoad() *Config {
 			WindowSize:  parseDurOr(getEnv("RATE_LIMIT_WINDOW", "1m"), 1*time.Minute),
 			MaxRequests: parseInt(getEnv("RATE_LIMIT_MAX_REQUESTS", "60"), 60),
 		},
+		Cleanup: CleanupCfg{
+			Interval:         parseDurOr(getEnv("TOKEN_CLEANUP_INTERVAL", "15m"), 15*time.Minute),
+			BatchSize:        parseInt(getEnv("TOKEN_CLEANUP_BATCH_SIZE", "1000"), 1000),
+			RevokedRetention: parseDurOr(getEnv("TOKEN_CLEANUP_REVOKED_RETENTION", "720h"), 30*24*time.Hour),
+		},
+		Lockout: LockoutCfg{
+			Threshold:    parseInt(getEnv("LOGIN_LOCKOUT_THRESHOLD", "5"), 5),
+			Duration:     parseDurOr(getEnv("LOGIN_LOCKOUT_DURATION", "15m"), 15*time.Minute),
+			ThrottleBase: parseDurOr(getEnv("LOGIN_THROTTLE_BASE", "1s"), time.Second),
+			ThrottleMax:  parseDurOr(getEnv("LOGIN_THROTTLE_MAX", "5m"), 5*time.Minute),
+		},
 	}
 }
 
@@ -164,6 +237,17 @@ func parseDurOr(s string, def time.Duration) time.Duration {
 	return d
 }
 
+// splitList — daftar dipisah koma, entri kosong dibuang
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
 func parseInt(s string, def int) int {
 	n, err := strconv.Atoi(s)
 	if err != nil {
@@ -2,7 +2,6 @@ package http
 
 import (
 	"context"
-	"crypto/rsa"
 	"net/http"
 	"strings"
 
@@ -26,22 +25,40 @@ func setTokenClaims(ctx context.Context, c shsec.TokenClaims) context.Context {
 	return context.WithValue(ctx, tokenClaimsKey, c)
 }
 
-// RequireScopes: verifikasi Bearer JWT (RS256) + semua scope harus ada.
-func RequireScopes(pub *rsa.PublicKey, scopes ...string) func(http.Handler) http.Handler {
+// RequireScopes: token divalidasi v (issuer, aud = identifier resource API ini, typ,
+// exp/nbf, revocation lewat v.Revocation mis. shsec.LocalDenylist) + semua scope harus
+// ada. Token DPoP-bound (cnf.jkt) wajib dikirim dengan skema DPoP + proof yang
+// diverifikasi dpop (nil => token DPoP-bound ditolak); token certificate-bound wajib
+// datang lewat mTLS dengan sertifikat yang sama.
+func RequireScopes(v *shsec.Validator, dpop *shsec.DPoPVerifier, scopes ...string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
+		if v == nil || v.Keys == nil || len(v.Audiences) == 0 {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "auth_misconfigured", http.StatusInternalServerError)
+			})
+		}
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			auth := r.Header.Get("Authorization")
 			parts := strings.SplitN(auth, " ", 2)
-			if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+			if len(parts) != 2 || (!strings.EqualFold(parts[0], "Bearer") && !strings.EqualFold(parts[0], "DPoP")) {
 				http.Error(w, "missing_bearer_token", http.StatusUnauthorized)
 				return
 			}
 			tokenStr := strings.TrimSpace(parts[1])
-			claims, err := shsec.ParseAndVerify(tokenStr, pub)
+			claims, err := v.Validate(r.Context(), tokenStr)
 			if err != nil {
 				http.Error(w, "invalid_token", http.StatusUnauthorized)
 				return
 			}
+			if err := dpop.VerifyBinding(r, parts[0], tokenStr, claims); err != nil {
+				dpop.Challenge(w, err)
+				http.Error(w, "invalid_token", http.StatusUnauthorized)
+				return
+			}
+			if err := shsec.VerifyCertificateBinding(r, claims); err != nil {
+				http.Error(w, "invalid_token", http.StatusUnauthorized)
+				return
+			}
 			if !hasAllScopes(claims.Scope, scopes) {
 				http.Error(w, "insufficient_scope", http.StatusForbidden)
 				return
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ParseTrustedProxies — daftar IP / CIDR proxy tepercaya (mis. TRUSTED_PROXIES).
+func ParseTrustedProxies(list []string) ([]*net.IPNet, error) {
+	var out []*net.IPNet
+	for _, v := range list {
+		if !strings.Contains(v, "/") {
+			if ip := net.ParseIP(v); ip != nil {
+				bits := 8 * len(ip.To16())
+				if ip.To4() != nil {
+					ip, bits = ip.To4(), 32
+				}
+				out = append(out, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+				continue
+			}
+		}
+		_, n, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q", v)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+// ClientIP — IP client yang tidak bisa dipalsukan: RemoteAddr, kecuali koneksi datang dari
+// proxy tepercaya; X-Forwarded-For lalu dibaca dari kanan dan hop pertama yang bukan proxy
+// tepercaya dipakai (hop paling kiri ditulis client sendiri).
+func ClientIP(r *http.Request, trusted []*net.IPNet) string {
+	ip := RemoteIP(r)
+	if !ipTrusted(ip, trusted) {
+		return ip
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		ip = hop
+		if !ipTrusted(hop, trusted) {
+			break
+		}
+	}
+	return ip
+}
+
+// ForwardedIP — hop pertama X-Forwarded-For (atau X-Real-IP / RemoteAddr); nilainya bisa
+// dipalsukan client sehingga hanya layak untuk tampilan log / audit.
+func ForwardedIP(r *http.Request) string {
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		return strings.TrimSpace(strings.Split(xff, ",")[0])
+	}
+	if xr := r.Header.Get("X-Real-IP"); xr != "" {
+		return strings.TrimSpace(xr)
+	}
+	return RemoteIP(r)
+}
+
+// RemoteIP — host dari RemoteAddr koneksi.
+func RemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func ipTrusted(v string, trusted []*net.IPNet) bool {
+	ip := net.ParseIP(v)
+	if ip == nil {
+		return false
+	}
+	for _, n := range trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
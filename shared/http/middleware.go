@@ -13,6 +13,7 @@ func CorrelationID(next http.Handler) http.Handler {
 		id := r.Header.Get("X-Correlation-Id")
 		if id == "" {
 			id = uuid.NewString()
+			r.Header.Set("X-Correlation-Id", id) // handler / audit membaca id yang sama
 		}
 		w.Header().Set("X-Correlation-Id", id)
 		next.ServeHTTP(w, r)
@@ -1,8 +1,13 @@
 package http
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
 	"net"
 	"net/http"
+	"os"
 	"time"
 )
 
@@ -12,16 +17,102 @@ type ServerOptions struct {
 	WriteTimeout time.Duration
 	IdleTimeout  time.Duration
 	Handler      http.Handler
+
+	// TLS (opsional); nil / CertFile kosong => plain HTTP
+	TLS *TLSOptions
+}
+
+// TLSOptions — sertifikat server dan kebijakan sertifikat client (mTLS).
+type TLSOptions struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string // CA untuk verifikasi sertifikat client
+	// none | request | require_any | verify_if_given | require;
+	// kosong => verify_if_given bila ClientCAFile diisi, selain itu none
+	ClientAuth string
 }
 
+// NewServer membuat http.Server; panic bila konfigurasi TLS tidak bisa dimuat.
 func NewServer(opt ServerOptions) *http.Server {
-	return &http.Server{
+	srv := &http.Server{
 		Addr:         normalizeAddr(opt.Addr),
 		Handler:      opt.Handler,
 		ReadTimeout:  opt.ReadTimeout,
 		WriteTimeout: opt.WriteTimeout,
 		IdleTimeout:  opt.IdleTimeout,
 	}
+	if opt.TLS != nil && opt.TLS.CertFile != "" {
+		cfg, err := opt.TLS.Config()
+		if err != nil {
+			panic(err)
+		}
+		srv.TLSConfig = cfg
+	}
+	return srv
+}
+
+// ListenAndServe menjalankan server: TLS bila TLSConfig berisi sertifikat, selain itu HTTP.
+func ListenAndServe(srv *http.Server) error {
+	if srv.TLSConfig != nil && len(srv.TLSConfig.Certificates) > 0 {
+		return srv.ListenAndServeTLS("", "")
+	}
+	return srv.ListenAndServe()
+}
+
+// Config membangun tls.Config (minimal TLS 1.2).
+func (o TLSOptions) Config() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load tls key pair: %w", err)
+	}
+	cfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+
+	if o.ClientCAFile != "" {
+		pool, err := LoadCertPool(o.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ClientCAs = pool
+	}
+
+	mode := o.ClientAuth
+	if mode == "" && o.ClientCAFile != "" {
+		mode = "verify_if_given"
+	}
+	switch mode {
+	case "", "none":
+		cfg.ClientAuth = tls.NoClientCert
+	case "request":
+		cfg.ClientAuth = tls.RequestClientCert
+	case "require_any":
+		cfg.ClientAuth = tls.RequireAnyClientCert
+	case "verify_if_given":
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	case "require":
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("unknown tls client auth mode %q", mode)
+	}
+	if cfg.ClientAuth >= tls.VerifyClientCertIfGiven && cfg.ClientCAs == nil {
+		return nil, errors.New("tls client certificate verification requires a client CA file")
+	}
+	return cfg, nil
+}
+
+// LoadCertPool membaca satu atau lebih sertifikat CA (PEM) dari file.
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(raw) {
+		return nil, fmt.Errorf("no certificates in %s", path)
+	}
+	return pool, nil
 }
 
 func normalizeAddr(addr string) string {
@@ -0,0 +1,145 @@
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"bkc_microservice/shared/security"
+)
+
+type testPKI struct {
+	ca    *x509.Certificate
+	caKey *ecdsa.PrivateKey
+	dir   string
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	p := &testPKI{dir: t.TempDir()}
+	p.ca, p.caKey, _ = p.issue(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "bkc test ca"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, true)
+	return p
+}
+
+// issue membuat sertifikat ECDSA ditandatangani CA (selfSigned => CA itu sendiri).
+func (p *testPKI) issue(t *testing.T, tmpl *x509.Certificate, selfSigned bool) (*x509.Certificate, *ecdsa.PrivateKey, tls.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tmpl.SerialNumber = serial
+	tmpl.NotBefore = time.Now().Add(-time.Minute)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	parent, parentKey := p.ca, p.caKey
+	if selfSigned {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key, tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}
+}
+
+func (p *testPKI) writePEM(t *testing.T, name, typ string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(p.dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTLSOptionsCertificateBoundToken(t *testing.T) {
+	pki := newTestPKI(t)
+
+	// sertifikat server + key ditulis ke file seperti konfigurasi SERVER_TLS_*
+	srvCert, _, srvPair := pki.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, false)
+	keyDER, err := x509.MarshalECPrivateKey(srvPair.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := TLSOptions{
+		CertFile:     pki.writePEM(t, "server.pem", "CERTIFICATE", srvCert.Raw),
+		KeyFile:      pki.writePEM(t, "server-key.pem", "EC PRIVATE KEY", keyDER),
+		ClientCAFile: pki.writePEM(t, "ca.pem", "CERTIFICATE", pki.ca.Raw),
+	}
+	cfg, err := opts.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ClientAuth != tls.VerifyClientCertIfGiven {
+		t.Fatalf("default client auth with CA file = %v, want VerifyClientCertIfGiven", cfg.ClientAuth)
+	}
+
+	clientCert, _, clientPair := pki.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "sync-cbs"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, false)
+	_, _, otherPair := pki.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "sync-cbs"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, false)
+	claims := &security.TokenClaims{Confirmation: &security.Confirmation{X5T: security.CertificateThumbprint(clientCert)}}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := security.VerifyCertificateBinding(r, claims); err != nil {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	srv.TLS = cfg
+	srv.StartTLS()
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(pki.ca)
+	get := func(certs ...tls.Certificate) int {
+		t.Helper()
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs}}}
+		res, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	if code := get(clientPair); code != http.StatusOK {
+		t.Fatalf("bound certificate: status %d, want 200", code)
+	}
+	if code := get(otherPair); code != http.StatusUnauthorized {
+		t.Fatalf("other certificate: status %d, want 401", code)
+	}
+	if code := get(); code != http.StatusUnauthorized {
+		t.Fatalf("no certificate: status %d, want 401", code)
+	}
+}
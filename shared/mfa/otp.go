@@ -34,6 +34,11 @@ func (s *OTPService) Verify(ctx context.Context, key, code string) bool {
 	return stored == code
 }
 
+// Invalidate menghapus OTP supaya tidak bisa dipakai ulang
+func (s *OTPService) Invalidate(ctx context.Context, key string) error {
+	return s.rdb.Del(ctx, "otp:"+key).Err()
+}
+
 func randInt(min, max int) int {
 	nBig, _ := rand.Int(rand.Reader, big.NewInt(int64(max-min+1)))
 	return int(nBig.Int64()) + min
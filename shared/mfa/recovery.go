@@ -0,0 +1,35 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"strings"
+)
+
+const RecoveryCodeCount = 10
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateRecoveryCodes membuat n kode sekali pakai format "xxxxx-xxxxx".
+// Plaintext hanya ditampilkan sekali ke user; yang disimpan adalah HashRecoveryCode.
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		buf := make([]byte, 7)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		s := strings.ToLower(recoveryEncoding.EncodeToString(buf))[:10]
+		codes = append(codes, s[:5]+"-"+s[5:])
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode menormalkan input user (spasi, tanda hubung, huruf besar) lalu SHA-256 hex.
+func HashRecoveryCode(code string) string {
+	norm := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(norm))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,8 +1,11 @@
 package mfa
 
 import (
+	"bytes"
+	"image/png"
 	"time"
 
+	"github.com/pquerna/otp"
 	"github.com/pquerna/otp/totp"
 )
 
@@ -29,3 +32,20 @@ func (t *TOTPService) Validate(passcode, secret string) bool {
 func (t *TOTPService) GenerateCode(secret string) (string, error) {
 	return totp.GenerateCode(secret, time.Now())
 }
+
+// QRCodePNG merender otpauth URL menjadi gambar PNG (size x size piksel)
+func (t *TOTPService) QRCodePNG(otpauthURL string, size int) ([]byte, error) {
+	key, err := otp.NewKeyFromURL(otpauthURL)
+	if err != nil {
+		return nil, err
+	}
+	img, err := key.Image(size, size)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
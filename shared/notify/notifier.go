@@ -0,0 +1,28 @@
+package notify
+
+import (
+	"context"
+	"log"
+)
+
+// Message adalah notifikasi keluar (email/SMS) ke user.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier mengirim pesan ke user. Implementasi SMTP/SMS bisa ditambahkan kemudian.
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogNotifier hanya menulis pesan ke log (dev / belum ada provider).
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier { return &LogNotifier{} }
+
+func (n *LogNotifier) Send(_ context.Context, msg Message) error {
+	log.Printf("[notify] to=%s subject=%q body=%q", msg.To, msg.Subject, msg.Body)
+	return nil
+}
@@ -9,7 +9,10 @@ import (
 	"strings"
 )
 
-func RequireScopes(publicPEM []byte, required ...string) func(http.Handler) http.Handler {
+// RequireScopes: token harus ditujukan ke audience (identifier resource API ini) dan
+// memuat semua scope required; hanya access token (typ) yang diterima. Middleware ini hanya menerima bearer token; token
+// DPoP-bound ditolak (gunakan shared/http.RequireScopes dengan DPoPVerifier).
+func RequireScopes(publicPEM []byte, audience string, required ...string) func(http.Handler) http.Handler {
 	req := make(map[string]struct{}, len(required))
 	for _, s := range required {
 		s = strings.TrimSpace(s)
@@ -18,13 +21,14 @@ func RequireScopes(publicPEM []byte, required ...string) func(http.Handler) http
 		}
 	}
 	pub, err := parseRSAPubFromPEM(publicPEM)
-	if err != nil {
+	if err != nil || audience == "" {
 		return func(next http.Handler) http.Handler {
 			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				http.Error(w, "auth misconfigured", http.StatusInternalServerError)
 			})
 		}
 	}
+	v := &Validator{Keys: StaticKey{Key: pub, Alg: "RS256"}, Audiences: []string{audience}}
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			auth := r.Header.Get("Authorization")
@@ -33,8 +37,15 @@ func RequireScopes(publicPEM []byte, required ...string) func(http.Handler) http
 				return
 			}
 			raw := strings.TrimSpace(strings.TrimPrefix(auth, "Bearer"))
-			claims, err := ParseAndVerify(raw, pub)
+			claims, err := v.Validate(r.Context(), raw)
+			if err == nil {
+				err = (*DPoPVerifier)(nil).VerifyBinding(r, "Bearer", raw, claims)
+			}
+			if err == nil {
+				err = VerifyCertificateBinding(r, claims)
+			}
 			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
 				http.Error(w, "invalid token", http.StatusUnauthorized)
 				return
 			}
@@ -0,0 +1,289 @@
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultDenylistPrefix  = "bl:jti:"
+	defaultDenylistChannel = "bl:jti:events"
+	defaultDenylistResync  = 5 * time.Minute
+	denylistScanCount      = 1000
+)
+
+// JTIDenylist — jti access token yang dicabut sebelum kedaluwarsa, disimpan di Redis
+// sampai exp token dan diumumkan lewat pub/sub supaya resource server (LocalDenylist)
+// tidak perlu lookup Redis per request. Dipakai auth-service (penulis).
+type JTIDenylist struct {
+	RDB     *redis.Client
+	Prefix  string // default "bl:jti:"
+	Channel string // default "bl:jti:events"
+}
+
+// DenylistEvent — pesan pub/sub untuk satu jti yang dicabut.
+type DenylistEvent struct {
+	JTI string `json:"jti"`
+	Exp int64  `json:"exp"` // unix detik
+}
+
+func (d *JTIDenylist) key(jti string) string {
+	return d.prefix() + jti
+}
+
+func (d *JTIDenylist) prefix() string {
+	if d.Prefix != "" {
+		return d.Prefix
+	}
+	return defaultDenylistPrefix
+}
+
+func (d *JTIDenylist) channel() string {
+	if d.Channel != "" {
+		return d.Channel
+	}
+	return defaultDenylistChannel
+}
+
+// Add mencabut jti sampai exp lalu mempublikasikannya; token yang sudah kedaluwarsa
+// tidak perlu dicatat. Publish yang gagal tertutup oleh resync LocalDenylist.
+func (d *JTIDenylist) Add(ctx context.Context, jti string, exp time.Time) error {
+	ttl := time.Until(exp)
+	if jti == "" || ttl <= 0 {
+		return nil
+	}
+	if err := d.RDB.Set(ctx, d.key(jti), exp.Unix(), ttl).Err(); err != nil {
+		return err
+	}
+	msg, _ := json.Marshal(DenylistEvent{JTI: jti, Exp: exp.Unix()})
+	return d.RDB.Publish(ctx, d.channel(), msg).Err()
+}
+
+// Contains — jti ada di denylist.
+func (d *JTIDenylist) Contains(ctx context.Context, jti string) (bool, error) {
+	if jti == "" {
+		return false, nil
+	}
+	n, err := d.RDB.Exists(ctx, d.key(jti)).Result()
+	return n > 0, err
+}
+
+// IsRevoked memenuhi RevocationChecker; token tanpa jti (token lama) dianggap tidak dicabut.
+func (d *JTIDenylist) IsRevoked(ctx context.Context, claims *TokenClaims) (bool, error) {
+	return d.Contains(ctx, claims.ID)
+}
+
+// LocalDenylist — salinan in-memory denylist jti untuk resource server (gateway, shared
+// middleware). Start berlangganan channel denylist dan melakukan resync penuh (SCAN) saat
+// subscribe dan setiap ResyncInterval, sehingga pesan yang hilang saat reconnect tetap
+// tertangkap. Sebelum sync pertama berhasil, IsRevoked bertanya langsung ke Redis.
+type LocalDenylist struct {
+	Remote         JTIDenylist
+	ResyncInterval time.Duration // default 5 menit
+
+	mu     sync.RWMutex
+	jtis   map[string]time.Time // jti -> exp
+	synced bool
+	stats  DenylistStats
+}
+
+// DenylistStats — metrik sinkronisasi LocalDenylist.
+type DenylistStats struct {
+	Entries     int       `json:"entries"`
+	Events      uint64    `json:"events"`
+	Resyncs     uint64    `json:"resyncs"`
+	LastResync  time.Time `json:"lastResync"`
+	LastError   string    `json:"lastError,omitempty"`
+	LastErrorAt time.Time `json:"lastErrorAt"`
+	Synced      bool      `json:"synced"`
+}
+
+func NewLocalDenylist(rdb *redis.Client) *LocalDenylist {
+	return &LocalDenylist{
+		Remote:         JTIDenylist{RDB: rdb},
+		ResyncInterval: defaultDenylistResync,
+		jtis:           map[string]time.Time{},
+	}
+}
+
+// Start menjalankan subscriber sampai ctx selesai. Subscribe yang gagal dicoba lagi
+// dengan backoff (1 detik, dua kali lipat, maksimal ResyncInterval).
+func (d *LocalDenylist) Start(ctx context.Context) {
+	backoff := time.Second
+	for {
+		err := d.run(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		d.recordError(err)
+		log.Printf("[Denylist] subscription failed, retrying in %s: %v", backoff, err)
+
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		if backoff *= 2; backoff > d.resyncInterval() {
+			backoff = d.resyncInterval()
+		}
+	}
+}
+
+// run berlangganan channel lalu memproses event sampai ctx selesai atau channel tertutup.
+func (d *LocalDenylist) run(ctx context.Context) error {
+	sub := d.Remote.RDB.Subscribe(ctx, d.Remote.channel())
+	defer sub.Close()
+	// tunggu konfirmasi subscribe: event setelah titik ini pasti diterima, sebelum itu
+	// tertutup oleh resync
+	if _, err := sub.Receive(ctx); err != nil {
+		return err
+	}
+	if err := d.resync(ctx); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(d.resyncInterval())
+	defer ticker.Stop()
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-ch:
+			if !ok {
+				return redis.ErrClosed
+			}
+			d.handle(msg.Payload)
+		case <-ticker.C:
+			// go-redis reconnect otomatis; pesan selama reconnect diambil dari SCAN
+			if err := d.resync(ctx); err != nil {
+				d.recordError(err)
+				log.Printf("[Denylist] resync failed, keeping %d entries: %v", d.Stats().Entries, err)
+			}
+		}
+	}
+}
+
+func (d *LocalDenylist) handle(payload string) {
+	var ev DenylistEvent
+	if err := json.Unmarshal([]byte(payload), &ev); err != nil || ev.JTI == "" {
+		return
+	}
+	exp := time.Unix(ev.Exp, 0)
+	if !time.Now().Before(exp) {
+		return
+	}
+	d.mu.Lock()
+	d.jtis[ev.JTI] = exp
+	d.stats.Events++
+	d.mu.Unlock()
+}
+
+// resync membaca seluruh key denylist dari Redis. Entri lokal tidak dihapus kecuali
+// sudah kedaluwarsa: jti tidak pernah "dipulihkan".
+func (d *LocalDenylist) resync(ctx context.Context) error {
+	prefix := d.Remote.prefix()
+	found := map[string]time.Time{}
+	now := time.Now()
+	iter := d.Remote.RDB.Scan(ctx, 0, prefix+"*", denylistScanCount).Iterator()
+	var batch []string
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		pipe := d.Remote.RDB.Pipeline()
+		ttls := make([]*redis.DurationCmd, len(batch))
+		for i, k := range batch {
+			ttls[i] = pipe.PTTL(ctx, k)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return err
+		}
+		for i, k := range batch {
+			if ttl := ttls[i].Val(); ttl > 0 {
+				found[strings.TrimPrefix(k, prefix)] = now.Add(ttl)
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) >= denylistScanCount {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for jti, exp := range d.jtis {
+		if !now.Before(exp) {
+			delete(d.jtis, jti)
+		}
+	}
+	for jti, exp := range found {
+		d.jtis[jti] = exp
+	}
+	d.synced = true
+	d.stats.Resyncs++
+	d.stats.LastResync = now
+	return nil
+}
+
+func (d *LocalDenylist) recordError(err error) {
+	d.mu.Lock()
+	d.stats.LastError = err.Error()
+	d.stats.LastErrorAt = time.Now()
+	d.mu.Unlock()
+}
+
+func (d *LocalDenylist) resyncInterval() time.Duration {
+	if d.ResyncInterval > 0 {
+		return d.ResyncInterval
+	}
+	return defaultDenylistResync
+}
+
+// IsRevoked memenuhi RevocationChecker: lookup in-memory, atau Redis bila belum tersinkron.
+func (d *LocalDenylist) IsRevoked(ctx context.Context, claims *TokenClaims) (bool, error) {
+	if claims.ID == "" {
+		return false, nil
+	}
+	d.mu.RLock()
+	exp, ok := d.jtis[claims.ID]
+	synced := d.synced
+	d.mu.RUnlock()
+	if ok {
+		return time.Now().Before(exp), nil
+	}
+	if !synced {
+		return d.Remote.Contains(ctx, claims.ID)
+	}
+	return false, nil
+}
+
+// Stats mengembalikan salinan metrik sinkronisasi.
+func (d *LocalDenylist) Stats() DenylistStats {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	st := d.stats
+	st.Entries = len(d.jtis)
+	st.Synced = d.synced
+	return st
+}
@@ -0,0 +1,243 @@
+package security
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+// DPoP (RFC 9449): client membuktikan kepemilikan private key di setiap request lewat
+// proof JWT di header "DPoP". Access token di-bind ke thumbprint key tersebut (cnf.jkt),
+// sehingga token yang bocor tidak bisa dipakai tanpa key-nya.
+
+const (
+	DPoPHeader      = "DPoP"
+	DPoPNonceHeader = "DPoP-Nonce"
+
+	dpopProofType     = "dpop+jwt"
+	defaultDPoPMaxAge = 5 * time.Minute
+	dpopClockSkew     = 30 * time.Second
+	dpopNonceTTL      = 5 * time.Minute
+)
+
+var (
+	ErrInvalidDPoPProof = errors.New("invalid dpop proof")
+	ErrUseDPoPNonce     = errors.New("dpop nonce required")
+	ErrDPoPRequired     = errors.New("dpop-bound token requires DPoP proof")
+	ErrDPoPNotBound     = errors.New("token is not dpop-bound")
+)
+
+// DPoPSigningAlgs — alg proof yang diterima (hanya asimetris).
+var DPoPSigningAlgs = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// Confirmation adalah klaim "cnf" (RFC 7800): key yang wajib dibuktikan pemegang token.
+type Confirmation struct {
+	JKT string `json:"jkt,omitempty"`      // thumbprint JWK DPoP (RFC 9449 §6)
+	X5T string `json:"x5t#S256,omitempty"` // thumbprint sertifikat mTLS (RFC 8705 §3.1)
+}
+
+// DPoPProof — hasil verifikasi proof.
+type DPoPProof struct {
+	JKT      string
+	ID       string
+	IssuedAt time.Time
+}
+
+// DPoPVerifier memverifikasi proof DPoP. Satu instance per server (auth-service / resource server).
+type DPoPVerifier struct {
+	RDB       *redis.Client // replay jti; nil => replay tidak dicek (hanya dev)
+	Prefix    string        // prefix key Redis replay
+	NonceKey  []byte        // opsional: wajibkan nonce dari server (HMAC, stateless)
+	MaxAge    time.Duration // umur maksimal proof (iat); default 5 menit
+	PublicURL string        // base URL publik resource server untuk htu; "" => dari request
+}
+
+// Verify memverifikasi proof untuk request method + htu. accessToken (opsional) wajib
+// cocok dengan klaim ath; kosong untuk request token ke auth-service.
+func (v *DPoPVerifier) Verify(ctx context.Context, proof, method, htu, accessToken string) (*DPoPProof, error) {
+	if v == nil || proof == "" {
+		return nil, ErrInvalidDPoPProof
+	}
+
+	var jwk JWK
+	claims := jwt.MapClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods(DPoPSigningAlgs))
+	if _, err := parser.ParseWithClaims(proof, claims, func(t *jwt.Token) (any, error) {
+		if typ, _ := t.Header["typ"].(string); typ != dpopProofType {
+			return nil, errors.New("unexpected typ")
+		}
+		raw, err := json.Marshal(t.Header["jwk"])
+		if err != nil || json.Unmarshal(raw, &jwk) != nil {
+			return nil, errors.New("invalid jwk header")
+		}
+		// header jwk tidak boleh memuat private key
+		var priv struct {
+			D string `json:"d"`
+		}
+		_ = json.Unmarshal(raw, &priv)
+		if priv.D != "" {
+			return nil, errors.New("jwk header contains private key")
+		}
+		return jwk.PublicKey()
+	}); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDPoPProof, err)
+	}
+
+	jti, _ := claims["jti"].(string)
+	htm, _ := claims["htm"].(string)
+	claimHTU, _ := claims["htu"].(string)
+	if jti == "" || htm != method || normalizeHTU(claimHTU) != normalizeHTU(htu) || normalizeHTU(htu) == "" {
+		return nil, ErrInvalidDPoPProof
+	}
+
+	iat, _ := claims.GetIssuedAt()
+	if iat == nil {
+		return nil, ErrInvalidDPoPProof
+	}
+	maxAge := v.MaxAge
+	if maxAge <= 0 {
+		maxAge = defaultDPoPMaxAge
+	}
+	now := time.Now()
+	if iat.Time.After(now.Add(dpopClockSkew)) || iat.Time.Before(now.Add(-maxAge)) {
+		return nil, ErrInvalidDPoPProof
+	}
+
+	if accessToken != "" {
+		sum := sha256.Sum256([]byte(accessToken))
+		if ath, _ := claims["ath"].(string); ath != base64.RawURLEncoding.EncodeToString(sum[:]) {
+			return nil, ErrInvalidDPoPProof
+		}
+	}
+	if len(v.NonceKey) > 0 {
+		if nonce, _ := claims["nonce"].(string); !v.validNonce(nonce) {
+			return nil, ErrUseDPoPNonce
+		}
+	}
+
+	jkt, err := jwk.Thumbprint()
+	if err != nil {
+		return nil, ErrInvalidDPoPProof
+	}
+	if v.RDB != nil {
+		// jti hanya boleh dipakai sekali selama jendela iat masih diterima
+		ok, err := v.RDB.SetNX(ctx, v.Prefix+"dpop:jti:"+jkt+":"+jti, 1, maxAge+dpopClockSkew).Result()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: jti replayed", ErrInvalidDPoPProof)
+		}
+	}
+	return &DPoPProof{JKT: jkt, ID: jti, IssuedAt: iat.Time}, nil
+}
+
+// VerifyBinding dipanggil resource server setelah access token diverifikasi. Token ber-cnf.jkt
+// wajib dikirim dengan skema "DPoP" + proof valid dari key yang sama; skema "DPoP" untuk
+// token tanpa binding ditolak. Token bearer biasa lolos tanpa pemeriksaan tambahan.
+func (v *DPoPVerifier) VerifyBinding(r *http.Request, scheme, accessToken string, claims *TokenClaims) error {
+	bound := claims.Confirmation != nil && claims.Confirmation.JKT != ""
+	usesDPoP := strings.EqualFold(scheme, "DPoP")
+	switch {
+	case !bound && !usesDPoP:
+		return nil
+	case !bound:
+		return ErrDPoPNotBound
+	case !usesDPoP:
+		return ErrDPoPRequired
+	}
+
+	proofs := r.Header.Values(DPoPHeader)
+	if len(proofs) != 1 {
+		return ErrInvalidDPoPProof
+	}
+	p, err := v.Verify(r.Context(), proofs[0], r.Method, v.RequestURL(r), accessToken)
+	if err != nil {
+		return err
+	}
+	if p.JKT != claims.Confirmation.JKT {
+		return fmt.Errorf("%w: key does not match token binding", ErrInvalidDPoPProof)
+	}
+	return nil
+}
+
+// RequestURL merekonstruksi htu request (tanpa query) dari PublicURL atau header request.
+func (v *DPoPVerifier) RequestURL(r *http.Request) string {
+	if v != nil && v.PublicURL != "" {
+		return strings.TrimRight(v.PublicURL, "/") + r.URL.Path
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if p := r.Header.Get("X-Forwarded-Proto"); p != "" {
+		scheme = p
+	}
+	return scheme + "://" + r.Host + r.URL.Path
+}
+
+// Challenge menulis header WWW-Authenticate (dan DPoP-Nonce bila perlu) untuk respons 401.
+func (v *DPoPVerifier) Challenge(w http.ResponseWriter, err error) {
+	code := "invalid_token"
+	switch {
+	case errors.Is(err, ErrUseDPoPNonce):
+		code = "use_dpop_nonce"
+	case errors.Is(err, ErrInvalidDPoPProof):
+		code = "invalid_dpop_proof"
+	}
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`DPoP error=%q, algs=%q`, code, strings.Join(DPoPSigningAlgs, " ")))
+	if n := v.Nonce(); n != "" {
+		w.Header().Set(DPoPNonceHeader, n)
+	}
+}
+
+// Nonce membuat nonce server baru (timestamp + HMAC); "" bila nonce tidak diwajibkan.
+func (v *DPoPVerifier) Nonce() string {
+	if v == nil || len(v.NonceKey) == 0 {
+		return ""
+	}
+	ts := make([]byte, 8)
+	binary.BigEndian.PutUint64(ts, uint64(time.Now().Unix()))
+	mac := hmac.New(sha256.New, v.NonceKey)
+	mac.Write(ts)
+	return base64.RawURLEncoding.EncodeToString(append(ts, mac.Sum(nil)...))
+}
+
+func (v *DPoPVerifier) validNonce(nonce string) bool {
+	raw, err := base64.RawURLEncoding.DecodeString(nonce)
+	if err != nil || len(raw) != 8+sha256.Size {
+		return false
+	}
+	mac := hmac.New(sha256.New, v.NonceKey)
+	mac.Write(raw[:8])
+	if !hmac.Equal(mac.Sum(nil), raw[8:]) {
+		return false
+	}
+	issued := time.Unix(int64(binary.BigEndian.Uint64(raw[:8])), 0)
+	return time.Since(issued) <= dpopNonceTTL && time.Until(issued) <= dpopClockSkew
+}
+
+// htu dibandingkan tanpa query / fragment, scheme + host case-insensitive (RFC 9449 §4.3)
+func normalizeHTU(v string) string {
+	u, err := url.Parse(v)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return ""
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	return strings.ToLower(u.Scheme) + "://" + strings.ToLower(u.Host) + path
+}
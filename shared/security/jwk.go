@@ -0,0 +1,189 @@
+package security
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+)
+
+// JWK — satu public key dalam format RFC 7517 (RSA, EC, OKP/Ed25519).
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Kid string `json:"kid,omitempty"`
+
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// EC / OKP
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// ParseJWKSet mem-parse dokumen JWKS dan memastikan setiap key valid.
+func ParseJWKSet(raw []byte) (*JWKSet, error) {
+	var set JWKSet
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, err
+	}
+	if len(set.Keys) == 0 {
+		return nil, errors.New("jwks has no keys")
+	}
+	for _, k := range set.Keys {
+		if _, err := k.PublicKey(); err != nil {
+			return nil, fmt.Errorf("jwk %q: %w", k.Kid, err)
+		}
+	}
+	return &set, nil
+}
+
+// FetchJWKSet mengambil JWKS dari URL.
+func FetchJWKSet(ctx context.Context, client *http.Client, url string) (*JWKSet, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("bad jwks status")
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&raw); err != nil {
+		return nil, err
+	}
+	return ParseJWKSet(raw)
+}
+
+// Lookup mencari key berdasarkan kid; tanpa kid hanya berhasil jika set berisi satu key.
+func (s *JWKSet) Lookup(kid string) (*JWK, error) {
+	if kid == "" {
+		if len(s.Keys) == 1 {
+			return &s.Keys[0], nil
+		}
+		return nil, errors.New("kid required")
+	}
+	for i := range s.Keys {
+		if s.Keys[i].Kid == kid {
+			return &s.Keys[i], nil
+		}
+	}
+	return nil, errors.New("kid not found")
+}
+
+// PublicKey mengonversi JWK ke *rsa.PublicKey, *ecdsa.PublicKey atau ed25519.PublicKey.
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		nb, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil || len(nb) == 0 {
+			return nil, errors.New("invalid rsa modulus")
+		}
+		eb, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(eb) == 0 {
+			return nil, errors.New("invalid rsa exponent")
+		}
+		e := 0
+		for _, b := range eb {
+			e = e<<8 + int(b)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(nb), E: e}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.New("unsupported ec curve")
+		}
+		xb, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, errors.New("invalid ec x")
+		}
+		yb, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, errors.New("invalid ec y")
+		}
+		pub := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(xb), Y: new(big.Int).SetBytes(yb)}
+		if !curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, errors.New("ec point not on curve")
+		}
+		return pub, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, errors.New("unsupported okp curve")
+		}
+		xb, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(xb) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid ed25519 key")
+		}
+		return ed25519.PublicKey(xb), nil
+	}
+	return nil, errors.New("unsupported kty")
+}
+
+// Thumbprint menghitung JWK SHA-256 thumbprint (RFC 7638): hash dari member wajib
+// key dalam urutan leksikografis, di-encode base64url.
+func (k JWK) Thumbprint() (string, error) {
+	var canonical string
+	switch k.Kty {
+	case "RSA":
+		canonical = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, k.E, k.N)
+	case "EC":
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":"EC","x":%q,"y":%q}`, k.Crv, k.X, k.Y)
+	case "OKP":
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":"OKP","x":%q}`, k.Crv, k.X)
+	default:
+		return "", errors.New("unsupported kty")
+	}
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// NewJWK membuat JWK publik dari *rsa.PublicKey, *ecdsa.PublicKey atau ed25519.PublicKey.
+func NewJWK(kid, alg string, pub crypto.PublicKey) (JWK, error) {
+	enc := base64.RawURLEncoding
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		e := new(big.Int).SetInt64(int64(k.E)).Bytes()
+		return JWK{Kty: "RSA", Alg: alg, Kid: kid, N: enc.EncodeToString(k.N.Bytes()), E: enc.EncodeToString(e)}, nil
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		return JWK{
+			Kty: "EC", Alg: alg, Kid: kid, Crv: k.Curve.Params().Name,
+			X: enc.EncodeToString(k.X.FillBytes(make([]byte, size))),
+			Y: enc.EncodeToString(k.Y.FillBytes(make([]byte, size))),
+		}, nil
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Alg: alg, Kid: kid, Crv: "Ed25519", X: enc.EncodeToString(k)}, nil
+	}
+	return JWK{}, errors.New("unsupported public key type")
+}
@@ -2,126 +2,270 @@ package security
 
 import (
 	"context"
-	"crypto/rsa"
-	"encoding/base64"
+	"crypto"
 	"encoding/json"
 	"errors"
-	"math/big"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-type jwkKey struct {
-	Kty string `json:"kty"`
-	Use string `json:"use"`
-	Alg string `json:"alg"`
-	N   string `json:"n"`
-	E   string `json:"e"`
-	Kid string `json:"kid"`
-}
+// JWKSCache menyimpan public key auth-service untuk verifikasi token di resource server.
+// Start menjalankan refresh di background mengikuti Cache-Control max-age; bila fetch gagal
+// key set terakhir tetap dipakai (stale-while-error). kid yang tidak dikenal memicu refetch
+// paksa paling sering sekali per MinRefreshInterval, sehingga token palsu dengan kid acak
+// tidak berubah menjadi banjir request ke auth-service.
+type JWKSCache struct {
+	URL                string
+	TTL                time.Duration // interval refresh bila respons tanpa Cache-Control max-age
+	MinRefreshInterval time.Duration // jarak minimal antar fetch (refresh & refetch kid tak dikenal)
+	MaxRefreshInterval time.Duration // batas atas max-age dari auth-service
+	Client             *http.Client
+
+	mu         sync.RWMutex
+	keys       map[string]jwksKey
+	expiresAt  time.Time
+	lastForced time.Time
 
-type jwksPayload struct {
-	Keys []jwkKey `json:"keys"`
+	fetchMu sync.Mutex // satu fetch pada satu waktu; request lain menunggu hasilnya
+	stats   JWKSStats
 }
 
-type JWKSCache struct {
-	URL       string
-	mu        sync.RWMutex
-	keys      map[string]*rsa.PublicKey
-	expiresAt time.Time
-	TTL       time.Duration
-	Client    *http.Client
+// JWKSStats — metrik hasil refresh JWKS.
+type JWKSStats struct {
+	Refreshes          uint64    `json:"refreshes"`
+	RefreshErrors      uint64    `json:"refreshErrors"`
+	ForcedRefetches    uint64    `json:"forcedRefetches"`    // kid tidak dikenal
+	ThrottledRefetches uint64    `json:"throttledRefetches"` // kid tidak dikenal, ditolak tanpa fetch
+	Keys               int       `json:"keys"`
+	LastRefresh        time.Time `json:"lastRefresh"`
+	LastError          string    `json:"lastError,omitempty"`
+	LastErrorAt        time.Time `json:"lastErrorAt"`
+	Stale              bool      `json:"stale"` // key set sudah melewati masa berlakunya
 }
 
+const (
+	defaultJWKSMinRefresh = 30 * time.Second
+	defaultJWKSMaxRefresh = 24 * time.Hour
+	jwksMaxBody           = 1 << 20
+)
+
 func NewJWKSCache(url string, ttl time.Duration) *JWKSCache {
 	return &JWKSCache{
-		URL:    url,
-		TTL:    ttl,
-		Client: &http.Client{Timeout: 5 * time.Second},
-		keys:   map[string]*rsa.PublicKey{},
+		URL:                url,
+		TTL:                ttl,
+		MinRefreshInterval: defaultJWKSMinRefresh,
+		MaxRefreshInterval: defaultJWKSMaxRefresh,
+		Client:             &http.Client{Timeout: 5 * time.Second},
+		keys:               map[string]jwksKey{},
 	}
 }
 
+// jwksKey — public key dari JWKS; alg kosong bila JWK tidak menyebutkan alg.
+type jwksKey struct {
+	alg string
+	pub crypto.PublicKey
+}
+
+// Start menjalankan refresh background sampai ctx selesai. Gagal fetch dicoba lagi
+// dengan backoff (MinRefreshInterval, dua kali lipat, maksimal TTL).
+func (c *JWKSCache) Start(ctx context.Context) {
+	backoff := c.minInterval()
+	for {
+		wait := backoff
+		if err := c.refresh(ctx); err != nil {
+			log.Printf("[JWKS] refresh failed, keeping %d cached keys: %v", c.Stats().Keys, err)
+			if backoff *= 2; backoff > c.TTL && c.TTL > 0 {
+				backoff = c.TTL
+			}
+		} else {
+			backoff = c.minInterval()
+			c.mu.RLock()
+			wait = time.Until(c.expiresAt)
+			c.mu.RUnlock()
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Stats mengembalikan salinan metrik refresh.
+func (c *JWKSCache) Stats() JWKSStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	st := c.stats
+	st.Keys = len(c.keys)
+	st.Stale = !c.expiresAt.IsZero() && time.Now().After(c.expiresAt)
+	return st
+}
+
+func (c *JWKSCache) minInterval() time.Duration {
+	if c.MinRefreshInterval > 0 {
+		return c.MinRefreshInterval
+	}
+	return defaultJWKSMinRefresh
+}
+
+// refresh mengambil JWKS; key set lama hanya diganti bila respons valid.
 func (c *JWKSCache) refresh(ctx context.Context) error {
-	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, c.URL, nil)
-	resp, err := c.Client.Do(req)
+	c.fetchMu.Lock()
+	defer c.fetchMu.Unlock()
+
+	keys, ttl, err := c.fetch(ctx)
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if err != nil {
+		c.stats.RefreshErrors++
+		c.stats.LastError = err.Error()
+		c.stats.LastErrorAt = time.Now()
 		return err
 	}
+	c.keys = keys
+	c.expiresAt = time.Now().Add(ttl)
+	c.stats.Refreshes++
+	c.stats.LastRefresh = time.Now()
+	return nil
+}
+
+func (c *JWKSCache) fetch(ctx context.Context) (map[string]jwksKey, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return errors.New("bad jwks status")
+		return nil, 0, fmt.Errorf("bad jwks status %d", resp.StatusCode)
 	}
-	var p jwksPayload
-	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
-		return err
+	var p JWKSet
+	if err := json.NewDecoder(io.LimitReader(resp.Body, jwksMaxBody)).Decode(&p); err != nil {
+		return nil, 0, err
 	}
-	m := make(map[string]*rsa.PublicKey)
+	m := make(map[string]jwksKey)
 	for _, k := range p.Keys {
-		nb, err := base64.RawURLEncoding.DecodeString(k.N)
-		if err != nil {
+		if k.Use != "" && k.Use != "sig" {
 			continue
 		}
-		eb, err := base64.RawURLEncoding.DecodeString(k.E)
+		pub, err := k.PublicKey()
 		if err != nil {
 			continue
 		}
-		e := 0
-		for _, b := range eb {
-			e = e<<8 + int(b)
+		m[k.Kid] = jwksKey{alg: k.Alg, pub: pub}
+	}
+	if len(m) == 0 {
+		return nil, 0, errors.New("jwks has no usable keys")
+	}
+	return m, c.refreshInterval(resp.Header.Get("Cache-Control")), nil
+}
+
+// refreshInterval — max-age Cache-Control (no-cache / no-store => MinRefreshInterval),
+// selain itu TTL; dibatasi [MinRefreshInterval, MaxRefreshInterval].
+func (c *JWKSCache) refreshInterval(cacheControl string) time.Duration {
+	d := c.TTL
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-cache" || directive == "no-store":
+			d = 0
+		case strings.HasPrefix(directive, "max-age="):
+			if n, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age=")); err == nil && n >= 0 {
+				d = time.Duration(n) * time.Second
+			}
 		}
-		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(nb), E: e}
-		m[k.Kid] = pub
 	}
-	c.mu.Lock()
-	c.keys = m
-	c.expiresAt = time.Now().Add(c.TTL)
-	c.mu.Unlock()
-	return nil
+	if d < c.minInterval() {
+		d = c.minInterval()
+	}
+	if c.MaxRefreshInterval > 0 && d > c.MaxRefreshInterval {
+		d = c.MaxRefreshInterval
+	}
+	return d
 }
 
-func (c *JWKSCache) keyForKid(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+// keyForKid mencari key di cache. Cache kosong / kedaluwarsa (tanpa Start) di-refresh;
+// kid tidak dikenal memicu refetch paksa yang dibatasi MinRefreshInterval.
+func (c *JWKSCache) keyForKid(ctx context.Context, kid string) (jwksKey, error) {
 	c.mu.RLock()
-	if time.Now().Before(c.expiresAt) {
-		if k, found := c.keys[kid]; found {
-			c.mu.RUnlock()
-			return k, nil
+	k, found := c.keys[kid]
+	expired := time.Now().After(c.expiresAt)
+	c.mu.RUnlock()
+	if found && !expired {
+		return k, nil
+	}
+
+	if found {
+		// stale: refresh seperti refetch paksa; gagal => key lama tetap dipakai
+		if c.allowForced() {
+			_ = c.refresh(ctx)
 		}
+		return k, nil
 	}
-	c.mu.RUnlock()
-	_ = c.refresh(ctx)
+
+	if !c.allowForced() {
+		c.mu.Lock()
+		c.stats.ThrottledRefetches++
+		c.mu.Unlock()
+		return jwksKey{}, errors.New("kid not found")
+	}
+	c.mu.Lock()
+	c.stats.ForcedRefetches++
+	c.mu.Unlock()
+	if err := c.refresh(ctx); err != nil {
+		log.Printf("[JWKS] refetch for kid %q failed: %v", kid, err)
+	}
+
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	if k, found := c.keys[kid]; found {
 		return k, nil
 	}
-	return nil, errors.New("kid not found")
+	return jwksKey{}, errors.New("kid not found")
 }
 
-func (c *JWKSCache) VerifyRS256(token string, expectedIssuer string) (*jwt.Token, *TokenClaims, error) {
-	parser := jwt.Parser{}
-	var claims TokenClaims
-	tok, err := parser.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
-		if t.Method != jwt.SigningMethodRS256 {
-			return nil, errors.New("alg")
-		}
-		kid, _ := t.Header["kid"].(string)
-		if kid == "" {
-			return nil, errors.New("no kid")
-		}
-		return c.keyForKid(context.Background(), kid)
-	})
-	if err != nil {
-		return nil, nil, err
+// allowForced — refetch di luar jadwal hanya bila fetch terakhir sudah lewat MinRefreshInterval
+func (c *JWKSCache) allowForced() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	last := c.lastForced
+	if c.stats.LastRefresh.After(last) {
+		last = c.stats.LastRefresh
 	}
-	if !tok.Valid {
-		return nil, nil, errors.New("invalid")
+	if now.Sub(last) < c.minInterval() {
+		return false
 	}
-	if expectedIssuer != "" && claims.Issuer != expectedIssuer {
-		return nil, nil, errors.New("iss mismatch")
+	c.lastForced = now
+	return true
+}
+
+// Verify memverifikasi token (RS256 / ES256 / EdDSA) via JWKS. expectedAudience (opsional)
+// — token harus memuat salah satunya di klaim aud (RFC 8707 resource server).
+func (c *JWKSCache) Verify(token string, expectedIssuer string, expectedAudience ...string) (*jwt.Token, *TokenClaims, error) {
+	return c.VerifyContext(context.Background(), token, expectedIssuer, expectedAudience...)
+}
+
+// VerifyContext seperti Verify; ctx membatasi refetch JWKS (mis. context request).
+// Hanya access token yang diterima; kebijakan lain lewat Validator dengan Keys: c.
+func (c *JWKSCache) VerifyContext(ctx context.Context, token string, expectedIssuer string, expectedAudience ...string) (*jwt.Token, *TokenClaims, error) {
+	v := &Validator{Keys: c, Audiences: expectedAudience}
+	if expectedIssuer != "" {
+		v.Issuers = []string{expectedIssuer}
 	}
-	return tok, &claims, nil
+	return v.validate(ctx, token)
 }
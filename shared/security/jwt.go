@@ -5,6 +5,7 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/hex"
 	"encoding/pem"
 	"errors"
 	"os"
@@ -63,12 +64,52 @@ type TokenClaims struct {
 	// >>> NEW: tenant marker disematkan ke JWT
 	TenantID string `json:"tenantId,omitempty"`
 
+	// rantai actor token exchange (RFC 8693 "act"); nil => token dipakai langsung oleh subject
+	Actor *Actor `json:"act,omitempty"`
+
+	// kunci pemegang token (RFC 7800 "cnf"); nil => bearer token biasa
+	Confirmation *Confirmation `json:"cnf,omitempty"`
+
 	jwt.RegisteredClaims
 }
 
+// Actor adalah klaim "act" RFC 8693 §4.1: pihak yang bertindak atas nama subject.
+// Actor sebelumnya (delegasi berantai) disarangkan di field Actor.
+type Actor struct {
+	Subject  string `json:"sub"`
+	ClientID string `json:"client_id,omitempty"`
+	Actor    *Actor `json:"act,omitempty"`
+}
+
+// Chain mengembalikan subject actor dari yang terbaru sampai yang paling awal.
+func (a *Actor) Chain() []string {
+	var out []string
+	for cur := a; cur != nil; cur = cur.Actor {
+		out = append(out, cur.Subject)
+	}
+	return out
+}
+
+// HasAudience — klaim aud token memuat salah satu audience yang diharapkan.
+func (c *TokenClaims) HasAudience(expected ...string) bool {
+	for _, aud := range c.RegisteredClaims.Audience {
+		for _, want := range expected {
+			if want != "" && aud == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (s *RS256Signer) Sign(claims TokenClaims, ttl time.Duration) (string, error) {
 	now := time.Now()
+	jti := claims.ID
+	if jti == "" {
+		jti = NewJTI()
+	}
 	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        jti,
 		Issuer:    s.issuer,
 		Subject:   subject(claims.UserID, claims.ClientID),
 		Audience:  claims.Audience,
@@ -79,6 +120,13 @@ func (s *RS256Signer) Sign(claims TokenClaims, ttl time.Duration) (string, error
 	return token.SignedString(s.private)
 }
 
+// NewJTI — id token acak (klaim jti) untuk denylist pencabutan.
+func NewJTI() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
 func subject(userID, clientID string) string {
 	if userID != "" {
 		return "user:" + userID
@@ -104,6 +152,20 @@ func (s *RS256Signer) RandBytes(n int) []byte {
 	return b
 }
 
+var ErrAudienceMismatch = errors.New("aud mismatch")
+
+// ParseAndVerifyAudience seperti ParseAndVerify, tetapi token juga harus ditujukan ke audience.
+func ParseAndVerifyAudience(tokenStr string, pub *rsa.PublicKey, audience string) (*TokenClaims, error) {
+	claims, err := ParseAndVerify(tokenStr, pub)
+	if err != nil {
+		return nil, err
+	}
+	if !claims.HasAudience(audience) {
+		return nil, ErrAudienceMismatch
+	}
+	return claims, nil
+}
+
 func ParseAndVerify(tokenStr string, pub *rsa.PublicKey) (*TokenClaims, error) {
 	tok, err := jwt.ParseWithClaims(tokenStr, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if token.Method != jwt.SigningMethodRS256 {
@@ -119,74 +181,3 @@ func ParseAndVerify(tokenStr string, pub *rsa.PublicKey) (*TokenClaims, error) {
 	}
 	return nil, errors.New("invalid token")
 }
-
-type RS256Key struct {
-	KID  string
-	Priv *rsa.PrivateKey
-	Pub  *rsa.PublicKey
-}
-
-type RS256KeyStore struct {
-	Active string
-	Keys   map[string]*RS256Key
-	Issuer string
-}
-
-func MustLoadKeyStore(activeKid, issuer string, pairs map[string]struct {
-	PrivatePath string
-	PublicPath  string
-}) *RS256KeyStore {
-	ks := &RS256KeyStore{Active: activeKid, Issuer: issuer, Keys: map[string]*RS256Key{}}
-	for kid, p := range pairs {
-		privPEM, err := os.ReadFile(p.PrivatePath)
-		if err != nil {
-			panic(err)
-		}
-		block, _ := pem.Decode(privPEM)
-		if block == nil {
-			panic("invalid private pem")
-		}
-		priv, err := x509.ParsePKCS1PrivateKey(block.Bytes)
-		if err != nil {
-			panic(err)
-		}
-
-		pubPEM, err := os.ReadFile(p.PublicPath)
-		if err != nil {
-			panic(err)
-		}
-		pb, _ := pem.Decode(pubPEM)
-		if pb == nil {
-			panic("invalid public pem")
-		}
-		ifc, err := x509.ParsePKIXPublicKey(pb.Bytes)
-		if err != nil {
-			panic(err)
-		}
-		pub, ok := ifc.(*rsa.PublicKey)
-		if !ok {
-			panic("not rsa public key")
-		}
-
-		ks.Keys[kid] = &RS256Key{KID: kid, Priv: priv, Pub: pub}
-	}
-	if _, ok := ks.Keys[activeKid]; !ok {
-		panic("active kid not found")
-	}
-	return ks
-}
-
-func (ks *RS256KeyStore) SignWithActive(claims TokenClaims, ttl time.Duration) (string, error) {
-	key := ks.Keys[ks.Active]
-	now := time.Now()
-	claims.RegisteredClaims = jwt.RegisteredClaims{
-		Issuer:    ks.Issuer,
-		Subject:   subject(claims.UserID, claims.ClientID),
-		Audience:  claims.Audience,
-		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
-		IssuedAt:  jwt.NewNumericDate(now),
-	}
-	t := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	t.Header["kid"] = key.KID
-	return t.SignedString(key.Priv)
-}
@@ -0,0 +1,438 @@
+package security
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeyStore menyimpan signing key auth-service beserta siklus hidupnya:
+//   - upcoming: sebelum NotBefore; sudah dipublikasikan di JWKS supaya cache verifier siap
+//   - active:   key dengan NotBefore terbaru yang NotBefore <= now < RetireAfter
+//   - retiring: setelah RetireAfter; tidak dipakai menandatangani, tetap dipublikasikan
+//     dan diverifikasi selama RetireGrace supaya token in-flight tetap valid
+//
+// Setelah itu key dibuang. Key dari direktori dibaca ulang berkala (Run), sehingga
+// rotasi cukup dengan menaruh file key baru tanpa redeploy.
+
+// SupportedSigningAlgs — alg yang bisa dipakai signing key.
+var SupportedSigningAlgs = []string{"RS256", "ES256", "EdDSA"}
+
+const defaultRetireGrace = 24 * time.Hour
+
+type SigningKey struct {
+	KID         string
+	Alg         string // RS256 | ES256 | EdDSA
+	Priv        crypto.Signer
+	Pub         crypto.PublicKey
+	NotBefore   time.Time // zero => langsung boleh aktif
+	RetireAfter time.Time // zero => tidak pernah pensiun
+}
+
+// keyMetadata — isi <kid>.json di samping <kid>.pem (semua field opsional).
+type keyMetadata struct {
+	KID         string     `json:"kid"`
+	Alg         string     `json:"alg"`
+	NotBefore   *time.Time `json:"not_before"`
+	RetireAfter *time.Time `json:"retire_after"`
+}
+
+type KeyStore struct {
+	Issuer      string
+	RetireGrace time.Duration // default 24 jam; minimal umur token terpanjang yang ditandatangani
+	Pinned      string        // kid yang dipaksa aktif (konfigurasi lama JWT_ACTIVE_KID)
+
+	dir        string
+	mu         sync.RWMutex
+	keys       map[string]*SigningKey
+	lastActive string
+}
+
+// RS256KeyStore — nama lama KeyStore (sebelum mendukung ES256 / EdDSA).
+type RS256KeyStore = KeyStore
+
+// NewKeyStore membuat key store dari key yang sudah dimuat.
+func NewKeyStore(issuer string, keys ...*SigningKey) (*KeyStore, error) {
+	ks := &KeyStore{Issuer: issuer, keys: map[string]*SigningKey{}}
+	for _, k := range keys {
+		if err := validateSigningKey(k); err != nil {
+			return nil, err
+		}
+		ks.keys[k.KID] = k
+	}
+	if _, err := ks.Active(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// LoadKeyStoreDir memuat setiap <kid>.pem (private key PKCS1 / PKCS8 / SEC1) di dir,
+// dengan metadata opsional <kid>.json.
+func LoadKeyStoreDir(dir, issuer string, retireGrace time.Duration) (*KeyStore, error) {
+	ks := &KeyStore{Issuer: issuer, RetireGrace: retireGrace, dir: dir}
+	if err := ks.Reload(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+func MustLoadKeyStoreDir(dir, issuer string, retireGrace time.Duration) *KeyStore {
+	ks, err := LoadKeyStoreDir(dir, issuer, retireGrace)
+	if err != nil {
+		panic(err)
+	}
+	return ks
+}
+
+// MustLoadKeyStore memuat pasangan key RSA (konfigurasi lama); activeKid selalu aktif,
+// key lain hanya untuk verifikasi.
+func MustLoadKeyStore(activeKid, issuer string, pairs map[string]struct {
+	PrivatePath string
+	PublicPath  string
+}) *KeyStore {
+	var keys []*SigningKey
+	for kid, p := range pairs {
+		k, err := loadSigningKey(p.PrivatePath, kid)
+		if err != nil {
+			panic(err)
+		}
+		keys = append(keys, k)
+	}
+	ks, err := NewKeyStore(issuer, keys...)
+	if err != nil {
+		panic(err)
+	}
+	if _, ok := ks.keys[activeKid]; !ok {
+		panic("active kid not found")
+	}
+	ks.Pinned = activeKid
+	return ks
+}
+
+// Reload membaca ulang direktori key; bila gagal, key lama tetap dipakai.
+func (ks *KeyStore) Reload() error {
+	if ks.dir == "" {
+		return nil
+	}
+	paths, err := filepath.Glob(filepath.Join(ks.dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+	keys := map[string]*SigningKey{}
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		k, err := loadSigningKey(path, kid)
+		if err != nil {
+			return fmt.Errorf("key %s: %w", path, err)
+		}
+		if _, dup := keys[k.KID]; dup {
+			return fmt.Errorf("duplicate kid %q", k.KID)
+		}
+		keys[k.KID] = k
+	}
+
+	next := &KeyStore{keys: keys, RetireGrace: ks.RetireGrace}
+	if _, err := next.Active(); err != nil {
+		return err
+	}
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.mu.Unlock()
+	return nil
+}
+
+// Run membaca ulang direktori key setiap interval dan mencatat pergantian key aktif;
+// interval <= 0 => reload mati.
+func (ks *KeyStore) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := ks.Reload(); err != nil {
+				log.Printf("[KeyStore] reload failed, keeping current keys: %v", err)
+			}
+			if k, err := ks.Active(); err == nil {
+				ks.mu.Lock()
+				if ks.lastActive != k.KID {
+					log.Printf("[KeyStore] active signing key %s (%s)", k.KID, k.Alg)
+					ks.lastActive = k.KID
+				}
+				ks.mu.Unlock()
+			}
+		}
+	}
+}
+
+// Active mengembalikan key untuk menandatangani saat ini.
+func (ks *KeyStore) Active() (*SigningKey, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	if k, ok := ks.keys[ks.Pinned]; ok {
+		return k, nil
+	}
+
+	now := time.Now()
+	var active, fallback *SigningKey
+	for _, k := range ks.keys {
+		if k.NotBefore.After(now) {
+			continue
+		}
+		if newerKey(k, fallback) {
+			fallback = k
+		}
+		if (k.RetireAfter.IsZero() || now.Before(k.RetireAfter)) && newerKey(k, active) {
+			active = k
+		}
+	}
+	if active != nil {
+		return active, nil
+	}
+	// semua key sudah pensiun: tetap menandatangani dengan key terbaru daripada gagal total
+	if fallback != nil && ks.published(fallback, now) {
+		log.Printf("[KeyStore] no unretired signing key, using %s", fallback.KID)
+		return fallback, nil
+	}
+	return nil, errors.New("no active signing key")
+}
+
+func newerKey(k, than *SigningKey) bool {
+	return than == nil || k.NotBefore.After(than.NotBefore) ||
+		(k.NotBefore.Equal(than.NotBefore) && k.KID > than.KID)
+}
+
+// published — key masih boleh diverifikasi / dipublikasikan (belum lewat RetireGrace)
+func (ks *KeyStore) published(k *SigningKey, now time.Time) bool {
+	if k.RetireAfter.IsZero() {
+		return true
+	}
+	grace := ks.RetireGrace
+	if grace <= 0 {
+		grace = defaultRetireGrace
+	}
+	return now.Before(k.RetireAfter.Add(grace))
+}
+
+// Lookup mengembalikan key yang masih dipublikasikan berdasarkan kid.
+func (ks *KeyStore) Lookup(kid string) (*SigningKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	k, ok := ks.keys[kid]
+	if !ok || (kid != ks.Pinned && !ks.published(k, time.Now())) {
+		return nil, false
+	}
+	return k, true
+}
+
+// JWKS — public key upcoming, active dan retiring, diurutkan berdasarkan kid.
+func (ks *KeyStore) JWKS() JWKSet {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	now := time.Now()
+	set := JWKSet{Keys: []JWK{}}
+	for kid, k := range ks.keys {
+		if kid != ks.Pinned && !ks.published(k, now) {
+			continue
+		}
+		jwk, err := NewJWK(k.KID, k.Alg, k.Pub)
+		if err != nil {
+			continue
+		}
+		jwk.Use = "sig"
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+// SignWithActive menandatangani claims dengan key aktif; jti acak bila claims.ID kosong.
+func (ks *KeyStore) SignWithActive(claims TokenClaims, ttl time.Duration) (string, error) {
+	now := time.Now()
+	jti := claims.ID
+	if jti == "" {
+		jti = NewJTI()
+	}
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        jti,
+		Issuer:    ks.Issuer,
+		Subject:   subject(claims.UserID, claims.ClientID),
+		Audience:  claims.Audience,
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(now),
+	}
+	key, err := ks.Active()
+	if err != nil {
+		return "", err
+	}
+	return key.sign(claims)
+}
+
+// Verify memverifikasi token yang ditandatangani key store ini (dipilih via header kid).
+func (ks *KeyStore) Verify(tokenStr string) (*TokenClaims, error) {
+	var claims TokenClaims
+	tok, err := jwt.ParseWithClaims(tokenStr, &claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, ok := ks.Lookup(kid)
+		if !ok {
+			return nil, errors.New("kid not found")
+		}
+		if t.Method.Alg() != key.Alg {
+			return nil, errors.New("alg does not match key")
+		}
+		return key.Pub, nil
+	}, jwt.WithValidMethods(SupportedSigningAlgs))
+	if err != nil {
+		return nil, err
+	}
+	if !tok.Valid {
+		return nil, errors.New("invalid token")
+	}
+	if ks.Issuer != "" && claims.Issuer != ks.Issuer {
+		return nil, errors.New("iss mismatch")
+	}
+	return &claims, nil
+}
+
+func (k *SigningKey) sign(claims jwt.Claims) (string, error) {
+	return k.signTyped(claims, "")
+}
+
+// signTyped — typ kosong => header typ default ("JWT")
+func (k *SigningKey) signTyped(claims jwt.Claims, typ string) (string, error) {
+	method := jwt.GetSigningMethod(k.Alg)
+	if method == nil {
+		return "", fmt.Errorf("unsupported alg %q", k.Alg)
+	}
+	t := jwt.NewWithClaims(method, claims)
+	t.Header["kid"] = k.KID
+	if typ != "" {
+		t.Header["typ"] = typ
+	}
+	return t.SignedString(k.Priv)
+}
+
+func validateSigningKey(k *SigningKey) error {
+	if k.KID == "" {
+		return errors.New("signing key without kid")
+	}
+	want, err := algForKey(k.Priv)
+	if err != nil {
+		return fmt.Errorf("key %s: %w", k.KID, err)
+	}
+	if k.Alg == "" {
+		k.Alg = want
+	}
+	if k.Alg != want {
+		return fmt.Errorf("key %s: alg %s does not match key type (%s)", k.KID, k.Alg, want)
+	}
+	if k.Pub == nil {
+		k.Pub = k.Priv.Public()
+	}
+	if !k.RetireAfter.IsZero() && !k.RetireAfter.After(k.NotBefore) {
+		return fmt.Errorf("key %s: retire_after must be after not_before", k.KID)
+	}
+	return nil
+}
+
+// loadSigningKey membaca private key PEM dan metadata <kid>.json (bila ada).
+func loadSigningKey(path, kid string) (*SigningKey, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	priv, err := parsePrivateKeyPEM(raw)
+	if err != nil {
+		return nil, err
+	}
+	k := &SigningKey{KID: kid, Priv: priv}
+
+	metaPath := strings.TrimSuffix(path, ".pem") + ".json"
+	if b, err := os.ReadFile(metaPath); err == nil {
+		var md keyMetadata
+		if err := json.Unmarshal(b, &md); err != nil {
+			return nil, fmt.Errorf("%s: %w", metaPath, err)
+		}
+		if md.KID != "" {
+			k.KID = md.KID
+		}
+		k.Alg = md.Alg
+		if md.NotBefore != nil {
+			k.NotBefore = *md.NotBefore
+		}
+		if md.RetireAfter != nil {
+			k.RetireAfter = *md.RetireAfter
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	if err := validateSigningKey(k); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+func parsePrivateKeyPEM(raw []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("invalid private pem")
+	}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("unsupported private key")
+		}
+		return signer, nil
+	}
+	return nil, fmt.Errorf("unsupported pem type %q", block.Type)
+}
+
+// algForKey — RSA => RS256, EC P-256 => ES256, Ed25519 => EdDSA
+func algForKey(priv crypto.Signer) (string, error) {
+	switch k := priv.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 2048 {
+			return "", errors.New("rsa key must be at least 2048 bits")
+		}
+		return "RS256", nil
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return "", errors.New("only P-256 ec keys are supported")
+		}
+		return "ES256", nil
+	case ed25519.PrivateKey:
+		return "EdDSA", nil
+	}
+	return "", errors.New("unsupported private key type")
+}
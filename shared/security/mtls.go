@@ -0,0 +1,46 @@
+package security
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"net/http"
+)
+
+// Certificate-bound access token (RFC 8705 §3): token memuat cnf.x5t#S256 = thumbprint
+// sertifikat client mTLS; resource server yang men-terminate TLS mencocokkannya dengan
+// sertifikat koneksi sehingga token tidak bisa dipakai dari koneksi lain.
+
+var ErrCertificateBinding = errors.New("certificate-bound token requires the bound client certificate")
+
+// CertificateThumbprint — x5t#S256: base64url(SHA-256(DER sertifikat)).
+func CertificateThumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// PeerCertificate — sertifikat client (leaf) koneksi TLS request; nil bila tidak ada.
+func PeerCertificate(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+	return r.TLS.PeerCertificates[0]
+}
+
+// VerifyCertificateBinding — token ber-cnf.x5t#S256 hanya diterima lewat koneksi mTLS
+// dengan sertifikat yang sama; token tanpa binding sertifikat lolos.
+func VerifyCertificateBinding(r *http.Request, claims *TokenClaims) error {
+	if claims.Confirmation == nil || claims.Confirmation.X5T == "" {
+		return nil
+	}
+	cert := PeerCertificate(r)
+	if cert == nil {
+		return ErrCertificateBinding
+	}
+	if subtle.ConstantTimeCompare([]byte(CertificateThumbprint(cert)), []byte(claims.Confirmation.X5T)) != 1 {
+		return ErrCertificateBinding
+	}
+	return nil
+}
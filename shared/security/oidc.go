@@ -0,0 +1,120 @@
+package security
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// IDTokenClaims adalah payload id_token OpenID Connect.
+type IDTokenClaims struct {
+	Nonce    string `json:"nonce,omitempty"`
+	AuthTime int64  `json:"auth_time,omitempty"`
+	AtHash   string `json:"at_hash,omitempty"`
+	AZP      string `json:"azp,omitempty"`
+	TenantID string `json:"tenantId,omitempty"`
+
+	UserID      string   `json:"-"`
+	Audience    []string `json:"-"`
+	AccessToken string   `json:"-"` // diisi => at_hash dihitung sesuai alg key penanda tangan
+
+	jwt.RegisteredClaims
+}
+
+// SignIDToken menandatangani id_token dengan key aktif. Subject mengikuti
+// format access token ("user:<id>") supaya cocok dengan /oauth/userinfo.
+func (ks *KeyStore) SignIDToken(claims IDTokenClaims, ttl time.Duration) (string, error) {
+	key, err := ks.Active()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		Issuer:    ks.Issuer,
+		Subject:   subject(claims.UserID, ""),
+		Audience:  claims.Audience,
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(now),
+	}
+	if claims.AccessToken != "" {
+		claims.AtHash = AccessTokenHashAlg(key.Alg, claims.AccessToken)
+	}
+	return key.sign(claims)
+}
+
+// VerifyIDTokenHint memverifikasi id_token_hint end_session: signature key store ini dan
+// iss; exp diabaikan karena RP boleh mengirim id_token yang sudah kedaluwarsa.
+func (ks *KeyStore) VerifyIDTokenHint(token string) (*IDTokenClaims, error) {
+	var claims IDTokenClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, ok := ks.Lookup(kid)
+		if !ok {
+			return nil, errors.New("kid not found")
+		}
+		if t.Method.Alg() != key.Alg {
+			return nil, errors.New("alg does not match key")
+		}
+		return key.Pub, nil
+	}, jwt.WithValidMethods(SupportedSigningAlgs), jwt.WithoutClaimsValidation())
+	if err != nil {
+		return nil, err
+	}
+	if ks.Issuer != "" && claims.Issuer != ks.Issuer {
+		return nil, errors.New("iss mismatch")
+	}
+	return &claims, nil
+}
+
+// BackchannelLogoutEvent — anggota klaim events logout_token (OIDC Back-Channel Logout 1.0).
+const BackchannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+
+// LogoutTokenClaims adalah payload logout_token; tanpa nonce, sub mengikuti format id_token.
+type LogoutTokenClaims struct {
+	Events map[string]struct{} `json:"events"`
+	SID    string              `json:"sid,omitempty"`
+
+	jwt.RegisteredClaims
+}
+
+// SignLogoutToken menandatangani logout_token (header typ logout+jwt) untuk client.
+func (ks *KeyStore) SignLogoutToken(clientID, userID string, ttl time.Duration) (string, error) {
+	key, err := ks.Active()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	claims := LogoutTokenClaims{
+		Events: map[string]struct{}{BackchannelLogoutEvent: {}},
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        NewJTI(),
+			Issuer:    ks.Issuer,
+			Subject:   subject(userID, ""),
+			Audience:  []string{clientID},
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+	return key.signTyped(claims, "logout+jwt")
+}
+
+// AccessTokenHash menghitung at_hash (OIDC Core 3.1.3.6) untuk token RS256:
+// base64url dari separuh kiri SHA-256 access token.
+func AccessTokenHash(accessToken string) string {
+	return AccessTokenHashAlg("RS256", accessToken)
+}
+
+// AccessTokenHashAlg — at_hash sesuai alg id_token: SHA-256 untuk RS256 / ES256,
+// SHA-512 untuk EdDSA (Ed25519).
+func AccessTokenHashAlg(alg, accessToken string) string {
+	if alg == "EdDSA" {
+		sum := sha512.Sum512([]byte(accessToken))
+		return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+	}
+	sum := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+}
@@ -0,0 +1,78 @@
+package security
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+const secretBoxPrefix = "v1:"
+
+// SecretBox mengenkripsi data sensitif at-rest (mis. TOTP secret) dengan AES-256-GCM.
+// Format hasil: "v1:" + base64url(nonce || ciphertext).
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox menerima key 32 byte.
+func NewSecretBox(key []byte) (*SecretBox, error) {
+	if len(key) != 32 {
+		return nil, errors.New("secretbox key must be 32 bytes")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretBox{aead: aead}, nil
+}
+
+// NewSecretBoxFromBase64 membaca key dari string base64 (std / url, dengan / tanpa padding).
+func NewSecretBoxFromBase64(encoded string) (*SecretBox, error) {
+	encoded = strings.TrimSpace(encoded)
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if key, err := enc.DecodeString(encoded); err == nil {
+			return NewSecretBox(key)
+		}
+	}
+	return nil, errors.New("secretbox key is not valid base64")
+}
+
+func (b *SecretBox) Seal(plaintext string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	out := b.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return secretBoxPrefix + base64.RawURLEncoding.EncodeToString(out), nil
+}
+
+func (b *SecretBox) Open(sealed string) (string, error) {
+	if !IsSealed(sealed) {
+		return "", errors.New("value is not sealed")
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(sealed, secretBoxPrefix))
+	if err != nil {
+		return "", err
+	}
+	ns := b.aead.NonceSize()
+	if len(raw) < ns {
+		return "", errors.New("sealed value too short")
+	}
+	pt, err := b.aead.Open(nil, raw[:ns], raw[ns:], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(pt), nil
+}
+
+// IsSealed — nilai hasil Seal (bukan plaintext lama)
+func IsSealed(v string) bool {
+	return strings.HasPrefix(v, secretBoxPrefix)
+}
@@ -0,0 +1,181 @@
+package security
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrIssuerMismatch = errors.New("iss mismatch")
+	ErrTokenType      = errors.New("token type not allowed")
+	ErrMissingClaim   = errors.New("required claim missing")
+	ErrTokenRevoked   = errors.New("token revoked")
+)
+
+// KeySource memilih public key verifikasi untuk token (berdasarkan header kid / alg).
+type KeySource interface {
+	VerificationKey(ctx context.Context, t *jwt.Token) (crypto.PublicKey, error)
+}
+
+// RevocationChecker — lookup pencabutan token (mis. denylist jti); true => token ditolak.
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, claims *TokenClaims) (bool, error)
+}
+
+// RevocationFunc mengadaptasi fungsi biasa menjadi RevocationChecker.
+type RevocationFunc func(ctx context.Context, claims *TokenClaims) (bool, error)
+
+func (f RevocationFunc) IsRevoked(ctx context.Context, claims *TokenClaims) (bool, error) {
+	return f(ctx, claims)
+}
+
+// StaticKey — satu public key tetap (mis. PEM auth-service yang di-mount). Alg kosong =>
+// alg mengikuti tipe key.
+type StaticKey struct {
+	Key crypto.PublicKey
+	Alg string
+}
+
+func (s StaticKey) VerificationKey(_ context.Context, t *jwt.Token) (crypto.PublicKey, error) {
+	if s.Key == nil {
+		return nil, errors.New("no verification key")
+	}
+	if s.Alg != "" && t.Method.Alg() != s.Alg {
+		return nil, errors.New("alg does not match key")
+	}
+	return s.Key, nil
+}
+
+// VerificationKey mengambil key dari JWKS via header kid.
+func (c *JWKSCache) VerificationKey(ctx context.Context, t *jwt.Token) (crypto.PublicKey, error) {
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("no kid")
+	}
+	k, err := c.keyForKid(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+	if k.alg != "" && k.alg != t.Method.Alg() {
+		return nil, errors.New("alg does not match jwk")
+	}
+	return k.pub, nil
+}
+
+// Validator — kebijakan validasi JWT di resource server (gateway / shared middleware).
+// Signature, exp dan nbf selalu dicek; field kosong berarti aturan tersebut tidak dipakai,
+// kecuali TokenTypes yang default hanya menerima access token.
+type Validator struct {
+	Keys       KeySource
+	Issuers    []string      // iss harus salah satu dari ini
+	Audiences  []string      // aud harus memuat salah satu dari ini (RFC 8707)
+	TokenTypes []string      // klaim typ yang diterima; kosong => "access"
+	Leeway     time.Duration // toleransi clock skew untuk exp / nbf / iat
+
+	// klaim yang wajib ada: "sub", "exp", "iat", "nbf", "jti", "scope", "client_id", "user_id", "tenant_id"
+	RequiredClaims []string
+
+	Revocation RevocationChecker // nil => pencabutan tidak dicek
+}
+
+// Validate memverifikasi token dan menerapkan seluruh kebijakan Validator.
+func (v *Validator) Validate(ctx context.Context, raw string) (*TokenClaims, error) {
+	_, claims, err := v.validate(ctx, raw)
+	return claims, err
+}
+
+func (v *Validator) validate(ctx context.Context, raw string) (*jwt.Token, *TokenClaims, error) {
+	if v == nil || v.Keys == nil {
+		return nil, nil, errors.New("validator misconfigured")
+	}
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(SupportedSigningAlgs),
+		jwt.WithLeeway(v.Leeway),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	}
+	var claims TokenClaims
+	tok, err := jwt.NewParser(opts...).ParseWithClaims(raw, &claims, func(t *jwt.Token) (interface{}, error) {
+		return v.Keys.VerificationKey(ctx, t)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if !tok.Valid {
+		return nil, nil, errors.New("invalid token")
+	}
+
+	if len(v.Issuers) > 0 && !containsStr(v.Issuers, claims.Issuer) {
+		return nil, nil, ErrIssuerMismatch
+	}
+	if len(v.Audiences) > 0 && !claims.HasAudience(v.Audiences...) {
+		return nil, nil, ErrAudienceMismatch
+	}
+	types := v.TokenTypes
+	if len(types) == 0 {
+		types = []string{"access"}
+	}
+	if !containsStr(types, claims.Type) {
+		return nil, nil, fmt.Errorf("%w: %q", ErrTokenType, claims.Type)
+	}
+	for _, name := range v.RequiredClaims {
+		if !claims.has(name) {
+			return nil, nil, fmt.Errorf("%w: %s", ErrMissingClaim, name)
+		}
+	}
+	if v.Revocation != nil {
+		revoked, err := v.Revocation.IsRevoked(ctx, &claims)
+		if err != nil {
+			return nil, nil, fmt.Errorf("revocation lookup: %w", err)
+		}
+		if revoked {
+			return nil, nil, ErrTokenRevoked
+		}
+	}
+	return tok, &claims, nil
+}
+
+// has — klaim name ada dan tidak kosong.
+func (c *TokenClaims) has(name string) bool {
+	switch name {
+	case "iss":
+		return c.Issuer != ""
+	case "sub":
+		return c.Subject != ""
+	case "aud":
+		return len(c.RegisteredClaims.Audience) > 0
+	case "exp":
+		return c.ExpiresAt != nil
+	case "iat":
+		return c.IssuedAt != nil
+	case "nbf":
+		return c.NotBefore != nil
+	case "jti":
+		return c.ID != ""
+	case "scope":
+		return c.Scope != ""
+	case "client_id":
+		return c.ClientID != ""
+	case "user_id":
+		return c.UserID != ""
+	case "tenant_id":
+		return c.TenantID != ""
+	case "typ":
+		return c.Type != ""
+	}
+	return false
+}
+
+func containsStr(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}